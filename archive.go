@@ -0,0 +1,130 @@
+package rawsql
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// decodeSQL returns the sql held by content according to the extension of name:
+// .gz files are decompressed, .zip, .tar, .tar.gz and .tgz archives yield every
+// .sql and .sql.gz entry ordered by less, anything else is returned as is.
+// Archive entries are named archive/entry.
+func decodeSQL(name string, content []byte, less func(a, b string) bool) ([]Source, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		raw, err := gunzip(content)
+		if err != nil {
+			return nil, err
+		}
+		return untar(name, raw, less)
+	case strings.HasSuffix(lower, ".tar"):
+		return untar(name, content, less)
+	case strings.HasSuffix(lower, ".zip"):
+		return unzip(name, content, less)
+	case strings.HasSuffix(lower, ".gz"):
+		raw, err := gunzip(content)
+		if err != nil {
+			return nil, err
+		}
+		return []Source{{Name: name, Content: raw}}, nil
+	default:
+		return []Source{{Name: name, Content: content}}, nil
+	}
+}
+
+func isArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSQLEntry(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".sql") || strings.HasSuffix(lower, ".sql.gz")
+}
+
+func gunzip(content []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func untar(archive string, content []byte, less func(a, b string) bool) ([]Source, error) {
+	entries := map[string][]byte{}
+	tr := tar.NewReader(bytes.NewReader(content))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || !isSQLEntry(hdr.Name) {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries[hdr.Name] = data
+	}
+	return decodeEntries(archive, entries, less)
+}
+
+func unzip(archive string, content []byte, less func(a, b string) bool) ([]Source, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string][]byte{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !isSQLEntry(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries[f.Name] = data
+	}
+	return decodeEntries(archive, entries, less)
+}
+
+func decodeEntries(archive string, entries map[string][]byte, less func(a, b string) bool) ([]Source, error) {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return less(names[i], names[j])
+	})
+	sources := make([]Source, 0, len(names))
+	for _, name := range names {
+		decoded, err := decodeSQL(archive+"/"+name, entries[name], less)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, decoded...)
+	}
+	return sources, nil
+}
@@ -0,0 +1,77 @@
+package rawsql
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Backend creates the Parser used when Config.Parser is nil, backends are
+// selected by the name they were registered with in Config.Backend
+type Backend func(config *Config) Parser
+
+// TiDBBackend is the name of the built-in Parser backend, the default
+const TiDBBackend = "tidb"
+
+var (
+	backendsMu sync.RWMutex
+	backends   = map[string]Backend{TiDBBackend: newDefaultParse}
+)
+
+// RegisterBackend makes a Parser backend available by name, it is meant to be
+// called from the init function of the package implementing the backend and
+// panics when name is already registered
+func RegisterBackend(name string, backend Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+	if backend == nil {
+		panic("rawsql: RegisterBackend backend is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("rawsql: RegisterBackend called twice for backend " + name)
+	}
+	backends[name] = backend
+}
+
+// Backends returns the names of the registered backends, sorted
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (config *Config) backend() (Backend, error) {
+	name := config.Backend
+	if name == "" {
+		name = TiDBBackend
+	}
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+	backend, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("rawsql: unknown backend %q (forgotten import?)", name)
+	}
+	return backend, nil
+}
+
+// ReportingParser is a Parser that reports what it ingested, the dialector
+// fills ParseReport from it. The built-in Parser implements it, other
+// backends should too.
+type ReportingParser interface {
+	Parser
+	Stats() ParseStats   // totals since the parser was created
+	Warnings() []Warning // every warning issued since the parser was created, in order
+}
+
+func (d *defaultParser) Stats() ParseStats {
+	return d.stats
+}
+
+func (d *defaultParser) Warnings() []Warning {
+	return d.warnings
+}
@@ -0,0 +1,103 @@
+// Package backendtest checks that a rawsql Parser backend builds the same
+// tables as the built-in TiDB backend, backend packages run it from their
+// tests:
+//
+//	func TestConformance(t *testing.T) {
+//		backendtest.Run(t, "mybackend")
+//	}
+//
+// rawsql ships the TiDB backend only, no vitess or other backend exists yet.
+// A backend on another parser would live in a module of its own and register
+// itself with rawsql.RegisterBackend from its init function.
+package backendtest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/rawsql"
+)
+
+// Corpus is the plain MySQL DDL every backend is expected to handle
+var Corpus = map[string][]string{
+	"columns": {
+		"CREATE TABLE `users` (" +
+			"`id` bigint unsigned NOT NULL AUTO_INCREMENT, " +
+			"`name` varchar(64) NOT NULL DEFAULT '' COMMENT 'display name', " +
+			"`age` int DEFAULT NULL, " +
+			"`score` decimal(10,2) DEFAULT '0.00', " +
+			"`active` tinyint(1) NOT NULL DEFAULT '1', " +
+			"`created_at` datetime(3) DEFAULT NULL, " +
+			"PRIMARY KEY (`id`)" +
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='people';",
+	},
+	"indexes": {
+		"CREATE TABLE `posts` (" +
+			"`id` bigint NOT NULL, `user_id` bigint NOT NULL, `slug` varchar(128) NOT NULL, " +
+			"PRIMARY KEY (`id`), UNIQUE KEY `uk_slug` (`slug`), KEY `idx_user` (`user_id`, `id`));",
+	},
+	"foreign keys": {
+		"CREATE TABLE `users` (`id` bigint NOT NULL, PRIMARY KEY (`id`));",
+		"CREATE TABLE `orders` (`id` bigint NOT NULL, `user_id` bigint, " +
+			"CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE);",
+	},
+	"alter table": {
+		"CREATE TABLE `users` (`id` bigint NOT NULL, `name` varchar(64));",
+		"ALTER TABLE `users` ADD COLUMN `email` varchar(128) AFTER `id`, MODIFY `name` varchar(255) NOT NULL;",
+		"ALTER TABLE `users` CHANGE `email` `mail` varchar(128);",
+	},
+	"drop table": {
+		"CREATE TABLE `a` (`id` bigint);",
+		"CREATE TABLE `b` (`id` bigint);",
+		"DROP TABLE IF EXISTS `a`;",
+	},
+}
+
+// Run parses every Corpus entry with backend and with the TiDB backend and
+// fails t when their snapshots differ
+func Run(t *testing.T, backend string) {
+	t.Helper()
+	diffs, err := Compare(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, diff := range diffs {
+		t.Errorf("%s: %s", name, diff)
+	}
+}
+
+// Compare parses every Corpus entry with backend and with the TiDB backend
+// and returns how their snapshots differ by corpus entry, empty when the
+// backend conforms
+func Compare(backend string) (map[string]string, error) {
+	diffs := make(map[string]string)
+	for name, sql := range Corpus {
+		want, err := snapshot(rawsql.TiDBBackend, sql)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		got, err := snapshot(backend, sql)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if got != want {
+			diffs[name] = fmt.Sprintf("%s backend differs from %s\n got: %s\nwant: %s", backend, rawsql.TiDBBackend, got, want)
+		}
+	}
+	return diffs, nil
+}
+
+func snapshot(backend string, sql []string) (string, error) {
+	dialector := rawsql.New(rawsql.Config{Backend: backend, SQL: append([]string(nil), sql...)}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector, &gorm.Config{Logger: logger.Discard}); err != nil {
+		return "", fmt.Errorf("%s backend: %w", backend, err)
+	}
+	var b bytes.Buffer
+	if err := rawsql.EncodeSnapshot(&b, dialector.Parser.GetTables()); err != nil {
+		return "", fmt.Errorf("%s backend: encode: %w", backend, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,71 @@
+package rawsql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// ErrBaselineTable is wrapped by the error returned under Config.BaselineLock
+// when a baseline table is created again
+var ErrBaselineTable = errors.New("rawsql: baseline table created again")
+
+// lockBaseline makes the tables parsed so far the baseline under
+// Config.BaselineLock, once
+func (d *defaultParser) lockBaseline() {
+	if !d.config.BaselineLock || d.baseline != nil {
+		return
+	}
+	d.baseline = make(map[string]struct{}, len(d.tables))
+	for name := range d.tables {
+		d.baseline[name] = struct{}{}
+	}
+}
+
+// baselineError reports the CREATE TABLE node of the baseline table existing
+// with the difference between their definitions
+func (d *defaultParser) baselineError(node ast.StmtNode, existing, created *Table) error {
+	return fmt.Errorf("%w: %s: %s\n%s", ErrBaselineTable, existing.Name, stmtSummary(node),
+		lineDiff(CreateTableSQL(existing), CreateTableSQL(created)))
+}
+
+// lineDiff returns the lines of a missing from b prefixed with "- " and those
+// added by b with "+ ", the common lines with "  ", in order
+func lineDiff(a, b string) string {
+	x, y := strings.Split(strings.TrimSuffix(a, "\n"), "\n"), strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			diff.WriteString("  " + x[i] + "\n")
+			i, j = i+1, j+1
+		case j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+			diff.WriteString("- " + x[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + y[j] + "\n")
+			j++
+		}
+	}
+	return diff.String()
+}
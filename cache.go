@@ -0,0 +1,127 @@
+package rawsql
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// cacheKey hashes every sql content in order together with the parser type,
+// snapshot version and parse options, so any change to the input invalidates
+// the cached tables
+func (dialector Dialector) cacheKey() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "rawsql/%d/%T\n", SnapshotVersion, dialector.Parser)
+	// options changing which statements are applied
+	fmt.Fprintf(h, "%q/%t/%t\n", dialector.SQLMode, dialector.SkipDML, dialector.SkipTemporaryTables)
+	// the charset the sql text is read in changes the column charsets and the
+	// literals of defaults and comments
+	if dialector.ParseCharset != "" || dialector.ParseCollation != "" {
+		fmt.Fprintf(h, "parse-charset/%q/%q\n", dialector.ParseCharset, dialector.ParseCollation)
+	}
+	// options changing which warnings the cached entries replay
+	if dialector.TargetVersion != "" {
+		fmt.Fprintf(h, "target-version/%q\n", dialector.TargetVersion)
+	}
+	if dialector.HonorForeignKeyChecks {
+		fmt.Fprintf(h, "honor-foreign-key-checks\n")
+	}
+	if dialector.RecoverSyntaxErrors {
+		fmt.Fprintf(h, "recover-syntax-errors\n")
+	}
+	if dialector.VectorScanType != nil {
+		fmt.Fprintf(h, "vector-scan-type/%s\n", dialector.VectorScanType)
+	}
+	if len(dialector.CommentExtractors) > 0 {
+		fmt.Fprintf(h, "comment-extractors/%q\n", dialector.CommentExtractors)
+	}
+	for _, m := range dialector.TypeMappings {
+		fmt.Fprintf(h, "type-mapping/%q/%q/%q/%s\n", m.Column, m.Table, m.DataType, m.ScanType)
+	}
+	if dialector.NullableScanType != NullableAsValue {
+		fmt.Fprintf(h, "nullable-scan-type/%d\n", dialector.NullableScanType)
+	}
+	if dialector.DroppedReferences != KeepForeignKeys {
+		fmt.Fprintf(h, "dropped-references/%d\n", dialector.DroppedReferences)
+	}
+	if dialector.SkipGhostTables {
+		fmt.Fprintf(h, "%q\n", dialector.ghostTablePatterns())
+	}
+	if len(dialector.Tables) > 0 {
+		tables := make(map[string]*Table, len(dialector.Tables))
+		for _, table := range dialector.Tables {
+			tables[table.Name] = table
+		}
+		EncodeSnapshot(h, tables)
+	}
+	for _, sql := range dialector.SQL {
+		sum := sha256.Sum256([]byte(sql))
+		h.Write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (dialector Dialector) cachePath() string {
+	return filepath.Join(dialector.CacheDir, dialector.cacheKey()+".json")
+}
+
+// cacheEntry is the content of a cache file: the snapshot of the tables and
+// the warnings of their parse, which loading the entry reports again
+type cacheEntry struct {
+	Warnings []Warning       `json:"warnings,omitempty"`
+	Snapshot json.RawMessage `json:"snapshot"`
+}
+
+// loadCache returns the cached tables for the current sql and the warnings
+// of their parse, ok is false on a miss
+func (dialector Dialector) loadCache() (tables map[string]*Table, warnings []Warning, ok bool) {
+	content, err := ioutil.ReadFile(dialector.cachePath())
+	if err != nil {
+		return nil, nil, false
+	}
+	var entry cacheEntry
+	if err = json.Unmarshal(content, &entry); err != nil {
+		return nil, nil, false
+	}
+	tables, err = DecodeSnapshot(bytes.NewReader(entry.Snapshot))
+	if err != nil {
+		return nil, nil, false
+	}
+	return tables, entry.Warnings, true
+}
+
+func (dialector Dialector) saveCache(tables map[string]*Table, warnings []Warning) error {
+	if err := os.MkdirAll(dialector.CacheDir, 0o755); err != nil {
+		return err
+	}
+	var snapshot bytes.Buffer
+	if err := EncodeSnapshot(&snapshot, tables); err != nil {
+		return err
+	}
+	content, err := json.Marshal(cacheEntry{Warnings: warnings, Snapshot: bytes.TrimSpace(snapshot.Bytes())})
+	if err != nil {
+		return err
+	}
+	path := dialector.cachePath()
+	tmp, err := ioutil.TempFile(dialector.CacheDir, ".rawsql-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(content); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
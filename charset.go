@@ -0,0 +1,177 @@
+package rawsql
+
+import (
+	"database/sql"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"github.com/pingcap/tidb/pkg/parser/types"
+)
+
+// NCHAR and NVARCHAR columns use the charset MySQL calls national
+const (
+	nationalCharset   = "utf8mb3"
+	nationalCollation = "utf8mb3_general_ci"
+)
+
+// charTypes are the data types that have a character set
+var charTypes = map[string]bool{
+	"char": true, "varchar": true, "tinytext": true, "text": true, "mediumtext": true, "longtext": true,
+	"enum": true, "set": true,
+}
+
+// tableCharset returns the default character set and collation declared by
+// the table options, a collation without charset implies the charset
+func tableCharset(options []*ast.TableOption) (charset, collation string) {
+	for _, opt := range options {
+		switch opt.Tp {
+		case ast.TableOptionCharset:
+			charset = strings.ToLower(opt.StrValue)
+		case ast.TableOptionCollate:
+			collation = strings.ToLower(opt.StrValue)
+		}
+	}
+	if charset == "" && collation != "" {
+		charset = collationCharset(collation)
+	}
+	return charset, collation
+}
+
+// collationCharset returns the charset a collation belongs to, MySQL collation
+// names start with their charset, e.g. utf8mb4_unicode_ci
+func collationCharset(collation string) string {
+	if i := strings.IndexByte(collation, '_'); i > 0 {
+		return collation[:i]
+	}
+	return collation
+}
+
+// inheritCharset resolves the charset and collation of a column of table the
+// way MySQL does: a column without charset uses the table default, a column
+// with a collation but no charset uses the charset of the collation.
+func (d *defaultParser) inheritCharset(table *Table, ct *ColumnType) {
+	if !charTypes[ct.DatabaseTypeName()] {
+		return
+	}
+	charset, collation := ct.CharsetValue.String, ct.CollationValue.String
+	switch {
+	case charset == "" && collation != "":
+		charset = collationCharset(collation)
+	case charset == "":
+		charset, collation = table.Charset, table.Collation
+	case collation == "" && charset == table.Charset:
+		collation = table.Collation
+	}
+	if ct.BinaryValue && charset != "" {
+		collation = charset + "_bin"
+	}
+	ct.CharsetValue = sql.NullString{String: d.intern(charset), Valid: charset != ""}
+	ct.CollationValue = sql.NullString{String: d.intern(collation), Valid: collation != ""}
+	if ct.CharLengthValue.Valid {
+		octets := ct.CharLengthValue.Int64
+		if _, text := textTypes[ct.DatabaseTypeName()]; !text {
+			octets *= charsetMaxBytes(charset)
+		}
+		ct.OctetLengthValue = sql.NullInt64{Int64: octets, Valid: true}
+	}
+}
+
+// textTypes are the string types whose length limits bytes, by that length
+var textTypes = map[string]int64{
+	"tinytext": 255, "text": 65535, "mediumtext": 16777215, "longtext": 4294967295,
+	"tinyblob": 255, "blob": 65535, "mediumblob": 16777215, "longblob": 4294967295,
+}
+
+// charLength returns the length of a string type in characters as MySQL
+// reports it, ok is false for the other types
+func charLength(tp *types.FieldType) (length int64, ok bool) {
+	switch tp.GetType() {
+	case mysql.TypeString, mysql.TypeVarchar, mysql.TypeVarString:
+		if tp.GetFlen() < 0 {
+			return 1, true
+		}
+		return int64(tp.GetFlen()), true
+	case mysql.TypeTinyBlob:
+		return textTypes["tinytext"], true
+	case mysql.TypeBlob:
+		return textTypes["text"], true
+	case mysql.TypeMediumBlob:
+		return textTypes["mediumtext"], true
+	case mysql.TypeLongBlob:
+		return textTypes["longtext"], true
+	case mysql.TypeEnum:
+		for _, elem := range tp.GetElems() {
+			if n := int64(utf8.RuneCountInString(elem)); n > length {
+				length = n
+			}
+		}
+		return length, true
+	case mysql.TypeSet:
+		for i, elem := range tp.GetElems() {
+			if i > 0 {
+				length++ // the comma separating the members
+			}
+			length += int64(utf8.RuneCountInString(elem))
+		}
+		return length, true
+	}
+	return 0, false
+}
+
+// charsetMaxBytes is the longest character of charset in bytes, the utf8mb4
+// default of MySQL 8.0 when the charset is not known
+func charsetMaxBytes(charset string) int64 {
+	switch charset {
+	case "latin1", "ascii", "binary", "latin2", "latin5", "latin7", "cp1250", "cp1251", "cp1256", "cp1257",
+		"cp850", "cp852", "cp866", "dec8", "greek", "hebrew", "hp8", "keybcs2", "koi8r", "koi8u", "macce",
+		"macroman", "swe7", "tis620", "armscii8", "geostd8":
+		return 1
+	case "big5", "cp932", "euckr", "gb2312", "gbk", "sjis", "ucs2":
+		return 2
+	case "utf8", "utf8mb3", "eucjpms", "ujis":
+		return 3
+	}
+	return 4
+}
+
+// columnTypeString formats tp the way information_schema.COLUMNS.COLUMN_TYPE
+// does, without the charset, collation and BINARY attributes. The values of
+// ENUM and SET columns keep their case, as MySQL compares and returns them as
+// declared.
+func columnTypeString(tp *types.FieldType) string {
+	if tp.GetType() == mysql.TypeEnum || tp.GetType() == mysql.TypeSet {
+		// e.g. enum('New','it''s'), escaped by CompactStr
+		s := tp.CompactStr()
+		return strings.ToLower(s[:strings.IndexByte(s, '(')]) + s[strings.IndexByte(s, '('):]
+	}
+	s := tp.InfoSchemaStr()
+	if mysql.HasZerofillFlag(tp.GetFlag()) {
+		s += " zerofill"
+	}
+	return strings.ToLower(s)
+}
+
+var nationalKeywords = []string{"NCHAR", "NVARCHAR", "NATIONAL"}
+
+// nationalColumns returns the lower cased names of the columns text declares
+// with a national character type. The tidb parser does not keep NCHAR apart
+// from CHAR, so the statement text is scanned for a column name followed by
+// one of the national keywords.
+func nationalColumns(text string) map[string]bool {
+	if !containsFold(text, "NCHAR") && !containsFold(text, "NVARCHAR") && !containsFold(text, "NATIONAL") {
+		return nil
+	}
+	columns := make(map[string]bool)
+	var prev sqlToken
+	for _, tok := range sqlTokens(text) {
+		for _, kw := range nationalKeywords {
+			if prev.isName() && tok.is(kw) {
+				columns[strings.ToLower(prev.text)] = true
+			}
+		}
+		prev = tok
+	}
+	return columns
+}
@@ -0,0 +1,137 @@
+package rawsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	"gorm.io/rawsql/meta"
+)
+
+// exprString returns the value of a literal expression, any other expression
+// is returned as its restored sql text
+func exprString(expr ast.ExprNode) string {
+	if expr == nil {
+		return ""
+	}
+	if v, ok := expr.(ast.ValueExpr); ok {
+		switch val := v.GetValue().(type) {
+		case nil:
+			return ""
+		case string:
+			return val
+		case []byte:
+			return string(val)
+		default:
+			return fmt.Sprint(val)
+		}
+	}
+	var b strings.Builder
+	flags := format.RestoreStringSingleQuotes | format.RestoreKeyWordUppercase | format.RestoreNameBackQuotes
+	if err := expr.Restore(format.NewRestoreCtx(flags, &b)); err != nil {
+		return expr.Text()
+	}
+	return b.String()
+}
+
+// nowString returns CURRENT_TIMESTAMP, which the parser folds its synonyms
+// into, as MySQL reports it with the fractional seconds precision if given
+func nowString(expr ast.ExprNode) string {
+	call, ok := expr.(*ast.FuncCallExpr)
+	if !ok {
+		return exprString(expr)
+	}
+	name := strings.ToUpper(call.FnName.O)
+	if len(call.Args) == 0 {
+		return name
+	}
+	return name + "(" + exprString(call.Args[0]) + ")"
+}
+
+// defaultExpression returns the default value expression expr as MySQL
+// reports it: CURRENT_TIMESTAMP with its precision, other expressions
+// without the parentheses they are declared in, e.g. uuid() for DEFAULT
+// (uuid())
+func defaultExpression(expr ast.ExprNode) string {
+	for {
+		paren, ok := expr.(*ast.ParenthesesExpr)
+		if !ok {
+			break
+		}
+		expr = paren.Expr
+	}
+	if call, ok := expr.(*ast.FuncCallExpr); ok && call.FnName.L == ast.CurrentTimestamp {
+		return nowString(call)
+	}
+	return exprString(expr)
+}
+
+// constantDefault evaluates a default value expression that is a literal,
+// possibly signed as in DEFAULT -1 or DEFAULT +2, into its string form and
+// kind. The tidb parser turns the sign into an operator on the literal.
+func constantDefault(expr ast.ExprNode, ct *ColumnType) (value string, kind meta.DefaultKind, ok bool) {
+	switch expr := expr.(type) {
+	case ast.ValueExpr:
+		if v := expr.GetValue(); v != nil {
+			return fmt.Sprint(v), defaultKind(v, ct), true
+		}
+	case *ast.UnaryOperationExpr:
+		if expr.Op != opcode.Minus && expr.Op != opcode.Plus {
+			return "", "", false
+		}
+		value, kind, ok = constantDefault(expr.V, ct)
+		switch {
+		case !ok || kind == meta.DefaultString || kind == "":
+			// signed strings are expressions
+			return "", "", false
+		case expr.Op == opcode.Plus:
+			return value, kind, true
+		}
+		if strings.HasPrefix(value, "-") {
+			value = value[1:]
+		} else if value != "0" {
+			value = "-" + value
+		}
+		if kind == meta.DefaultUint || kind == meta.DefaultBool {
+			kind = meta.DefaultInt
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				kind = meta.DefaultFloat
+			}
+		}
+		return value, kind, true
+	}
+	return "", "", false
+}
+
+// QuoteString quotes s as a MySQL string literal, it is the inverse of the
+// unescaping applied to the comments and defaults of parsed columns and tables
+func QuoteString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\'':
+			b.WriteString("''")
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case 0:
+			b.WriteString(`\0`)
+		case 26:
+			b.WriteString(`\Z`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
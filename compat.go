@@ -0,0 +1,88 @@
+package rawsql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"gorm.io/gorm/migrator"
+	"gorm.io/rawsql/meta"
+)
+
+// checkCompatibility warns about the features of a CREATE or ALTER TABLE the
+// server of Config.TargetVersion rejects or silently ignores, when one is set
+func (d *defaultParser) checkCompatibility(node ast.StmtNode) {
+	if d.config.TargetVersion == "" {
+		return
+	}
+	// validated by Initialize
+	version, _ := parseServerVersion(d.config.TargetVersion)
+
+	var cols []*ast.ColumnDef
+	var constraints []*ast.Constraint
+	switch stmt := node.(type) {
+	case *ast.CreateTableStmt:
+		cols, constraints = stmt.Cols, stmt.Constraints
+	case *ast.AlterTableStmt:
+		for _, spec := range stmt.Specs {
+			cols = append(cols, spec.NewColumns...)
+			if spec.Constraint != nil {
+				constraints = append(constraints, spec.Constraint)
+			}
+		}
+	}
+
+	for _, col := range cols {
+		for _, opt := range col.Options {
+			switch {
+			case opt.Tp == ast.ColumnOptionDefaultValue && expressionDefault(col, opt.Expr) && !version.TiDB && version.before(8, 0, 13):
+				d.warn(node, fmt.Sprintf("expression default of column %s needs mysql-8.0.13, the target is %s", col.Name.Name.O, version))
+			case opt.Tp == ast.ColumnOptionCheck:
+				d.warnCheck(node, version, "column "+col.Name.Name.O)
+			}
+		}
+	}
+	for _, cons := range constraints {
+		if cons.Tp == ast.ConstraintCheck {
+			of := "constraint"
+			if cons.Name != "" {
+				of += " " + cons.Name
+			}
+			d.warnCheck(node, version, of)
+			continue
+		}
+		for _, key := range cons.Keys {
+			if !key.Desc || !version.TiDB && !version.before(8, 0, 0) {
+				continue
+			}
+			column := ""
+			if key.Column != nil {
+				column = key.Column.Name.O
+			}
+			d.warn(node, fmt.Sprintf("descending index column %s is ignored by %s", column, version))
+		}
+	}
+}
+
+// warnCheck warns about a CHECK constraint parsed but not enforced by version
+func (d *defaultParser) warnCheck(node ast.StmtNode, version serverVersion, of string) {
+	if version.TiDB && version.before(7, 2, 0) || !version.TiDB && version.before(8, 0, 16) {
+		d.warn(node, fmt.Sprintf("CHECK %s is not enforced by %s", of, version))
+	}
+}
+
+// expressionDefault reports whether the default expr of col is an expression
+// rather than a literal or the CURRENT_TIMESTAMP of a temporal column, which
+// every version supports
+func expressionDefault(col *ast.ColumnDef, expr ast.ExprNode) bool {
+	if v, ok := expr.(ast.ValueExpr); ok && v.GetValue() == nil {
+		return false
+	}
+	if _, _, ok := constantDefault(expr, meta.NewColumnType(migrator.ColumnType{ScanTypeValue: getType(col.Tp)})); ok {
+		return false
+	}
+	call, ok := expr.(*ast.FuncCallExpr)
+	temporal := col.Tp.GetType() == mysql.TypeDatetime || col.Tp.GetType() == mysql.TypeTimestamp
+	return !ok || !temporal || !strings.EqualFold(call.FnName.O, "CURRENT_TIMESTAMP")
+}
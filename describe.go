@@ -0,0 +1,144 @@
+package rawsql
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
+// QueryValue is a ? placeholder or a result column of a query, typed after
+// the column of a parsed table it stands for
+type QueryValue struct {
+	Name     string       // the alias, column name or text of result columns as MySQL names them, the column of placeholders, empty for other placeholders
+	Table    string       // the parsed table of Column, empty when the value stands for no column
+	Column   string       // as declared in Table
+	ScanType reflect.Type // following Config.NullableScanType when Nullable, nil when not known
+	Nullable bool
+}
+
+// GoType returns the name of the scan type, e.g. sql.NullString, or
+// interface{} when it is not known
+func (v QueryValue) GoType() string {
+	if v.ScanType == nil {
+		return "interface{}"
+	}
+	return v.ScanType.String()
+}
+
+// QueryDescription is what DescribeQuery infers of a statement
+type QueryDescription struct {
+	Params  []QueryValue // the ? placeholders in order
+	Columns []QueryValue // the result columns of a SELECT, nil for other statements and when not all are known, e.g. selecting * of an unknown table
+	Issues  []Issue      // as CheckQuery reports them
+}
+
+// DescribeQuery infers the Go types of the ? placeholders and the result
+// columns of the single statement sql from the parsed tables, so wrappers of
+// prepared queries can be generated without a database. A placeholder takes
+// the scan type of the column it is compared with or written to, LIMIT ?
+// takes an int64 and LIKE ? a string; a result column takes the scan type of
+// the column it selects, COUNT an int64 and MIN and MAX that of their column.
+// Values written to nullable columns and columns of the outer side of LEFT
+// and RIGHT JOIN are nullable, values compared with a column are not. The
+// error is about sql that does not parse or holds several statements.
+func (dialector Dialector) DescribeQuery(sql string) (*QueryDescription, error) {
+	stmtNodes, err := dialector.parseQueries(sql)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmtNodes) != 1 {
+		return nil, fmt.Errorf("rawsql: DescribeQuery takes a single statement, got %d", len(stmtNodes))
+	}
+
+	node := stmtNodes[0]
+	c := newQueryChecker(dialector.store.get(), node)
+	columns, ok := c.checkStmt(node)
+	style := dialector.NullableScanType
+	description := &QueryDescription{Issues: c.issues}
+	for _, param := range paramMarkers(node) {
+		description.Params = append(description.Params, c.params[param].value(style))
+	}
+	if ok {
+		for _, column := range columns {
+			description.Columns = append(description.Columns, c.resultValue(column, style))
+		}
+	}
+	return description, nil
+}
+
+// value returns the QueryValue of the placeholder
+func (p queryParam) value(style NullableStyle) QueryValue {
+	if p.column.column == nil {
+		return QueryValue{ScanType: p.scanType}
+	}
+	ct := p.column.column
+	v := QueryValue{Name: ct.Name(), Table: p.column.table, Column: ct.Name()}
+	if p.assigned {
+		v.Nullable, _ = ct.Nullable()
+	}
+	v.ScanType = style.scanType(ct.ScanType(), v.Nullable)
+	return v
+}
+
+// resultValue returns the QueryValue of a result column
+func (c *queryChecker) resultValue(column resultColumn, style NullableStyle) QueryValue {
+	v := QueryValue{Name: column.name}
+	if aggregate, ok := column.expr.(*ast.AggregateFuncExpr); ok {
+		switch strings.ToLower(aggregate.F) {
+		case ast.AggFuncCount:
+			v.ScanType = longT
+			return v
+		case ast.AggFuncMin, ast.AggFuncMax:
+			if arg, ok := aggregate.Args[0].(*ast.ColumnNameExpr); ok {
+				name := column.name
+				column = c.columns[arg]
+				column.name, column.nullable = name, true
+			}
+		}
+	}
+	if column.column == nil {
+		return v
+	}
+	ct := column.column
+	v.Table, v.Column = column.table, ct.Name()
+	nullable, _ := ct.Nullable()
+	v.Nullable = nullable || column.nullable
+	v.ScanType = style.scanType(ct.ScanType(), v.Nullable)
+	return v
+}
+
+// paramMarkers returns the ? placeholders of node in the order of the text
+func paramMarkers(node ast.Node) []ast.ParamMarkerExpr {
+	collector := &paramCollector{}
+	node.Accept(collector)
+	sort.SliceStable(collector.params, func(i, j int) bool {
+		return paramOffset(collector.params[i]) < paramOffset(collector.params[j])
+	})
+	return collector.params
+}
+
+func paramOffset(param ast.ParamMarkerExpr) int {
+	if p, ok := param.(*test_driver.ParamMarkerExpr); ok {
+		return p.Offset
+	}
+	return 0
+}
+
+type paramCollector struct {
+	params []ast.ParamMarkerExpr
+}
+
+func (v *paramCollector) Enter(n ast.Node) (ast.Node, bool) {
+	if param, ok := n.(ast.ParamMarkerExpr); ok {
+		v.params = append(v.params, param)
+	}
+	return n, false
+}
+
+func (v *paramCollector) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
@@ -0,0 +1,270 @@
+package rawsql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TableDiff is the difference between two versions of a table, see
+// CompareTables
+type TableDiff struct {
+	Name               string        // of the new version of the table
+	Changes            []FieldChange // of the table name and options, e.g. engine or ROW_FORMAT
+	AddedColumns       []gorm.ColumnType
+	RemovedColumns     []gorm.ColumnType
+	ChangedColumns     []ColumnDiff
+	AddedIndexes       []gorm.Index
+	RemovedIndexes     []gorm.Index
+	ChangedIndexes     []IndexDiff
+	AddedForeignKeys   []ForeignKey
+	RemovedForeignKeys []ForeignKey // a changed foreign key is removed and added again, as MySQL has no way to alter one
+
+	from, to *Table // compared, for the definitions of the columns and Unified
+}
+
+// ColumnDiff is a column found in both versions of a table, renamed or not,
+// whose definition changed
+type ColumnDiff struct {
+	Name     string // of the new version of the column
+	From, To gorm.ColumnType
+	Changes  []FieldChange
+}
+
+// IndexDiff is an index found in both versions of a table by name whose
+// definition, see IndexSQL, changed
+type IndexDiff struct {
+	Name     string
+	From, To gorm.Index
+}
+
+// FieldChange is a changed part of a table or column definition, its values
+// are sql as CreateTableSQL writes it, empty when the part is left out, e.g.
+// Field nullable, From NULL, To NOT NULL
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// Empty reports whether both versions of the table are the same
+func (diff TableDiff) Empty() bool {
+	return len(diff.Changes) == 0 && len(diff.AddedColumns) == 0 && len(diff.RemovedColumns) == 0 &&
+		len(diff.ChangedColumns) == 0 && len(diff.AddedIndexes) == 0 && len(diff.RemovedIndexes) == 0 &&
+		len(diff.ChangedIndexes) == 0 && len(diff.AddedForeignKeys) == 0 && len(diff.RemovedForeignKeys) == 0
+}
+
+// CompareTables returns the difference from table a to table b. Columns are
+// matched by name, following the column renames of b, indexes and foreign keys
+// by name, all case-insensitively. Added columns, indexes and foreign keys are
+// in the order of b, the others in the order of a.
+func CompareTables(a, b *Table) TableDiff {
+	diff := TableDiff{Name: b.Name, Changes: fieldChanges(tableFields(a), tableFields(b)), from: a, to: b}
+
+	matched := make(map[int]bool, len(b.ColumnTypes))
+	for _, from := range a.ColumnTypes {
+		i := columnIndex(b.ColumnTypes, b.CurrentColumnName(from.Name()))
+		if i < 0 || matched[i] {
+			diff.RemovedColumns = append(diff.RemovedColumns, from)
+			continue
+		}
+		matched[i] = true
+		to := b.ColumnTypes[i]
+		if changes := fieldChanges(columnFields(a, from), columnFields(b, to)); len(changes) > 0 {
+			diff.ChangedColumns = append(diff.ChangedColumns, ColumnDiff{Name: to.Name(), From: from, To: to, Changes: changes})
+		}
+	}
+	for i, to := range b.ColumnTypes {
+		if !matched[i] {
+			diff.AddedColumns = append(diff.AddedColumns, to)
+		}
+	}
+
+	indexes := make(map[string]gorm.Index, len(b.Indexes))
+	for _, idx := range b.Indexes {
+		indexes[indexKey(idx)] = idx
+	}
+	for _, from := range a.Indexes {
+		to, ok := indexes[indexKey(from)]
+		switch {
+		case !ok:
+			diff.RemovedIndexes = append(diff.RemovedIndexes, from)
+		case IndexSQL(from) != IndexSQL(to):
+			diff.ChangedIndexes = append(diff.ChangedIndexes, IndexDiff{Name: to.Name(), From: from, To: to})
+		}
+		delete(indexes, indexKey(from))
+	}
+	for _, idx := range b.Indexes {
+		if _, ok := indexes[indexKey(idx)]; ok {
+			diff.AddedIndexes = append(diff.AddedIndexes, idx)
+		}
+	}
+
+	foreignKeys := make(map[string]string, len(b.ForeignKeys))
+	for _, fk := range b.ForeignKeys {
+		foreignKeys[strings.ToLower(fk.Name)] = foreignKeySQL(fk)
+	}
+	same := make(map[string]bool, len(a.ForeignKeys))
+	for _, fk := range a.ForeignKeys {
+		if def, ok := foreignKeys[strings.ToLower(fk.Name)]; ok && def == foreignKeySQL(fk) {
+			same[strings.ToLower(fk.Name)] = true
+		} else {
+			diff.RemovedForeignKeys = append(diff.RemovedForeignKeys, fk)
+		}
+	}
+	for _, fk := range b.ForeignKeys {
+		if !same[strings.ToLower(fk.Name)] {
+			diff.AddedForeignKeys = append(diff.AddedForeignKeys, fk)
+		}
+	}
+	return diff
+}
+
+// indexKey identifies an index within its table, the primary key has no name
+// and unnamed indexes are known by their definition
+func indexKey(idx gorm.Index) string {
+	if pk, _ := idx.PrimaryKey(); pk {
+		return "primary"
+	}
+	if idx.Name() == "" {
+		return IndexSQL(idx)
+	}
+	return strings.ToLower(idx.Name())
+}
+
+// field is a part of a table or column definition compared by CompareTables
+type field struct {
+	name, value string
+}
+
+// fieldChanges returns the fields whose values differ between from and to,
+// which hold the same fields in the same order apart from the table options
+func fieldChanges(from, to []field) (changes []FieldChange) {
+	values := make(map[string]string, len(to))
+	for _, f := range to {
+		values[f.name] = f.value
+	}
+	seen := make(map[string]bool, len(from))
+	for _, f := range from {
+		seen[f.name] = true
+		if f.value != values[f.name] {
+			changes = append(changes, FieldChange{Field: f.name, From: f.value, To: values[f.name]})
+		}
+	}
+	for _, f := range to {
+		if !seen[f.name] && f.value != "" {
+			changes = append(changes, FieldChange{Field: f.name, To: f.value})
+		}
+	}
+	return changes
+}
+
+// tableFields returns the parts of the definition of table CreateTableSQL
+// writes after the column list, and its name
+func tableFields(table *Table) []field {
+	fields := []field{
+		{"name", quoteName(table.Name)},
+		{"engine", table.Engine},
+		{"charset", table.Charset},
+		{"collation", table.Collation},
+		{"comment", quoteComment(table.Comment)},
+	}
+	options := make([]string, 0, len(table.Options))
+	for name := range table.Options {
+		options = append(options, name)
+	}
+	sort.Strings(options)
+	for _, name := range options {
+		// options without a value, e.g. PLACEMENT POLICY DEFAULT, are set
+		value := table.Options[name]
+		if value == "" {
+			value = name
+		}
+		fields = append(fields, field{name, value})
+	}
+	return fields
+}
+
+// columnFields returns the parts of the definition of ct CreateTableSQL
+// writes, and its name
+func columnFields(table *Table, ct gorm.ColumnType) []field {
+	columnType, _ := ct.ColumnType()
+	fields := []field{{"name", quoteName(ct.Name())}, {"type", columnType}}
+	var charset, collation, srid, generated, onUpdate, invisible, format, storage string
+	if c, ok := ct.(*ColumnType); ok {
+		charset, _ = c.Charset()
+		collation, _ = c.Collation()
+		// charsets and collations inherited from the table are no change of
+		// the column when the table changes them
+		if charset == table.Charset {
+			charset = ""
+		}
+		if collation == table.Collation {
+			collation = ""
+		}
+		if value, ok := c.SRID(); ok {
+			srid = fmt.Sprint(value)
+		}
+		if expression, stored, ok := c.Generated(); ok {
+			generated = "AS (" + expression + ") VIRTUAL"
+			if stored {
+				generated = "AS (" + expression + ") STORED"
+			}
+		}
+		onUpdate = c.OnUpdate()
+		if c.Invisible() {
+			invisible = "INVISIBLE"
+		}
+		format, storage = c.ColumnFormat(), c.Storage()
+	}
+	nullable := "NULL"
+	if value, ok := ct.Nullable(); ok && !value {
+		nullable = "NOT NULL"
+	}
+	var defaultValue string
+	if value, ok := ct.DefaultValue(); ok {
+		var kind DefaultKind
+		if c, ok := ct.(*ColumnType); ok {
+			kind = c.DefaultKind()
+		}
+		defaultValue = defaultSQL(value, kind, strings.ToLower(ct.DatabaseTypeName()))
+	}
+	var autoIncrement, primaryKey, unique, comment string
+	if value, _ := ct.AutoIncrement(); value {
+		autoIncrement = "AUTO_INCREMENT"
+	}
+	if value, _ := ct.PrimaryKey(); value {
+		primaryKey = "PRIMARY KEY"
+	}
+	if value, _ := ct.Unique(); value {
+		unique = "UNIQUE"
+	}
+	if value, ok := ct.Comment(); ok {
+		comment = quoteComment(value)
+	}
+	return append(fields,
+		field{"charset", charset},
+		field{"collation", collation},
+		field{"srid", srid},
+		field{"generated", generated},
+		field{"nullable", nullable},
+		field{"default", defaultValue},
+		field{"on update", onUpdate},
+		field{"auto increment", autoIncrement},
+		field{"primary key", primaryKey},
+		field{"unique", unique},
+		field{"invisible", invisible},
+		field{"column format", format},
+		field{"storage", storage},
+		field{"comment", comment},
+	)
+}
+
+func quoteComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return QuoteString(comment)
+}
@@ -0,0 +1,191 @@
+package rawsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// String renders the diff for reviewers, a line per entry below the quoted
+// table name: + for additions, - for removals and ~ for changes, followed by
+// the severity of the entry and why, e.g.
+//
+//	`users`
+//	  + column `bio` text [safe]
+//	  ~ column `name`: type varchar(32) -> varchar(64), nullable NULL -> NOT NULL [needs-backfill: existing NULLs must be filled in first]
+//	  - index KEY `idx_age` (`age`) [safe]
+//
+// It is empty when the diff is.
+func (diff TableDiff) String() string {
+	if diff.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(quoteName(diff.Name) + "\n")
+	for _, entry := range diff.Entries() {
+		if entry.Reason != "" {
+			fmt.Fprintf(&b, "  %s %s [%s: %s]\n", entry.Op, entry.Text, entry.Severity, entry.Reason)
+		} else {
+			fmt.Fprintf(&b, "  %s %s [%s]\n", entry.Op, entry.Text, entry.Severity)
+		}
+	}
+	return b.String()
+}
+
+func changeText(change FieldChange) string {
+	from, to := change.From, change.To
+	if from == "" {
+		from = "(none)"
+	}
+	if to == "" {
+		to = "(none)"
+	}
+	return change.Field + " " + from + " -> " + to
+}
+
+// table returns t, or an empty table for a diff not made by CompareTables
+func (diff TableDiff) table(t *Table) *Table {
+	if t == nil {
+		return &Table{}
+	}
+	return t
+}
+
+// Unified renders the diff as a unified diff of the CreateTableSQL of both
+// versions of the table with three lines of context, as git diff shows the
+// files written by Export. It is empty when the diff is or was not made by
+// CompareTables.
+func (diff TableDiff) Unified() string {
+	if diff.from == nil || diff.to == nil || diff.Empty() {
+		return ""
+	}
+	return unifiedDiff("a/"+exportFileName(diff.from.Name), "b/"+exportFileName(diff.to.Name),
+		CreateTableSQL(diff.from), CreateTableSQL(diff.to), 3)
+}
+
+// unifiedDiff turns the lineDiff of a and b into hunks of changed lines with
+// context lines around them, nearby hunks are merged
+func unifiedDiff(fromFile, toFile, a, b string, context int) string {
+	lines := strings.Split(strings.TrimSuffix(lineDiff(a, b), "\n"), "\n")
+	var changed []int
+	// line[k] counts the lines of a and b before lines[k]
+	aLine, bLine := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for k, line := range lines {
+		aLine[k+1], bLine[k+1] = aLine[k], bLine[k]
+		if line[0] != '+' {
+			aLine[k+1]++
+		}
+		if line[0] != '-' {
+			bLine[k+1]++
+		}
+		if line[0] != ' ' {
+			changed = append(changed, k)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromFile, toFile)
+	for h := 0; h < len(changed); {
+		last := h
+		for last+1 < len(changed) && changed[last+1]-changed[last] <= 2*context {
+			last++
+		}
+		start, end := changed[h]-context, changed[last]+context+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(lines) {
+			end = len(lines)
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aLine[start], aLine[end]-aLine[start]), hunkRange(bLine[start], bLine[end]-bLine[start]))
+		for _, line := range lines[start:end] {
+			out.WriteString(line[:1] + line[2:] + "\n")
+		}
+		h = last + 1
+	}
+	return out.String()
+}
+
+// hunkRange writes the lines of a hunk starting after line start, counting
+// from 1 as unified diffs do
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+type tableDiffJSON struct {
+	Name               string           `json:"name"`
+	Severity           Severity         `json:"severity"`
+	Entries            []DiffEntry      `json:"entries,omitempty"`
+	Changes            []FieldChange    `json:"changes,omitempty"`
+	AddedColumns       []string         `json:"added_columns,omitempty"` // definitions as CreateTableSQL writes them
+	RemovedColumns     []string         `json:"removed_columns,omitempty"`
+	ChangedColumns     []columnDiffJSON `json:"changed_columns,omitempty"`
+	AddedIndexes       []string         `json:"added_indexes,omitempty"` // IndexSQL
+	RemovedIndexes     []string         `json:"removed_indexes,omitempty"`
+	ChangedIndexes     []indexDiffJSON  `json:"changed_indexes,omitempty"`
+	AddedForeignKeys   []ForeignKey     `json:"added_foreign_keys,omitempty"`
+	RemovedForeignKeys []ForeignKey     `json:"removed_foreign_keys,omitempty"`
+}
+
+type columnDiffJSON struct {
+	Name    string        `json:"name"`
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+type indexDiffJSON struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MarshalJSON renders the diff for tools, columns and indexes as their
+// definitions in a CREATE TABLE, with its Entries and its highest severity
+func (diff TableDiff) MarshalJSON() ([]byte, error) {
+	from, to := diff.table(diff.from), diff.table(diff.to)
+	columns := func(table *Table, cts []gorm.ColumnType) (defs []string) {
+		for _, ct := range cts {
+			defs = append(defs, columnSQL(table, ct))
+		}
+		return defs
+	}
+	indexes := func(idxs []gorm.Index) (defs []string) {
+		for _, idx := range idxs {
+			defs = append(defs, IndexSQL(idx))
+		}
+		return defs
+	}
+	j := tableDiffJSON{
+		Name:               diff.Name,
+		Severity:           diff.Severity(),
+		Entries:            diff.Entries(),
+		Changes:            diff.Changes,
+		AddedColumns:       columns(to, diff.AddedColumns),
+		RemovedColumns:     columns(from, diff.RemovedColumns),
+		AddedIndexes:       indexes(diff.AddedIndexes),
+		RemovedIndexes:     indexes(diff.RemovedIndexes),
+		AddedForeignKeys:   diff.AddedForeignKeys,
+		RemovedForeignKeys: diff.RemovedForeignKeys,
+	}
+	for _, column := range diff.ChangedColumns {
+		j.ChangedColumns = append(j.ChangedColumns, columnDiffJSON{
+			Name: column.Name, From: columnSQL(from, column.From), To: columnSQL(to, column.To), Changes: column.Changes,
+		})
+	}
+	for _, idx := range diff.ChangedIndexes {
+		j.ChangedIndexes = append(j.ChangedIndexes, indexDiffJSON{Name: idx.Name, From: IndexSQL(idx.From), To: IndexSQL(idx.To)})
+	}
+	return json.Marshal(j)
+}
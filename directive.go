@@ -0,0 +1,222 @@
+package rawsql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// ignoreDirective matches the comment lines making the parser skip sql it
+// cannot handle: -- rawsql:ignore skips the statement that follows, the
+// lines between -- rawsql:ignore-begin and -- rawsql:ignore-end, or the end
+// of the sql, are skipped as a whole. Streamed Readers are parsed a statement
+// at a time and only honor -- rawsql:ignore.
+var ignoreDirective = regexp.MustCompile(`(?m)^[ \t]*--[ \t]+rawsql:(ignore|ignore-begin|ignore-end)[ \t\r]*$`)
+
+// sqlSegment is a part of sql, ignored when a directive says so
+type sqlSegment struct {
+	text    string
+	line    int // of the start of text in the sql
+	ignored bool
+}
+
+// ignoredStmt stands for a statement skipped by an ignore directive
+type ignoredStmt struct {
+	ast.StmtNode
+}
+
+// directiveSegments splits sql into the parts to parse and the parts ignore
+// directives skip, nil when it has no directive
+func directiveSegments(sql string) []sqlSegment {
+	if !strings.Contains(sql, "rawsql:ignore") {
+		return nil
+	}
+	matches := ignoreDirective.FindAllStringSubmatchIndex(sql, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	tokens := sqlTokens(sql)
+	// directives inside a string or a block comment are text
+	quoted := func(offset int) bool {
+		end := 0
+		for _, tok := range tokens {
+			if tok.start > offset {
+				break
+			}
+			if end = tok.end; offset < end {
+				return true
+			}
+		}
+		// comments are no tokens, a block comment would start after the last one
+		gap := sql[end:offset]
+		i := strings.LastIndex(gap, "/*")
+		return i >= 0 && !strings.Contains(gap[i:], "*/")
+	}
+
+	var segments []sqlSegment
+	add := func(start, end int, ignored bool) {
+		if start < end {
+			segments = append(segments, sqlSegment{text: sql[start:end], line: strings.Count(sql[:start], "\n") + 1, ignored: ignored})
+		}
+	}
+	last, begin := 0, -1
+	for _, m := range matches {
+		if m[0] < last || quoted(m[0]) {
+			continue
+		}
+		switch kind := sql[m[2]:m[3]]; {
+		case begin >= 0:
+			if kind == "ignore-end" {
+				add(begin, m[0], true)
+				last, begin = m[1], -1
+			}
+		case kind == "ignore-begin":
+			add(last, m[0], false)
+			begin = m[1]
+		case kind == "ignore":
+			add(last, m[0], false)
+			end := len(sql)
+			for _, tok := range tokens {
+				if tok.start > m[1] && tok.kind == 'p' && tok.text == ";" {
+					end = tok.end
+					break
+				}
+			}
+			add(m[1], end, true)
+			last = end
+		}
+	}
+	if begin >= 0 {
+		add(begin, len(sql), true)
+	} else {
+		add(last, len(sql), false)
+	}
+	return segments
+}
+
+// ignoredStmts returns a statement per statement of seg
+func ignoredStmts(seg sqlSegment) []ast.StmtNode {
+	var stmtNodes []ast.StmtNode
+	for _, span := range statementSpans(seg.text) {
+		ignored := &ignoredStmt{StmtNode: &ast.DoStmt{}}
+		ignored.SetText(nil, seg.text[span.start:span.end])
+		stmtNodes = append(stmtNodes, ignored)
+	}
+	return stmtNodes
+}
+
+// directiveLine matches the -- rawsql:name=value comment lines attaching
+// metadata to the table of the statement they precede, or to the column
+// whose definition follows them within the statement
+var directiveLine = regexp.MustCompile(`(?m)^[ \t]*--[ \t]+rawsql:([a-z][a-z0-9-]*)=([^\r\n]*)$`)
+
+// The directives model generation understands
+const (
+	DirectiveModelName = "model-name" // Go type name of the model of a table, see GenModelName
+	DirectiveFieldName = "field-name" // Go field name of a column, see GenFieldNames
+	DirectiveFieldType = "field-type" // Go type of a column, see GenFieldType
+)
+
+// notColumns are the words starting the definitions of a CREATE TABLE that
+// are no column
+var notColumns = wordSet("PRIMARY KEY INDEX UNIQUE CONSTRAINT FOREIGN CHECK FULLTEXT SPATIAL PARTITION")
+
+// stmtDirectives returns the directives of a CREATE or ALTER TABLE: those
+// before its first token belong to the table, the others to the column
+// defined next, by lower cased column name. stray are the directives no
+// column definition follows.
+func stmtDirectives(text string) (table map[string]string, columns map[string]map[string]string, stray []string) {
+	if !strings.Contains(text, "rawsql:") {
+		return nil, nil, nil
+	}
+	matches := directiveLine.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return nil, nil, nil
+	}
+	tokens := sqlTokens(text)
+	names := columnNameTokens(tokens)
+	for _, m := range matches {
+		name, value := text[m[2]:m[3]], strings.TrimSpace(text[m[4]:m[5]])
+		if len(tokens) == 0 || m[0] < tokens[0].start {
+			if table == nil {
+				table = make(map[string]string)
+			}
+			table[name] = value
+			continue
+		}
+		column := ""
+		for _, tok := range names {
+			if tok.start > m[0] {
+				column = strings.ToLower(tok.text)
+				break
+			}
+		}
+		if column == "" {
+			stray = append(stray, name)
+			continue
+		}
+		if columns == nil {
+			columns = make(map[string]map[string]string)
+		}
+		if columns[column] == nil {
+			columns[column] = make(map[string]string)
+		}
+		columns[column][name] = value
+	}
+	return table, columns, stray
+}
+
+// columnNameTokens returns the names of the columns a CREATE TABLE defines,
+// or the ADD, MODIFY and CHANGE COLUMN clauses of an ALTER TABLE
+func columnNameTokens(tokens []sqlToken) (names []sqlToken) {
+	if len(tokens) == 0 {
+		return nil
+	}
+	create := tokens[0].is("CREATE")
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok.kind == 'p' && tok.text == "(":
+			depth++
+			if create && depth == 1 && i+1 < len(tokens) {
+				names = appendColumnName(names, tokens[i+1])
+			}
+		case tok.kind == 'p' && tok.text == ")":
+			depth--
+		case create && depth == 1 && tok.kind == 'p' && tok.text == "," && i+1 < len(tokens):
+			names = appendColumnName(names, tokens[i+1])
+		case !create && depth == 0 && (tok.is("ADD") || tok.is("MODIFY") || tok.is("CHANGE")):
+			j := i + 1
+			if j < len(tokens) && tokens[j].is("COLUMN") {
+				j++
+			}
+			if tok.is("CHANGE") {
+				j++
+			}
+			if j < len(tokens) {
+				names = appendColumnName(names, tokens[j])
+			}
+		}
+	}
+	return names
+}
+
+func appendColumnName(names []sqlToken, tok sqlToken) []sqlToken {
+	if tok.kind == 'q' || tok.kind == 'w' && !notColumns[strings.ToUpper(tok.text)] {
+		names = append(names, tok)
+	}
+	return names
+}
+
+// applyDirectives reads the directives of a CREATE or ALTER TABLE, keeping
+// those of its columns for getColumnType, and returns those of the table
+func (d *defaultParser) applyDirectives(node ast.StmtNode) map[string]string {
+	table, columns, stray := stmtDirectives(node.Text())
+	d.directives = columns
+	for _, name := range stray {
+		d.warn(node, fmt.Sprintf("rawsql:%s directive is not followed by a column definition", name))
+	}
+	return table
+}
@@ -0,0 +1,81 @@
+package rawsql
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// applyDrop drops the tables of a DROP TABLE, all or none of them as MySQL
+// does: without IF EXISTS a single missing table fails the whole statement
+func (d *defaultParser) applyDrop(drop *ast.DropTableStmt) {
+	if drop.IsView {
+		d.stats.Skipped++
+		d.warn(drop, "unsupported statement")
+		return
+	}
+
+	names := make([]string, 0, len(drop.Tables))
+	for _, table := range drop.Tables {
+		name := table.Name.String()
+		if _, ok := d.temporary[name]; ok {
+			delete(d.temporary, name)
+			continue
+		}
+		if drop.TemporaryKeyword != ast.TemporaryNone && d.config.SkipTemporaryTables || d.isGhostTable(name) {
+			continue
+		}
+		if _, has := d.tables[name]; !has {
+			if !drop.IfExists {
+				panic(fmt.Sprintf("table %s not exists", name))
+			}
+			d.notice(drop, fmt.Sprintf("unknown table %s", name))
+			continue
+		}
+		names = append(names, name)
+	}
+
+	cascade := false
+	for _, tok := range sqlTokens(drop.Text()) {
+		cascade = cascade || tok.is("CASCADE")
+	}
+	for _, name := range names {
+		delete(d.tables, name)
+	}
+	for _, name := range names {
+		d.dropReferences(drop, name, cascade)
+	}
+}
+
+// dropReferences handles the foreign keys of the remaining tables referencing
+// the dropped table name according to Config.DroppedReferences
+func (d *defaultParser) dropReferences(drop *ast.DropTableStmt, name string, cascade bool) {
+	remove := cascade || d.config.DroppedReferences == RemoveForeignKeys
+	tables := make([]string, 0, len(d.tables))
+	for t := range d.tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		table := d.tables[t]
+		kept := table.ForeignKeys[:0]
+		for _, fk := range table.ForeignKeys {
+			switch {
+			case fk.ReferencedTable != name:
+				kept = append(kept, fk)
+			case remove:
+				d.notice(drop, fmt.Sprintf("foreign key %s of %s removed with table %s", fk.Name, table.Name, name))
+			default:
+				if !d.foreignKeyChecksOff || !d.config.HonorForeignKeyChecks {
+					d.warn(drop, fmt.Sprintf("foreign key %s of %s references dropped table %s", fk.Name, table.Name, name))
+				}
+				kept = append(kept, fk)
+			}
+		}
+		if len(kept) == 0 {
+			kept = nil
+		}
+		table.ForeignKeys = kept
+	}
+}
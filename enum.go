@@ -0,0 +1,273 @@
+package rawsql
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// GenEnumName returns the Go type name generated for the ENUM or SET column
+// ct of table: the type of its -- rawsql:field-type=T directive, or the names
+// of the model and the column, e.g. OrdersStatus for orders.status
+func GenEnumName(table *Table, ct gorm.ColumnType) string {
+	if c, ok := ct.(*ColumnType); ok && c.DirectivesValue[DirectiveFieldType] != "" {
+		return c.DirectivesValue[DirectiveFieldType]
+	}
+	model := GenModelName(table)
+	if model == "" {
+		model = goName(table.Name)
+	}
+	return model + goName(ct.Name())
+}
+
+// GenEnum returns the Go source file of package pkg declaring typeName, a
+// string type holding the values of the ENUM column ct: a constant per value,
+// e.g. OrderStatusPaid, the list of values, and the driver.Valuer and
+// sql.Scanner implementations rejecting other values, so fields of the type
+// stay within the column. Nullable columns take a *typeName field, Scan
+// rejects NULL.
+func GenEnum(pkg, typeName string, ct gorm.ColumnType) ([]byte, error) {
+	c, ok := ct.(*ColumnType)
+	if !ok || ct.DatabaseTypeName() != "enum" {
+		return nil, fmt.Errorf("rawsql: %s is no ENUM column", ct.Name())
+	}
+	values, _ := c.EnumValues()
+	if len(values) == 0 {
+		return nil, fmt.Errorf("rawsql: ENUM column %s has no values", ct.Name())
+	}
+	names := enumConstNames(typeName, values)
+
+	var buf bytes.Buffer
+	genHeader(&buf, pkg, "ENUM", ct, "database/sql/driver", "fmt")
+	fmt.Fprintf(&buf, "// %s is a value of the ENUM column %s\ntype %s string\n\n", typeName, ct.Name(), typeName)
+	buf.WriteString("const (\n")
+	for i, value := range values {
+		fmt.Fprintf(&buf, "\t%s %s = %s\n", names[i], typeName, strconv.Quote(value))
+	}
+	buf.WriteString(")\n\n")
+	fmt.Fprintf(&buf, "// %sValues are the values of %s in declaration order\n", typeName, typeName)
+	fmt.Fprintf(&buf, "var %sValues = []%s{%s}\n\n", typeName, typeName, strings.Join(names, ", "))
+	fmt.Fprintf(&buf, `// Valid reports whether v is a value of the column
+func (v %[1]s) Valid() bool {
+	switch v {
+	case %[2]s:
+		return true
+	}
+	return false
+}
+
+// Value implements driver.Valuer
+func (v %[1]s) Value() (driver.Value, error) {
+	if !v.Valid() {
+		return nil, fmt.Errorf("invalid %[1]s %%q", string(v))
+	}
+	return string(v), nil
+}
+
+// Scan implements sql.Scanner
+func (v *%[1]s) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return fmt.Errorf("cannot scan %%T into %[1]s", src)
+	}
+	if !%[1]s(s).Valid() {
+		return fmt.Errorf("invalid %[1]s %%q", s)
+	}
+	*v = %[1]s(s)
+	return nil
+}
+`, typeName, strings.Join(names, ", "))
+	return format.Source(buf.Bytes())
+}
+
+// GenSet returns the Go source file of package pkg declaring typeName, a
+// bitmask of the values of the SET column ct: a constant per value, e.g.
+// PostFlagsPinned, the Has, Add and Remove helpers, and the driver.Valuer and
+// sql.Scanner implementations reading and writing the comma separated values
+// MySQL takes, in declaration order. Scan also takes the integer a SET
+// column is in numeric context and rejects values the column does not have.
+func GenSet(pkg, typeName string, ct gorm.ColumnType) ([]byte, error) {
+	c, ok := ct.(*ColumnType)
+	if !ok || ct.DatabaseTypeName() != "set" {
+		return nil, fmt.Errorf("rawsql: %s is no SET column", ct.Name())
+	}
+	values, _ := c.EnumValues()
+	if len(values) == 0 || len(values) > 64 {
+		return nil, fmt.Errorf("rawsql: SET column %s has %d values, not 1 to 64", ct.Name(), len(values))
+	}
+	names := enumConstNames(typeName, values)
+	text := strings.ToLower(typeName[:1]) + typeName[1:] + "Text"
+
+	var buf bytes.Buffer
+	genHeader(&buf, pkg, "SET", ct, "database/sql/driver", "fmt", "strings")
+	fmt.Fprintf(&buf, "// %s is a set of the values of the SET column %s, a bit per value\ntype %s uint64\n\n", typeName, ct.Name(), typeName)
+	buf.WriteString("const (\n")
+	for i, name := range names {
+		if i == 0 {
+			fmt.Fprintf(&buf, "\t%s %s = 1 << iota\n", name, typeName)
+		} else {
+			fmt.Fprintf(&buf, "\t%s\n", name)
+		}
+	}
+	buf.WriteString(")\n\n")
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	fmt.Fprintf(&buf, "// %s are the values of the bits of %s, lowest first\n", text, typeName)
+	fmt.Fprintf(&buf, "var %s = [...]string{%s}\n\n", text, strings.Join(quoted, ", "))
+	fmt.Fprintf(&buf, `// Has reports whether s holds every value of flags
+func (s %[1]s) Has(flags %[1]s) bool {
+	return s&flags == flags
+}
+
+// Add returns s with the values of flags
+func (s %[1]s) Add(flags %[1]s) %[1]s {
+	return s | flags
+}
+
+// Remove returns s without the values of flags
+func (s %[1]s) Remove(flags %[1]s) %[1]s {
+	return s &^ flags
+}
+
+// Valid reports whether s only holds values of the column
+func (s %[1]s) Valid() bool {
+	return s&^(%[2]s) == 0
+}
+
+// Values returns the values s holds in declaration order
+func (s %[1]s) Values() []string {
+	var values []string
+	for i, value := range %[3]s {
+		if s&(1<<uint(i)) != 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// String returns the values s holds separated by commas, as MySQL writes
+// them
+func (s %[1]s) String() string {
+	return strings.Join(s.Values(), ",")
+}
+
+// Parse%[1]s reads the comma separated values of a %[1]s, compared
+// case-insensitively as MySQL compares them
+func Parse%[1]s(text string) (%[1]s, error) {
+	var s %[1]s
+	if text == "" {
+		return s, nil
+	}
+next:
+	for _, value := range strings.Split(text, ",") {
+		for i, v := range %[3]s {
+			if strings.EqualFold(v, value) {
+				s |= 1 << uint(i)
+				continue next
+			}
+		}
+		return 0, fmt.Errorf("invalid %[1]s value %%q", value)
+	}
+	return s, nil
+}
+
+// Value implements driver.Valuer
+func (s %[1]s) Value() (driver.Value, error) {
+	if !s.Valid() {
+		return nil, fmt.Errorf("invalid %[1]s %%d", uint64(s))
+	}
+	return s.String(), nil
+}
+
+// Scan implements sql.Scanner
+func (s *%[1]s) Scan(src interface{}) error {
+	var v %[1]s
+	var err error
+	switch src := src.(type) {
+	case string:
+		v, err = Parse%[1]s(src)
+	case []byte:
+		v, err = Parse%[1]s(string(src))
+	case int64:
+		if v = %[1]s(src); !v.Valid() {
+			err = fmt.Errorf("invalid %[1]s %%d", src)
+		}
+	default:
+		err = fmt.Errorf("cannot scan %%T into %[1]s", src)
+	}
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+`, typeName, strings.Join(names, " | "), text)
+	return format.Source(buf.Bytes())
+}
+
+// genHeader writes the start of a file generated for the column ct of kind
+// ENUM or SET, importing imports
+func genHeader(buf *bytes.Buffer, pkg, kind string, ct gorm.ColumnType, imports ...string) {
+	columnType, _ := ct.ColumnType()
+	fmt.Fprintf(buf, "// Code generated by rawsql from the %s column %s %s. DO NOT EDIT.\n\n", kind, ct.Name(), columnType)
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	buf.WriteString("import (\n")
+	for _, path := range imports {
+		fmt.Fprintf(buf, "\t%q\n", path)
+	}
+	buf.WriteString(")\n\n")
+}
+
+// enumConstNames returns the constant names of values: typeName followed by
+// the value in camel case, Empty for the empty value and the position of the
+// value when it has no letter or digit or its name is taken
+func enumConstNames(typeName string, values []string) []string {
+	names := make([]string, len(values))
+	taken := make(map[string]bool, len(values))
+	for i, value := range values {
+		name := goName(value)
+		switch {
+		case value == "":
+			name = "Empty"
+		case name == "":
+			name = "Value" + strconv.Itoa(i+1)
+		}
+		if taken[name] {
+			name += strconv.Itoa(i + 1)
+		}
+		taken[name] = true
+		names[i] = typeName + name
+	}
+	return names
+}
+
+// goName turns a sql name or value into an exported Go name, e.g. OrderItems
+// for order_items
+func goName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+		}
+		b.WriteRune(r)
+		upper = false
+	}
+	return b.String()
+}
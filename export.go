@@ -0,0 +1,274 @@
+package rawsql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ExportIndexFile lists the files written by Export, one per line in an order
+// they can be executed in
+const ExportIndexFile = "index.txt"
+
+// Export writes a normalized CREATE TABLE statement per table into dir, named
+// after the table, and ExportIndexFile listing them with referenced tables
+// first, or by name when foreign keys form a cycle. Existing files of the same
+// names are overwritten.
+func Export(dir string, tables map[string]*Table) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	ordered, err := TablesInDependencyOrder(tables)
+	if err != nil {
+		ordered = ordered[:0]
+		for _, name := range NewGraph(tables).Tables() {
+			ordered = append(ordered, tables[name])
+		}
+	}
+	var index strings.Builder
+	for _, table := range ordered {
+		name := exportFileName(table.Name)
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(CreateTableSQL(table)), 0o644); err != nil {
+			return err
+		}
+		index.WriteString(name + "\n")
+	}
+	return ioutil.WriteFile(filepath.Join(dir, ExportIndexFile), []byte(index.String()), 0o644)
+}
+
+func exportFileName(table string) string {
+	return strings.NewReplacer("/", "_", `\`, "_").Replace(table) + ".sql"
+}
+
+// CreateTableSQL returns a normalized CREATE TABLE statement of table: ALTER
+// TABLE statements are folded in, charsets and collations inherited from the
+// table are left out and so is the AUTO_INCREMENT counter.
+func CreateTableSQL(table *Table) string {
+	defs := make([]string, 0, len(table.ColumnTypes)+len(table.Indexes)+len(table.ForeignKeys))
+	hasPrimaryIndex := false
+	for _, idx := range table.Indexes {
+		if pk, _ := idx.PrimaryKey(); pk {
+			hasPrimaryIndex = true
+		}
+	}
+	for _, ct := range table.ColumnTypes {
+		def := columnSQL(table, ct)
+		// a primary key declared on the column has no index of its own
+		if pk, _ := ct.PrimaryKey(); pk && !hasPrimaryIndex {
+			def += " PRIMARY KEY"
+		}
+		defs = append(defs, def)
+	}
+	for _, idx := range table.Indexes {
+		if len(idx.Columns()) == 0 || foreignKeyIndex(table, idx) {
+			continue
+		}
+		defs = append(defs, IndexSQL(idx))
+	}
+	for _, fk := range table.ForeignKeys {
+		defs = append(defs, foreignKeySQL(fk))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n  %s\n)", quoteName(table.Name), strings.Join(defs, ",\n  "))
+	if table.Engine != "" {
+		b.WriteString(" ENGINE=" + table.Engine)
+	}
+	if table.Charset != "" {
+		b.WriteString(" DEFAULT CHARSET=" + table.Charset)
+	}
+	if table.Collation != "" {
+		b.WriteString(" COLLATE=" + table.Collation)
+	}
+	options := make([]string, 0, len(table.Options))
+	for name := range table.Options {
+		options = append(options, name)
+	}
+	sort.Strings(options)
+	for _, name := range options {
+		if value := table.Options[name]; value != "" {
+			b.WriteString(" " + name + "=" + value)
+		} else {
+			b.WriteString(" " + name)
+		}
+	}
+	if table.Comment != "" {
+		b.WriteString(" COMMENT=" + QuoteString(table.Comment))
+	}
+	b.WriteString(";\n")
+	return b.String()
+}
+
+// IndexSQL returns the definition of idx in a CREATE TABLE, e.g. UNIQUE KEY
+// `uk_name` (`name`(32)). Indexes differing in anything MySQL keeps, such as
+// the prefix lengths of their columns, have different definitions, so two
+// indexes are the same when their IndexSQL is.
+func IndexSQL(idx gorm.Index) string {
+	var lengths []int
+	if idx, ok := idx.(*Index); ok {
+		lengths = idx.PrefixLengths()
+	}
+	columns := make([]string, 0, len(idx.Columns()))
+	for i, column := range idx.Columns() {
+		if i < len(lengths) && lengths[i] > 0 {
+			column = fmt.Sprintf("%s(%d)", quoteName(column), lengths[i])
+		} else {
+			column = quoteName(column)
+		}
+		columns = append(columns, column)
+	}
+	pk, _ := idx.PrimaryKey()
+	unique, _ := idx.Unique()
+	name, list, prefix, class := idx.Name(), "("+strings.Join(columns, ", ")+")", "", ""
+	if idx, ok := idx.(*Index); ok {
+		class = idx.Class()
+		if idx.Constraint() != "" {
+			prefix = "CONSTRAINT " + quoteName(idx.Constraint()) + " "
+			if pk || name == idx.Constraint() {
+				name = ""
+			}
+		}
+		if idx.Using() != "" {
+			list += " USING " + idx.Using()
+		}
+		if size, ok := idx.KeyBlockSize(); ok {
+			list += fmt.Sprintf(" KEY_BLOCK_SIZE=%d", size)
+		}
+		if idx.Parser() != "" {
+			list += " WITH PARSER " + quoteName(idx.Parser())
+		}
+		if idx.Comment() != "" {
+			list += " COMMENT " + QuoteString(idx.Comment())
+		}
+	}
+	if name != "" {
+		list = quoteName(name) + " " + list
+	}
+	switch {
+	case pk:
+		return prefix + "PRIMARY KEY " + list
+	case unique:
+		return prefix + "UNIQUE KEY " + list
+	case class != "":
+		return class + " KEY " + list
+	}
+	return "KEY " + list
+}
+
+func foreignKeySQL(fk ForeignKey) string {
+	def := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteName(fk.Name), quoteNames(fk.Columns), quoteName(fk.ReferencedTable), quoteNames(fk.ReferencedColumns))
+	if fk.OnDelete != "" {
+		def += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" {
+		def += " ON UPDATE " + fk.OnUpdate
+	}
+	return def
+}
+
+// foreignKeyIndex reports whether idx is the one a foreign key constraint
+// creates, it comes back from the CONSTRAINT clause
+func foreignKeyIndex(table *Table, idx gorm.Index) bool {
+	if pk, _ := idx.PrimaryKey(); pk {
+		return false
+	}
+	if unique, _ := idx.Unique(); unique {
+		return false
+	}
+	for _, fk := range table.ForeignKeys {
+		if fk.Name == idx.Name() && reflect.DeepEqual(fk.Columns, idx.Columns()) {
+			return true
+		}
+	}
+	return false
+}
+
+func columnSQL(table *Table, ct gorm.ColumnType) string {
+	columnType, _ := ct.ColumnType()
+	// integer display widths are filled in by the parser, written out they
+	// would read back as a decimal size
+	if precision, _, ok := ct.DecimalSize(); !ok || precision < 0 {
+		if i := strings.Index(columnType, "("); i > 0 && integerTypes[strings.ToLower(ct.DatabaseTypeName())] {
+			if j := strings.Index(columnType[i:], ")"); j > 0 {
+				columnType = columnType[:i] + columnType[i+j+1:]
+			}
+		}
+	}
+	def := []string{quoteName(ct.Name()), columnType}
+	if c, ok := ct.(*ColumnType); ok {
+		charset, _ := c.Charset()
+		collation, _ := c.Collation()
+		switch {
+		case charset != "" && charset != table.Charset:
+			def = append(def, "CHARACTER SET "+charset)
+			if collation != "" {
+				def = append(def, "COLLATE "+collation)
+			}
+		case collation != "" && collation != table.Collation:
+			def = append(def, "COLLATE "+collation)
+		}
+	}
+	if c, ok := ct.(*ColumnType); ok {
+		if srid, ok := c.SRID(); ok {
+			def = append(def, fmt.Sprintf("SRID %d", srid))
+		}
+		if expression, stored, ok := c.Generated(); ok {
+			if stored {
+				def = append(def, "AS ("+expression+") STORED")
+			} else {
+				def = append(def, "AS ("+expression+") VIRTUAL")
+			}
+		}
+	}
+	if nullable, ok := ct.Nullable(); ok && !nullable {
+		def = append(def, "NOT NULL")
+	}
+	if value, ok := ct.DefaultValue(); ok {
+		var kind DefaultKind
+		if c, ok := ct.(*ColumnType); ok {
+			kind = c.DefaultKind()
+		}
+		def = append(def, "DEFAULT "+defaultSQL(value, kind, strings.ToLower(ct.DatabaseTypeName())))
+	}
+	if c, ok := ct.(*ColumnType); ok && c.OnUpdate() != "" {
+		def = append(def, "ON UPDATE "+c.OnUpdate())
+	}
+	if autoIncrement, _ := ct.AutoIncrement(); autoIncrement {
+		def = append(def, "AUTO_INCREMENT")
+	}
+	if unique, _ := ct.Unique(); unique {
+		def = append(def, "UNIQUE")
+	}
+	if c, ok := ct.(*ColumnType); ok {
+		if c.Invisible() {
+			def = append(def, "INVISIBLE")
+		}
+		if c.ColumnFormat() != "" {
+			def = append(def, "COLUMN_FORMAT "+c.ColumnFormat())
+		}
+		if c.Storage() != "" {
+			def = append(def, "STORAGE "+c.Storage())
+		}
+	}
+	if comment, ok := ct.Comment(); ok && comment != "" {
+		def = append(def, "COMMENT "+QuoteString(comment))
+	}
+	return strings.Join(def, " ")
+}
+
+var integerTypes = map[string]bool{"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true, "bigint": true}
+
+func quoteNames(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, quoteName(name))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,95 @@
+package rawsql
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/model"
+)
+
+// ForeignKeyPolicy decides what happens to the foreign keys referencing a
+// dropped table
+type ForeignKeyPolicy int
+
+const (
+	KeepForeignKeys   ForeignKeyPolicy = iota // keep them and report a warning, as MySQL refuses such drops with FOREIGN_KEY_CHECKS on
+	RemoveForeignKeys                         // remove them from the referencing tables, DROP TABLE ... CASCADE always does
+)
+
+func (d *defaultParser) getForeignKeys(create *ast.CreateTableStmt) []ForeignKey {
+	table := create.Table.Name.String()
+	fks := make([]ForeignKey, 0)
+	for _, col := range create.Cols {
+		fks = d.columnForeignKeys(fks, table, col)
+	}
+	for _, cons := range create.Constraints {
+		if cons.Tp != ast.ConstraintForeignKey || cons.Refer == nil {
+			continue
+		}
+		fk := d.foreignKey(foreignKeyName(cons.Name, table, fks), cons.Refer)
+		for _, key := range cons.Keys {
+			if key.Column != nil {
+				fk.Columns = append(fk.Columns, d.intern(key.Column.Name.String()))
+			}
+		}
+		fks = append(fks, fk)
+	}
+	if len(fks) == 0 {
+		return nil
+	}
+	return fks
+}
+
+// columnForeignKeys appends the foreign keys declared inline by a column, e.g.
+// user_id BIGINT REFERENCES users(id)
+func (d *defaultParser) columnForeignKeys(fks []ForeignKey, table string, col *ast.ColumnDef) []ForeignKey {
+	for _, opt := range col.Options {
+		if opt.Tp != ast.ColumnOptionReference || opt.Refer == nil {
+			continue
+		}
+		fk := d.foreignKey(foreignKeyName("", table, fks), opt.Refer)
+		fk.Columns = append(fk.Columns, d.intern(col.Name.Name.String()))
+		fks = append(fks, fk)
+	}
+	return fks
+}
+
+// foreignKeyName names unnamed foreign keys the way InnoDB does, table_ibfk_N
+func foreignKeyName(name, table string, fks []ForeignKey) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("%s_ibfk_%d", table, len(fks)+1)
+}
+
+func (d *defaultParser) foreignKey(name string, refer *ast.ReferenceDef) ForeignKey {
+	fk := ForeignKey{Name: d.intern(name), Columns: []string{}, ReferencedColumns: []string{}}
+	if refer.Table != nil {
+		fk.ReferencedTable = d.intern(refer.Table.Name.String())
+	}
+	for _, key := range refer.IndexPartSpecifications {
+		if key.Column != nil {
+			fk.ReferencedColumns = append(fk.ReferencedColumns, d.intern(key.Column.Name.String()))
+		}
+	}
+	if refer.OnDelete != nil && refer.OnDelete.ReferOpt != model.ReferOptionNoOption {
+		fk.OnDelete = refer.OnDelete.ReferOpt.String()
+	}
+	if refer.OnUpdate != nil && refer.OnUpdate.ReferOpt != model.ReferOptionNoOption {
+		fk.OnUpdate = refer.OnUpdate.ReferOpt.String()
+	}
+	return fk
+}
+
+// checkForeignKeys warns about foreign keys of table referencing tables not
+// created yet, unless FOREIGN_KEY_CHECKS was turned off and the config honors it
+func (d *defaultParser) checkForeignKeys(node ast.StmtNode, table *Table) {
+	if d.foreignKeyChecksOff && d.config.HonorForeignKeyChecks {
+		return
+	}
+	for _, fk := range table.ForeignKeys {
+		if _, ok := d.tables[fk.ReferencedTable]; !ok && fk.ReferencedTable != table.Name {
+			d.warn(node, fmt.Sprintf("foreign key %s references unknown table %s", fk.Name, fk.ReferencedTable))
+		}
+	}
+}
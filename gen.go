@@ -0,0 +1,69 @@
+package rawsql
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GenFieldType returns the Go type of a parsed column as gorm.io/gen expects
+// it in gen.FieldType(column, type), e.g. uint64 for bigint unsigned and bool
+// for tinyint(1), types gen does not infer from the column type itself, or the
+// type of a -- rawsql:field-type=T directive. It is empty when the column has
+// no scan type; nullable columns are only made pointers or sql.Null types
+// under Config.NullableScanType, gen's FieldNullable does that otherwise.
+//
+// rawsql does not import gen, the options are built by the caller:
+//
+//	for column, typ := range rawsql.GenFieldTypes(table) {
+//		opts = append(opts, gen.FieldType(column, typ))
+//	}
+func GenFieldType(ct gorm.ColumnType) string {
+	if c, ok := ct.(*ColumnType); ok && c.DirectivesValue[DirectiveFieldType] != "" {
+		return c.DirectivesValue[DirectiveFieldType]
+	}
+	if t := ct.ScanType(); t != nil {
+		return t.String()
+	}
+	return ""
+}
+
+// GenFieldTypes returns the GenFieldType of every column of table by column name
+func GenFieldTypes(table *Table) map[string]string {
+	types := make(map[string]string, len(table.ColumnTypes))
+	for _, ct := range table.ColumnTypes {
+		if t := GenFieldType(ct); t != "" {
+			types[ct.Name()] = t
+		}
+	}
+	return types
+}
+
+// GenModelName returns the model name a -- rawsql:model-name=Name directive
+// gives table, to feed g.GenerateModelAs, empty when there is none
+func GenModelName(table *Table) string {
+	return table.Directives[DirectiveModelName]
+}
+
+// GenFieldNames returns the field names -- rawsql:field-name=Name directives
+// give the columns of table by column name, to feed gen.FieldRename
+func GenFieldNames(table *Table) map[string]string {
+	names := make(map[string]string)
+	for _, ct := range table.ColumnTypes {
+		if c, ok := ct.(*ColumnType); ok && c.DirectivesValue[DirectiveFieldName] != "" {
+			names[ct.Name()] = c.DirectivesValue[DirectiveFieldName]
+		}
+	}
+	return names
+}
+
+// GenFieldTags returns the GormTag of every column of table by column name,
+// followed by its UniqueIndexTags, to feed gen.FieldGORMTag
+func GenFieldTags(table *Table) map[string]string {
+	tags := make(map[string]string, len(table.ColumnTypes))
+	unique := UniqueIndexTags(table)
+	for _, ct := range table.ColumnTypes {
+		tags[ct.Name()] = strings.Join(append([]string{GormTag(ct)}, unique[ct.Name()]...), ";")
+	}
+	return tags
+}
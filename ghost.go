@@ -0,0 +1,38 @@
+package rawsql
+
+import "path"
+
+// DefaultGhostTablePatterns match the tables gh-ost and pt-online-schema-change
+// leave behind while migrating a table, e.g. _users_gho and _users_old
+var DefaultGhostTablePatterns = []string{"_*_gho", "_*_ghc", "_*_del", "_*_old", "_*_new"}
+
+func (config *Config) ghostTablePatterns() []string {
+	if config.GhostTablePatterns != nil {
+		return config.GhostTablePatterns
+	}
+	return DefaultGhostTablePatterns
+}
+
+// validateGhostTablePatterns reports the first malformed pattern
+func (config *Config) validateGhostTablePatterns() error {
+	for _, pattern := range config.GhostTablePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isGhostTable reports whether statements on name should be ignored because
+// of Config.SkipGhostTables
+func (d *defaultParser) isGhostTable(name string) bool {
+	if !d.config.SkipGhostTables {
+		return false
+	}
+	for _, pattern := range d.config.ghostTablePatterns() {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,10 @@ module gorm.io/rawsql
 go 1.18
 
 require (
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/pingcap/tidb/pkg/parser v0.0.0-20240407083020-62d6f4737bfb
 	gorm.io/gorm v1.25.2
+	gorm.io/rawsql/meta v0.0.0
 )
 
 require (
@@ -19,6 +21,13 @@ require (
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.26.0 // indirect
 	golang.org/x/exp v0.0.0-20240205201215-2c58cdc269a3 // indirect
+	golang.org/x/sys v0.5.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 )
+
+// gorm.io/rawsql/meta is tagged meta/vX.Y.Z before rawsql is tagged and the
+// requirement above raised to that tag, see Releasing in README.md. The
+// replace only applies inside this repository, builds of a tagged rawsql
+// resolve the required meta tag.
+replace gorm.io/rawsql/meta => ./meta
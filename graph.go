@@ -0,0 +1,144 @@
+package rawsql
+
+import "sort"
+
+// Edge is a foreign key of table From referencing table To
+type Edge struct {
+	From       string
+	To         string
+	ForeignKey ForeignKey
+}
+
+// Graph is the foreign key graph of a set of tables, references to tables
+// outside the set are left out
+type Graph struct {
+	tables       []string
+	edges        []Edge
+	references   map[string][]string // the other tables a table references
+	referencedBy map[string][]string // the other tables referencing a table
+}
+
+// Graph returns the foreign key graph of the tables of the last load or watch
+// reload
+func (dialector Dialector) Graph() *Graph {
+	if dialector.store == nil {
+		return NewGraph(nil)
+	}
+	return NewGraph(dialector.store.get())
+}
+
+// NewGraph builds the foreign key graph of tables
+func NewGraph(tables map[string]*Table) *Graph {
+	g := &Graph{
+		tables:       make([]string, 0, len(tables)),
+		edges:        make([]Edge, 0),
+		references:   make(map[string][]string, len(tables)),
+		referencedBy: make(map[string][]string, len(tables)),
+	}
+	for name := range tables {
+		g.tables = append(g.tables, name)
+	}
+	sort.Strings(g.tables)
+
+	for _, name := range g.tables {
+		seen := map[string]bool{}
+		for _, fk := range tables[name].ForeignKeys {
+			ref := fk.ReferencedTable
+			if _, ok := tables[ref]; !ok {
+				continue
+			}
+			g.edges = append(g.edges, Edge{From: name, To: ref, ForeignKey: fk})
+			if ref == name || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			g.references[name] = append(g.references[name], ref)
+			g.referencedBy[ref] = append(g.referencedBy[ref], name)
+		}
+	}
+	for _, refs := range g.references {
+		sort.Strings(refs)
+	}
+	return g
+}
+
+// Tables returns the names of the tables of the graph, sorted
+func (g *Graph) Tables() []string {
+	return append([]string(nil), g.tables...)
+}
+
+// Edges returns every foreign key between tables of the graph, self
+// references included, sorted by referencing table then declaration order
+func (g *Graph) Edges() []Edge {
+	return append([]Edge(nil), g.edges...)
+}
+
+// Referenced returns the other tables the foreign keys of table reference
+func (g *Graph) Referenced(table string) []string {
+	return append([]string(nil), g.references[table]...)
+}
+
+// Referencing returns the other tables with foreign keys referencing table
+func (g *Graph) Referencing(table string) []string {
+	return append([]string(nil), g.referencedBy[table]...)
+}
+
+// Dependents returns every table referencing table directly or through other
+// tables, i.e. the tables possibly impacted by a change of table, sorted
+func (g *Graph) Dependents(table string) []string {
+	return g.reach(table, g.referencedBy)
+}
+
+// Dependencies returns every table table references directly or through
+// other tables, sorted
+func (g *Graph) Dependencies(table string) []string {
+	return g.reach(table, g.references)
+}
+
+func (g *Graph) reach(table string, next map[string][]string) []string {
+	seen := map[string]bool{table: true}
+	queue := []string{table}
+	found := make([]string, 0)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, n := range next[name] {
+			if !seen[n] {
+				seen[n] = true
+				found = append(found, n)
+				queue = append(queue, n)
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// Components returns the groups of tables connected by foreign keys in either
+// direction, each sorted and ordered by their first table, a table without
+// any foreign key forms a group of its own
+func (g *Graph) Components() [][]string {
+	seen := map[string]bool{}
+	components := make([][]string, 0)
+	for _, table := range g.tables {
+		if seen[table] {
+			continue
+		}
+		seen[table] = true
+		component := []string{table}
+		for i := 0; i < len(component); i++ {
+			name := component[i]
+			for _, next := range [][]string{g.references[name], g.referencedBy[name]} {
+				for _, n := range next {
+					if !seen[n] {
+						seen[n] = true
+						component = append(component, n)
+					}
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	return components
+}
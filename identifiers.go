@@ -0,0 +1,126 @@
+package rawsql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pingcap/tidb/pkg/parser"
+)
+
+// MaxIdentifierLength is the number of characters MySQL and TiDB allow in
+// table, column, index and constraint names
+const MaxIdentifierLength = 64
+
+// ValidateIdentifiers reports the names of the parsed tables, columns, indexes
+// and foreign keys longer than MaxIdentifierLength or colliding with a word
+// reserved by the server of Config.TargetVersion, such names fail or need
+// quoting everywhere they are used
+func (dialector Dialector) ValidateIdentifiers() []Issue {
+	// validated by Initialize
+	version, _ := parseServerVersion(dialector.TargetVersion)
+	return validateIdentifiers(dialector.store.get(), version)
+}
+
+// ValidateIdentifiers is Dialector.ValidateIdentifiers for tables parsed
+// elsewhere, targetVersion is given as in Config.TargetVersion
+func ValidateIdentifiers(tables map[string]*Table, targetVersion string) ([]Issue, error) {
+	version, err := parseServerVersion(targetVersion)
+	if err != nil {
+		return nil, err
+	}
+	return validateIdentifiers(tables, version), nil
+}
+
+func validateIdentifiers(tables map[string]*Table, version serverVersion) (issues []Issue) {
+	reserved := reservedWords(version)
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, table := range names {
+		check := func(kind, name string) {
+			switch {
+			case utf8.RuneCountInString(name) > MaxIdentifierLength:
+				issues = append(issues, Issue{Table: table, Message: fmt.Sprintf("%s name %s is longer than %d characters", kind, name, MaxIdentifierLength)})
+			case reserved[strings.ToUpper(name)]:
+				issues = append(issues, Issue{Table: table, Message: fmt.Sprintf("%s name %s is a reserved word in %s", kind, name, version)})
+			}
+		}
+		t := tables[table]
+		check("table", t.Name)
+		for _, ct := range t.ColumnTypes {
+			check("column", ct.Name())
+		}
+		for _, idx := range t.Indexes {
+			if pk, _ := idx.PrimaryKey(); !pk && idx.Name() != "" {
+				check("index", idx.Name())
+			}
+		}
+		for _, fk := range t.ForeignKeys {
+			check("foreign key", fk.Name)
+		}
+	}
+	return issues
+}
+
+// reservedWords returns the upper case words reserved by the server version
+func reservedWords(version serverVersion) map[string]bool {
+	if version.TiDB {
+		return tidbReservedWords
+	}
+	if version.Major < 8 {
+		return mysql57ReservedWords
+	}
+	return mysql80ReservedWords
+}
+
+var tidbReservedWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, keyword := range parser.Keywords {
+		if keyword.Reserved {
+			words[keyword.Word] = true
+		}
+	}
+	return words
+}()
+
+var mysql57ReservedWords = wordSet(mysqlReservedWords, "ANALYSE")
+
+// mysql80ReservedWords adds the window function and common table expression
+// keywords MySQL 8.0 reserves and drops ANALYSE it removed
+var mysql80ReservedWords = wordSet(mysqlReservedWords, "CUBE", "CUME_DIST", "DENSE_RANK", "EMPTY", "EXCEPT",
+	"FIRST_VALUE", "FUNCTION", "GROUPING", "GROUPS", "JSON_TABLE", "LAG", "LAST_VALUE", "LATERAL", "LEAD",
+	"NTH_VALUE", "NTILE", "OF", "OVER", "PERCENT_RANK", "RANK", "RECURSIVE", "ROW", "ROWS", "ROW_NUMBER",
+	"SYSTEM", "WINDOW")
+
+// mysqlReservedWords are reserved by both MySQL 5.7 and 8.0
+const mysqlReservedWords = `ACCESSIBLE ADD ALL ALTER ANALYZE AND AS ASC ASENSITIVE BEFORE BETWEEN BIGINT BINARY
+BLOB BOTH BY CALL CASCADE CASE CHANGE CHAR CHARACTER CHECK COLLATE COLUMN CONDITION CONSTRAINT CONTINUE
+CONVERT CREATE CROSS CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP CURRENT_USER CURSOR DATABASE DATABASES
+DAY_HOUR DAY_MICROSECOND DAY_MINUTE DAY_SECOND DEC DECIMAL DECLARE DEFAULT DELAYED DELETE DESC DESCRIBE
+DETERMINISTIC DISTINCT DISTINCTROW DIV DOUBLE DROP DUAL EACH ELSE ELSEIF ENCLOSED ESCAPED EXISTS EXIT
+EXPLAIN FALSE FETCH FLOAT FLOAT4 FLOAT8 FOR FORCE FOREIGN FROM FULLTEXT GENERATED GET GRANT GROUP HAVING
+HIGH_PRIORITY HOUR_MICROSECOND HOUR_MINUTE HOUR_SECOND IF IGNORE IN INDEX INFILE INNER INOUT INSENSITIVE
+INSERT INT INT1 INT2 INT3 INT4 INT8 INTEGER INTERVAL INTO IO_AFTER_GTIDS IO_BEFORE_GTIDS IS ITERATE JOIN
+KEY KEYS KILL LEADING LEAVE LEFT LIKE LIMIT LINEAR LINES LOAD LOCALTIME LOCALTIMESTAMP LOCK LONG LONGBLOB
+LONGTEXT LOOP LOW_PRIORITY MASTER_BIND MASTER_SSL_VERIFY_SERVER_CERT MATCH MAXVALUE MEDIUMBLOB MEDIUMINT
+MEDIUMTEXT MIDDLEINT MINUTE_MICROSECOND MINUTE_SECOND MOD MODIFIES NATURAL NOT NO_WRITE_TO_BINLOG NULL
+NUMERIC ON OPTIMIZE OPTIMIZER_COSTS OPTION OPTIONALLY OR ORDER OUT OUTER OUTFILE PARTITION PRECISION
+PRIMARY PROCEDURE PURGE RANGE READ READS READ_WRITE REAL REFERENCES REGEXP RELEASE RENAME REPEAT REPLACE
+REQUIRE RESIGNAL RESTRICT RETURN REVOKE RIGHT RLIKE SCHEMA SCHEMAS SECOND_MICROSECOND SELECT SENSITIVE
+SEPARATOR SET SHOW SIGNAL SMALLINT SPATIAL SPECIFIC SQL SQLEXCEPTION SQLSTATE SQLWARNING SQL_BIG_RESULT
+SQL_CALC_FOUND_ROWS SQL_SMALL_RESULT SSL STARTING STORED STRAIGHT_JOIN TABLE TERMINATED THEN TINYBLOB
+TINYINT TINYTEXT TO TRAILING TRIGGER TRUE UNDO UNION UNIQUE UNLOCK UNSIGNED UPDATE USAGE USE USING
+UTC_DATE UTC_TIME UTC_TIMESTAMP VALUES VARBINARY VARCHAR VARCHARACTER VARYING VIRTUAL WHEN WHERE WHILE
+WITH WRITE XOR YEAR_MONTH ZEROFILL`
+
+func wordSet(words string, more ...string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range append(strings.Fields(words), more...) {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,28 @@
+package rawsql
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/rawsql/meta"
+)
+
+// ImportFromDB reads tables, or every table when none is given, through the
+// migrator of a live database and returns a built-in Parser holding them, so
+// the tooling working on parsed sql runs against a database alike. The Parser
+// can be given in Config.Parser and applies any further sql on top of the
+// imported tables. gorm migrators expose no foreign keys, so none are
+// imported, see meta.ImportTables.
+func ImportFromDB(db *gorm.DB, tables ...string) (Parser, error) {
+	imported, err := meta.ImportTables(db, tables...)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDefaultParse(&Config{}).(*defaultParser)
+	for name, table := range withAlterer(imported) {
+		d.tables[name] = table
+		d.stats.Tables++
+		d.stats.Columns += len(table.ColumnTypes)
+		d.stats.Indexes += len(table.Indexes)
+	}
+	return d, nil
+}
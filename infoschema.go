@@ -0,0 +1,506 @@
+package rawsql
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InformationSchema is a Loader building CREATE TABLE statements from exports
+// of information_schema tables, for when only metadata exports and no DDL are
+// available. Exports are read by extension: .csv and .tsv files with a header
+// row naming the information_schema columns, NULL or \N standing for NULL as
+// written by mysql -B and SELECT ... INTO OUTFILE, and .json files holding an
+// array of objects keyed by column name.
+type InformationSchema struct {
+	Columns        string // export of information_schema.COLUMNS, required
+	Statistics     string // export of information_schema.STATISTICS for the indexes, without it only COLUMN_KEY is used
+	KeyColumnUsage string // export of information_schema.KEY_COLUMN_USAGE for the foreign keys, optional, with the UPDATE_RULE and DELETE_RULE of REFERENTIAL_CONSTRAINTS when joined in
+	Tables         string // export of information_schema.TABLES for the engines, collations and comments of the tables, optional
+	Schema         string // TABLE_SCHEMA to load, may be empty when the exports hold a single schema
+}
+
+// infoRow is a row of an export by upper case column name, NULL values are absent
+type infoRow map[string]string
+
+func (r infoRow) get(column string) (string, bool) {
+	v, ok := r[column]
+	return v, ok
+}
+
+func (r infoRow) int(column string) int {
+	n, _ := strconv.Atoi(r[column])
+	return n
+}
+
+func (is InformationSchema) Load(ctx context.Context) ([]Source, error) {
+	if is.Columns == "" {
+		return nil, errors.New("rawsql: InformationSchema requires the Columns export")
+	}
+	columns, err := readInfoRows(is.Columns)
+	if err != nil {
+		return nil, err
+	}
+	var statistics, keys []infoRow
+	if is.Statistics != "" {
+		if statistics, err = readInfoRows(is.Statistics); err != nil {
+			return nil, err
+		}
+	}
+	if is.KeyColumnUsage != "" {
+		if keys, err = readInfoRows(is.KeyColumnUsage); err != nil {
+			return nil, err
+		}
+	}
+	var tables []infoRow
+	if is.Tables != "" {
+		if tables, err = readInfoRows(is.Tables); err != nil {
+			return nil, err
+		}
+	}
+	for _, rows := range []*[]infoRow{&columns, &statistics, &keys, &tables} {
+		if *rows, err = is.filter(*rows); err != nil {
+			return nil, err
+		}
+	}
+	return []Source{{Name: is.Columns, Content: []byte(infoSchemaSQL(columns, statistics, keys, tables, is.Statistics != ""))}}, nil
+}
+
+// infoSchemaSQL builds the CREATE TABLE statements of the tables described by
+// rows of information_schema tables, withStatistics when the STATISTICS rows
+// describe the indexes
+func infoSchemaSQL(columns, statistics, keys, tableRows []infoRow, withStatistics bool) string {
+	tables := map[string]*infoTable{}
+	table := func(name string) *infoTable {
+		if tables[name] == nil {
+			tables[name] = &infoTable{name: name, indexes: map[string]*infoIndex{}, foreignKeys: map[string]*infoForeignKey{}}
+		}
+		return tables[name]
+	}
+	for _, row := range columns {
+		t := table(row["TABLE_NAME"])
+		t.columns = append(t.columns, row)
+	}
+	for _, row := range statistics {
+		if t, ok := tables[row["TABLE_NAME"]]; ok {
+			t.addIndex(row)
+		}
+	}
+	for _, row := range keys {
+		if t, ok := tables[row["TABLE_NAME"]]; ok {
+			t.addForeignKey(row)
+		}
+	}
+	for _, row := range tableRows {
+		if t, ok := tables[row["TABLE_NAME"]]; ok {
+			t.options = row
+		}
+	}
+
+	var b strings.Builder
+	order, checksOff := infoTableOrder(tables)
+	if checksOff {
+		b.WriteString("SET FOREIGN_KEY_CHECKS = 0;\n")
+	}
+	for _, name := range order {
+		b.WriteString(tables[name].createTable(withStatistics))
+	}
+	if checksOff {
+		b.WriteString("SET FOREIGN_KEY_CHECKS = 1;\n")
+	}
+	return b.String()
+}
+
+// filter keeps the rows of Schema, or checks the rows belong to a single schema
+func (is InformationSchema) filter(rows []infoRow) ([]infoRow, error) {
+	if is.Schema == "" {
+		for _, row := range rows {
+			if schema := row["TABLE_SCHEMA"]; schema != rows[0]["TABLE_SCHEMA"] {
+				return nil, fmt.Errorf("rawsql: information_schema export holds schemas %s and %s, set InformationSchema.Schema", rows[0]["TABLE_SCHEMA"], schema)
+			}
+		}
+		return rows, nil
+	}
+	kept := make([]infoRow, 0, len(rows))
+	for _, row := range rows {
+		if schema, ok := row.get("TABLE_SCHEMA"); !ok || schema == is.Schema {
+			kept = append(kept, row)
+		}
+	}
+	return kept, nil
+}
+
+type infoTable struct {
+	name        string
+	options     infoRow // of information_schema.TABLES, nil without it
+	columns     []infoRow
+	indexes     map[string]*infoIndex
+	foreignKeys map[string]*infoForeignKey
+}
+
+type infoIndex struct {
+	name    string
+	unique  bool
+	kind    string // FULLTEXT or SPATIAL, empty for BTREE and HASH indexes
+	comment string
+	columns []infoRow
+}
+
+type infoForeignKey struct {
+	name       string
+	referenced string
+	onUpdate   string
+	onDelete   string
+	columns    []infoRow
+}
+
+func (t *infoTable) addIndex(row infoRow) {
+	idx := t.indexes[row["INDEX_NAME"]]
+	if idx == nil {
+		idx = &infoIndex{name: row["INDEX_NAME"], unique: row["NON_UNIQUE"] == "0"}
+		if kind := strings.ToUpper(row["INDEX_TYPE"]); kind == "FULLTEXT" || kind == "SPATIAL" {
+			idx.kind = kind
+		}
+		idx.comment, _ = row.get("INDEX_COMMENT")
+		t.indexes[idx.name] = idx
+	}
+	idx.columns = append(idx.columns, row)
+}
+
+// referentialAction returns the action of an UPDATE_RULE or DELETE_RULE,
+// empty for the RESTRICT and NO ACTION MySQL reports for foreign keys
+// declared without one
+func referentialAction(rule string) string {
+	if rule = strings.ToUpper(rule); rule == "RESTRICT" || rule == "NO ACTION" {
+		return ""
+	}
+	return rule
+}
+
+func (t *infoTable) addForeignKey(row infoRow) {
+	referenced, ok := row.get("REFERENCED_TABLE_NAME")
+	if !ok || referenced == "" {
+		return
+	}
+	fk := t.foreignKeys[row["CONSTRAINT_NAME"]]
+	if fk == nil {
+		fk = &infoForeignKey{name: row["CONSTRAINT_NAME"], referenced: referenced, onUpdate: referentialAction(row["UPDATE_RULE"]), onDelete: referentialAction(row["DELETE_RULE"])}
+		t.foreignKeys[fk.name] = fk
+	}
+	fk.columns = append(fk.columns, row)
+}
+
+// infoTableOrder creates referenced tables first, or turns foreign key checks
+// off when the foreign keys form a cycle
+func infoTableOrder(tables map[string]*infoTable) (order []string, checksOff bool) {
+	graph := make(map[string]*Table, len(tables))
+	for name, t := range tables {
+		table := &Table{Name: name}
+		for _, fk := range t.foreignKeys {
+			table.ForeignKeys = append(table.ForeignKeys, ForeignKey{Name: fk.name, ReferencedTable: fk.referenced})
+		}
+		graph[name] = table
+	}
+	ordered, err := TablesInDependencyOrder(graph)
+	if err != nil {
+		for name := range tables {
+			order = append(order, name)
+		}
+		sort.Strings(order)
+		return order, true
+	}
+	for _, table := range ordered {
+		order = append(order, table.Name)
+	}
+	return order, false
+}
+
+func (t *infoTable) createTable(withStatistics bool) string {
+	sortRows(t.columns, "ORDINAL_POSITION")
+	defs := make([]string, 0, len(t.columns)+len(t.indexes)+len(t.foreignKeys))
+	var primary []string
+	for _, col := range t.columns {
+		defs = append(defs, columnDefinition(col, withStatistics))
+		if col["COLUMN_KEY"] == "PRI" {
+			primary = append(primary, quoteName(col["COLUMN_NAME"]))
+		}
+	}
+
+	if !withStatistics && len(primary) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(primary, ", ")+")")
+	}
+	names := make([]string, 0, len(t.indexes))
+	for name := range t.indexes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "PRIMARY") != (names[j] == "PRIMARY") {
+			return names[i] == "PRIMARY"
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		defs = append(defs, t.indexes[name].definition())
+	}
+
+	names = names[:0]
+	for name := range t.foreignKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		defs = append(defs, t.foreignKeys[name].definition())
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)%s;\n", quoteName(t.name), strings.Join(defs, ",\n  "), t.tableOptions())
+}
+
+// tableOptions returns the table options of the information_schema.TABLES
+// row of the table, the charset is the one the collation name starts with
+func (t *infoTable) tableOptions() string {
+	var options string
+	if engine, ok := t.options.get("ENGINE"); ok && engine != "" {
+		options += " ENGINE=" + engine
+	}
+	if collation, ok := t.options.get("TABLE_COLLATION"); ok && collation != "" {
+		charset := collation
+		if i := strings.Index(collation, "_"); i > 0 {
+			charset = collation[:i]
+		}
+		options += " DEFAULT CHARSET=" + charset + " COLLATE=" + collation
+	}
+	if comment := t.options["TABLE_COMMENT"]; comment != "" {
+		options += " COMMENT=" + QuoteString(comment)
+	}
+	return options
+}
+
+func columnDefinition(col infoRow, withStatistics bool) string {
+	def := []string{quoteName(col["COLUMN_NAME"]), col["COLUMN_TYPE"]}
+	if charset, ok := col.get("CHARACTER_SET_NAME"); ok {
+		def = append(def, "CHARACTER SET "+charset)
+	}
+	if collation, ok := col.get("COLLATION_NAME"); ok {
+		def = append(def, "COLLATE "+collation)
+	}
+	extra := strings.ToUpper(col["EXTRA"])
+	if expr, ok := col.get("GENERATION_EXPRESSION"); ok && expr != "" {
+		kind := "VIRTUAL"
+		if strings.Contains(extra, "STORED") {
+			kind = "STORED"
+		}
+		def = append(def, "AS ("+expr+") "+kind)
+	}
+	if col["IS_NULLABLE"] == "NO" {
+		def = append(def, "NOT NULL")
+	}
+	if value, ok := col.get("COLUMN_DEFAULT"); ok {
+		var kind DefaultKind
+		if strings.Contains(extra, "DEFAULT_GENERATED") {
+			kind = DefaultExpression
+		}
+		def = append(def, "DEFAULT "+defaultSQL(value, kind, strings.ToLower(col["DATA_TYPE"])))
+	}
+	if strings.Contains(extra, "AUTO_INCREMENT") {
+		def = append(def, "AUTO_INCREMENT")
+	}
+	if i := strings.Index(extra, "ON UPDATE "); i >= 0 {
+		def = append(def, col["EXTRA"][i:])
+	}
+	if !withStatistics && col["COLUMN_KEY"] == "UNI" {
+		def = append(def, "UNIQUE")
+	}
+	if strings.Contains(extra, "INVISIBLE") {
+		def = append(def, "INVISIBLE")
+	}
+	if comment := col["COLUMN_COMMENT"]; comment != "" {
+		def = append(def, "COMMENT "+QuoteString(comment))
+	}
+	return strings.Join(def, " ")
+}
+
+// defaultSQL turns a default value of kind back into sql: parsed columns and
+// information_schema hold literals unquoted and expressions as is. Defaults
+// of an unknown kind, e.g. from MySQL 5.7 which does not flag expressions
+// DEFAULT_GENERATED, are taken for CURRENT_TIMESTAMP on temporal columns and
+// for quoted literals otherwise.
+func defaultSQL(value string, kind DefaultKind, dataType string) string {
+	switch kind {
+	case DefaultString:
+		return QuoteString(value)
+	case DefaultInt, DefaultUint, DefaultFloat, DefaultBool:
+		return value
+	case DefaultExpression:
+		// MySQL only takes CURRENT_TIMESTAMP and literals unparenthesized
+		if upper := strings.ToUpper(value); strings.HasPrefix(upper, "CURRENT_TIMESTAMP") || strings.HasPrefix(value, "(") {
+			return value
+		}
+		return "(" + value + ")"
+	}
+	upper := strings.ToUpper(value)
+	if (dataType == "datetime" || dataType == "timestamp") && (strings.HasPrefix(upper, "CURRENT_TIMESTAMP") || strings.HasPrefix(upper, "NOW(")) {
+		return value
+	}
+	return QuoteString(value)
+}
+
+func (idx *infoIndex) definition() string {
+	sortRows(idx.columns, "SEQ_IN_INDEX")
+	columns := make([]string, 0, len(idx.columns))
+	for _, col := range idx.columns {
+		column := quoteName(col["COLUMN_NAME"])
+		if part, ok := col.get("SUB_PART"); ok && part != "" {
+			column += "(" + part + ")"
+		}
+		columns = append(columns, column)
+	}
+	list := "(" + strings.Join(columns, ", ") + ")"
+	if idx.comment != "" {
+		list += " COMMENT " + QuoteString(idx.comment)
+	}
+	switch {
+	case idx.name == "PRIMARY":
+		return "PRIMARY KEY " + list
+	case idx.kind != "":
+		return idx.kind + " KEY " + quoteName(idx.name) + " " + list
+	case idx.unique:
+		return "UNIQUE KEY " + quoteName(idx.name) + " " + list
+	default:
+		return "KEY " + quoteName(idx.name) + " " + list
+	}
+}
+
+func (fk *infoForeignKey) definition() string {
+	sortRows(fk.columns, "ORDINAL_POSITION")
+	columns := make([]string, 0, len(fk.columns))
+	referenced := make([]string, 0, len(fk.columns))
+	for _, col := range fk.columns {
+		columns = append(columns, quoteName(col["COLUMN_NAME"]))
+		referenced = append(referenced, quoteName(col["REFERENCED_COLUMN_NAME"]))
+	}
+	def := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteName(fk.name), strings.Join(columns, ", "), quoteName(fk.referenced), strings.Join(referenced, ", "))
+	if fk.onDelete != "" {
+		def += " ON DELETE " + fk.onDelete
+	}
+	if fk.onUpdate != "" {
+		def += " ON UPDATE " + fk.onUpdate
+	}
+	return def
+}
+
+func sortRows(rows []infoRow, column string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].int(column) < rows[j].int(column)
+	})
+}
+
+// readInfoRows reads an export by the extension of path
+func readInfoRows(path string) ([]infoRow, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return readDelimitedRows(content, ',')
+	case ".tsv", ".txt":
+		return readDelimitedRows(content, '\t')
+	case ".json":
+		return readJSONRows(content)
+	default:
+		return nil, fmt.Errorf("rawsql: unsupported information_schema export %s, expected .csv, .tsv or .json", path)
+	}
+}
+
+func readDelimitedRows(content []byte, comma rune) ([]infoRow, error) {
+	var records [][]string
+	if comma == ',' {
+		r := csv.NewReader(strings.NewReader(string(content)))
+		r.FieldsPerRecord = -1
+		var err error
+		if records, err = r.ReadAll(); err != nil {
+			return nil, err
+		}
+	} else {
+		// mysql -B escapes tabs, newlines and backslashes instead of quoting
+		for _, line := range strings.Split(strings.TrimRight(string(content), "\r\n"), "\n") {
+			fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+			for i, field := range fields {
+				if field != `\N` {
+					fields[i] = unescapeTabular(field)
+				}
+			}
+			records = append(records, fields)
+		}
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	rows := make([]infoRow, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := infoRow{}
+		for i, value := range record {
+			if i < len(header) && value != "NULL" && value != `\N` {
+				row[strings.ToUpper(strings.TrimSpace(header[i]))] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func unescapeTabular(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case '0':
+			b.WriteByte(0)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func readJSONRows(content []byte) ([]infoRow, error) {
+	var objects []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, err
+	}
+	rows := make([]infoRow, 0, len(objects))
+	for _, object := range objects {
+		row := infoRow{}
+		for k, v := range object {
+			if v != nil {
+				row[strings.ToUpper(k)] = fmt.Sprint(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,86 @@
+package rawsql
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// invisibleMarker replaces the INVISIBLE column attribute, which the tidb
+// parser does not support, with a column comment getColumnType recognizes
+const invisibleMarker = "COMMENT 'rawsql:invisible'"
+
+const invisibleComment = "rawsql:invisible"
+
+// visibleMarker replaces the VISIBLE column attribute, the default, so the
+// statement text can still be restored
+const visibleMarker = "/*rawsql:visible*/"
+
+// indexWords start the definitions in which VISIBLE and INVISIBLE are index
+// attributes the tidb parser understands
+var indexWords = []string{"KEY", "INDEX", "PRIMARY", "UNIQUE", "FULLTEXT", "SPATIAL", "CONSTRAINT", "FOREIGN", "CHECK"}
+
+// rewriteInvisible replaces the column VISIBLE and INVISIBLE attributes of sql
+// so it can be parsed, index visibility is left alone
+func rewriteInvisible(sql string) string {
+	if !containsFold(sql, "VISIBLE") {
+		return sql
+	}
+
+	type segment struct {
+		words int  // words seen in the current definition
+		index bool // the definition is an index, not a column
+	}
+	var (
+		b     strings.Builder
+		last  int
+		stack = []segment{{}}
+	)
+	for _, tok := range sqlTokens(sql) {
+		seg := &stack[len(stack)-1]
+		switch {
+		case tok.kind == 'p' && tok.text == "(":
+			stack = append(stack, segment{})
+		case tok.kind == 'p' && tok.text == ")":
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case tok.kind == 'p' && tok.text == ",":
+			*seg = segment{}
+		case tok.kind == 'p' && tok.text == ";":
+			stack = []segment{{}}
+		case (tok.is("INVISIBLE") || tok.is("VISIBLE")) && seg.words > 0 && !seg.index:
+			b.WriteString(sql[last:tok.start])
+			if tok.is("INVISIBLE") {
+				b.WriteString(invisibleMarker)
+			} else {
+				b.WriteString(visibleMarker)
+			}
+			last = tok.end
+		case tok.isName():
+			for _, w := range indexWords {
+				seg.index = seg.index || tok.is(w)
+			}
+			seg.words++
+		}
+	}
+	if last == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+	return b.String()
+}
+
+// restoreInvisible undoes rewriteInvisible in statement text
+func restoreInvisible(text string) string {
+	if !strings.Contains(text, "rawsql:") {
+		return text
+	}
+	text = strings.ReplaceAll(text, invisibleMarker, "INVISIBLE")
+	return strings.ReplaceAll(text, visibleMarker, "VISIBLE")
+}
+
+func isInvisibleMarker(opt *ast.ColumnOption) bool {
+	v, ok := opt.Expr.(ast.ValueExpr)
+	return ok && v.GetValue() == invisibleComment
+}
@@ -0,0 +1,287 @@
+package rawsql
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// LintRule checks a parsed table and reports the problems it finds, rules are
+// run by Lint, e.g. RowSizeLimits
+type LintRule func(table *Table) []Issue
+
+// Lint runs every rule over tables in name order
+func Lint(tables map[string]*Table, rules ...LintRule) (issues []Issue) {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, rule := range rules {
+			issues = append(issues, rule(tables[name])...)
+		}
+	}
+	return issues
+}
+
+// Lint runs every rule over the parsed tables in name order
+func (dialector Dialector) Lint(rules ...LintRule) []Issue {
+	return Lint(dialector.store.get(), rules...)
+}
+
+// InnoDB limits checked by RowSizeLimits
+const (
+	MaxRowSize          = 65535 // bytes of a row, not counting the off page part of TEXT and BLOB columns
+	MaxIndexKeySize     = 3072  // bytes of an index key with the DYNAMIC and COMPRESSED row formats
+	MaxCompactKeyPrefix = 767   // bytes of an index column with the REDUNDANT and COMPACT row formats
+)
+
+// RowSizeLimits is a LintRule estimating the row size and index key sizes of
+// table from the byte lengths of its columns, it reports rows larger than
+// MaxRowSize and keys larger than InnoDB allows, MySQL rejects both tables
+func RowSizeLimits(table *Table) (issues []Issue) {
+	issue := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{Table: table.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if size := rowWidth(table); size > MaxRowSize {
+		issue("estimated row size of %d bytes exceeds the %d bytes InnoDB allows", size, MaxRowSize)
+	}
+	columns := make(map[string]gorm.ColumnType, len(table.ColumnTypes))
+	for _, ct := range table.ColumnTypes {
+		columns[strings.ToLower(ct.Name())] = ct
+	}
+
+	compact := false
+	switch strings.ToUpper(table.Options["ROW_FORMAT"]) {
+	case "REDUNDANT", "COMPACT":
+		compact = true
+	}
+	for _, idx := range table.Indexes {
+		if class := indexClass(idx); class == "FULLTEXT" || class == "SPATIAL" {
+			continue
+		}
+		var prefix []int
+		if idx, ok := idx.(*Index); ok {
+			prefix = idx.PrefixLengths()
+		}
+		var key int64
+		for i, column := range idx.Columns() {
+			ct, ok := columns[strings.ToLower(column)]
+			if !ok {
+				continue
+			}
+			part := keyBytes(ct, 0)
+			if i < len(prefix) && prefix[i] > 0 {
+				part = keyBytes(ct, prefix[i])
+			}
+			if compact && part > MaxCompactKeyPrefix {
+				issue("key column %s of index %s takes %d bytes, the %s row format allows %d", column, indexName(idx), part, strings.ToUpper(table.Options["ROW_FORMAT"]), MaxCompactKeyPrefix)
+			}
+			key += part
+		}
+		if key > MaxIndexKeySize {
+			issue("estimated key size of index %s of %d bytes exceeds the %d bytes InnoDB allows", indexName(idx), key, MaxIndexKeySize)
+		}
+	}
+	return issues
+}
+
+// rowWidth estimates the maximum row size of table in bytes the way InnoDB
+// counts it against MaxRowSize
+func rowWidth(table *Table) int64 {
+	var size, nullable int64
+	for _, ct := range table.ColumnTypes {
+		size += storedBytes(ct)
+		if null, _ := ct.Nullable(); null {
+			nullable++
+		}
+	}
+	// the NULL flags take a bit per nullable column
+	return size + (nullable+7)/8
+}
+
+func indexClass(idx gorm.Index) string {
+	if idx, ok := idx.(*Index); ok {
+		return idx.Class()
+	}
+	return ""
+}
+
+func indexName(idx gorm.Index) string {
+	if pk, _ := idx.PrimaryKey(); pk {
+		return "PRIMARY"
+	}
+	return idx.Name()
+}
+
+// fixedBytes are the storage sizes of the types whose size does not depend on
+// their length
+var fixedBytes = map[string]int64{
+	"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "integer": 4, "bigint": 8,
+	"float": 4, "double": 8, "real": 8, "date": 3, "year": 1, "time": 3, "datetime": 5, "timestamp": 4,
+}
+
+// blobBytes are the bytes a TEXT, BLOB or JSON column keeps in the row: its
+// length and a pointer to the values stored off page
+var blobBytes = map[string]int64{
+	"tinytext": 9, "tinyblob": 9, "text": 10, "blob": 10, "mediumtext": 11, "mediumblob": 11,
+	"longtext": 12, "longblob": 12, "json": 12,
+}
+
+// storedBytes estimates the bytes ct takes in a row at most
+func storedBytes(ct gorm.ColumnType) int64 {
+	dataType := strings.ToLower(ct.DatabaseTypeName())
+	if size, ok := blobBytes[dataType]; ok {
+		return size
+	}
+	if size, ok := fixedBytes[dataType]; ok {
+		switch dataType {
+		case "time", "datetime", "timestamp":
+			// fractional seconds take a byte per two digits
+			size += (typeArgument(ct) + 1) / 2
+		}
+		return size
+	}
+	switch dataType {
+	case "decimal", "numeric":
+		precision, scale, ok := ct.DecimalSize()
+		if !ok {
+			precision, scale = 10, 0
+		}
+		return decimalBytes(precision-scale) + decimalBytes(scale)
+	case "bit":
+		if bits := typeArgument(ct); bits > 1 {
+			return (bits + 7) / 8
+		}
+		return 1
+	case "enum":
+		return 2
+	case "set":
+		return 8
+	}
+	if c, ok := ct.(*ColumnType); ok {
+		if octets, ok := c.OctetLength(); ok {
+			switch dataType {
+			case "varchar", "varbinary":
+				// the length takes one byte up to 255 bytes, two beyond
+				if octets > 255 {
+					return octets + 2
+				}
+				return octets + 1
+			}
+			return octets
+		}
+	}
+	// spatial types are stored like BLOBs
+	return blobBytes["longblob"]
+}
+
+// keyBytes estimates the bytes ct takes in an index key, of its first prefix
+// characters when prefix is not 0
+func keyBytes(ct gorm.ColumnType, prefix int) int64 {
+	c, ok := ct.(*ColumnType)
+	if !ok {
+		return storedBytes(ct)
+	}
+	chars, ok := c.CharLength()
+	if !ok {
+		return storedBytes(ct)
+	}
+	octets, _ := c.OctetLength()
+	if prefix > 0 && int64(prefix) < chars {
+		// prefixes of binary strings count bytes
+		if !charTypes[c.DatabaseTypeName()] {
+			return int64(prefix)
+		}
+		charset, _ := c.Charset()
+		return int64(prefix) * charsetMaxBytes(charset)
+	}
+	return octets
+}
+
+// decimalBytes is the storage size of the given number of decimal digits,
+// packed nine to four bytes
+func decimalBytes(digits int64) int64 {
+	if digits <= 0 {
+		return 0
+	}
+	leftover := [...]int64{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+	return digits/9*4 + leftover[digits%9]
+}
+
+// typeArgument returns the number in parentheses of a column type such as
+// datetime(3) or bit(8), 0 when there is none
+func typeArgument(ct gorm.ColumnType) int64 {
+	columnType, _ := ct.ColumnType()
+	i, j := strings.IndexByte(columnType, '('), strings.IndexByte(columnType, ')')
+	if i < 0 || j < i {
+		return 0
+	}
+	n, _ := strconv.ParseInt(columnType[i+1:j], 10, 64)
+	return n
+}
+
+// NamingRules configures the NamingConventions lint rule, the zero value
+// checks nothing
+type NamingRules struct {
+	SnakeCase         bool   // table, column, index and foreign key names must be lower snake_case
+	IndexPrefix       string // prefix of the names of non unique indexes, e.g. idx_
+	UniquePrefix      string // prefix of the names of unique indexes, e.g. uk_
+	ForeignKeyPattern string // path.Match pattern of foreign key names, {table} and {referenced_table} are replaced by the table names, e.g. fk_{table}_{referenced_table}*
+}
+
+var snakeCase = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+
+// NamingConventions returns a LintRule reporting the names breaking rules, it
+// fails when ForeignKeyPattern is malformed
+func NamingConventions(rules NamingRules) (LintRule, error) {
+	if _, err := path.Match(rules.ForeignKeyPattern, ""); err != nil {
+		return nil, fmt.Errorf("rawsql: invalid foreign key pattern %q: %w", rules.ForeignKeyPattern, err)
+	}
+	return func(table *Table) (issues []Issue) {
+		issue := func(format string, args ...interface{}) {
+			issues = append(issues, Issue{Table: table.Name, Message: fmt.Sprintf(format, args...)})
+		}
+		snake := func(kind, name string) {
+			if rules.SnakeCase && !snakeCase.MatchString(name) {
+				issue("%s name %s is not snake_case", kind, name)
+			}
+		}
+
+		snake("table", table.Name)
+		for _, ct := range table.ColumnTypes {
+			snake("column", ct.Name())
+		}
+		for _, idx := range table.Indexes {
+			if pk, _ := idx.PrimaryKey(); pk || idx.Name() == "" || foreignKeyIndex(table, idx) {
+				continue
+			}
+			snake("index", idx.Name())
+			prefix := rules.IndexPrefix
+			if unique, _ := idx.Unique(); unique {
+				prefix = rules.UniquePrefix
+			}
+			if !strings.HasPrefix(idx.Name(), prefix) {
+				issue("index name %s does not start with %s", idx.Name(), prefix)
+			}
+		}
+		for _, fk := range table.ForeignKeys {
+			snake("foreign key", fk.Name)
+			if rules.ForeignKeyPattern == "" {
+				continue
+			}
+			pattern := strings.NewReplacer("{table}", table.Name, "{referenced_table}", fk.ReferencedTable).Replace(rules.ForeignKeyPattern)
+			if ok, _ := path.Match(pattern, fk.Name); !ok {
+				issue("foreign key name %s does not match %s", fk.Name, pattern)
+			}
+		}
+		return issues
+	}, nil
+}
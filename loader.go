@@ -0,0 +1,50 @@
+package rawsql
+
+import (
+	"context"
+)
+
+// Source is a named piece of sql content returned by a Loader. Content is
+// decoded by the extension of Name like files, so .gz and archives are accepted.
+type Source struct {
+	Name    string
+	Content []byte
+}
+
+// Loader supplies sql from custom locations such as object storage, secret
+// managers or a database, without rawsql depending on their clients
+type Loader interface {
+	Load(ctx context.Context) ([]Source, error)
+}
+
+// LoaderFunc adapts an ordinary function to a Loader
+type LoaderFunc func(ctx context.Context) ([]Source, error)
+
+func (f LoaderFunc) Load(ctx context.Context) ([]Source, error) {
+	return f(ctx)
+}
+
+func (config *Config) context() context.Context {
+	if config.Context == nil {
+		return context.Background()
+	}
+	return config.Context
+}
+
+func (dialector Dialector) loaderTOSQL() error {
+	for _, loader := range dialector.Loaders {
+		if loader == nil {
+			continue
+		}
+		sources, err := loader.Load(dialector.context())
+		if err != nil {
+			return err
+		}
+		for _, src := range sources {
+			if err = dialector.appendSQL(src.Name, src.Content); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package rawsql
+
+import "github.com/pingcap/tidb/pkg/parser/ast"
+
+// skip counts node as skipped and logs why
+func (d *defaultParser) skip(node ast.StmtNode, reason string) {
+	d.stats.Skipped++
+	if d.config.Logger != nil {
+		d.config.Logger.Info(d.config.context(), "rawsql: skipped %s: %s", reason, stmtSummary(node))
+	}
+}
+
+func (d *defaultParser) logApplied(node ast.StmtNode) {
+	if d.config.Logger != nil {
+		d.config.Logger.Info(d.config.context(), "rawsql: applied %s", stmtSummary(node))
+	}
+}
+
+func (d *defaultParser) logWarning(w Warning) {
+	if d.config.Logger == nil {
+		return
+	}
+	if w.Notice {
+		d.config.Logger.Info(d.config.context(), "rawsql: %s", w)
+	} else {
+		d.config.Logger.Warn(d.config.context(), "rawsql: %s", w)
+	}
+}
+
+// logReport logs the time spent on every source and on the whole load
+func (dialector Dialector) logReport(report *ParseReport) {
+	if dialector.Logger == nil {
+		return
+	}
+	ctx := dialector.context()
+	if report.Cached {
+		dialector.Logger.Info(ctx, "rawsql: loaded tables from %s in %s", dialector.CacheDir, report.Duration)
+		return
+	}
+	for _, file := range report.Files {
+		dialector.Logger.Info(ctx, "rawsql: parsed %s in %s: %d statements, %d skipped",
+			file.Name, file.Duration, file.Statements, file.Skipped)
+	}
+	dialector.Logger.Info(ctx, "rawsql: parsed %d tables from %d statements in %s, %d warnings",
+		report.Tables, report.Statements, report.Duration, len(report.Warnings))
+}
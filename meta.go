@@ -0,0 +1,67 @@
+package rawsql
+
+import (
+	"io"
+
+	"gorm.io/rawsql/meta"
+)
+
+// The parsed tables and their snapshots live in gorm.io/rawsql/meta, which
+// does not depend on the TiDB parser, the types are aliased here so both
+// packages can be used interchangeably.
+type (
+	Table      = meta.Table
+	ColumnType = meta.ColumnType
+	ForeignKey = meta.ForeignKey
+	AlterHint  = meta.AlterHint
+	Rename     = meta.Rename
+	Index      = meta.Index
+
+	DefaultKind = meta.DefaultKind
+	Expression  = meta.Expression
+
+	SchemaAssertion     = meta.SchemaAssertion
+	SchemaMismatchError = meta.SchemaMismatchError
+	TableMismatch       = meta.TableMismatch
+	ColumnMismatch      = meta.ColumnMismatch
+)
+
+// The kinds of column defaults, see meta.DefaultKind
+const (
+	DefaultInt        = meta.DefaultInt
+	DefaultUint       = meta.DefaultUint
+	DefaultFloat      = meta.DefaultFloat
+	DefaultBool       = meta.DefaultBool
+	DefaultString     = meta.DefaultString
+	DefaultExpression = meta.DefaultExpression
+)
+
+// PlacementPolicyOption is the Table.Options key of the TiDB placement policy
+const PlacementPolicyOption = meta.PlacementPolicyOption
+
+// SnapshotVersion is bumped whenever the serialized Table layout changes
+const SnapshotVersion = meta.SnapshotVersion
+
+// EncodeSnapshot writes tables as JSON, see meta.EncodeSnapshot
+func EncodeSnapshot(w io.Writer, tables map[string]*Table) error {
+	return meta.EncodeSnapshot(w, tables)
+}
+
+// DecodeSnapshot reads tables written by EncodeSnapshot, see
+// meta.DecodeSnapshot, their Alterer is ApplyAlter
+func DecodeSnapshot(r io.Reader) (map[string]*Table, error) {
+	tables, err := meta.DecodeSnapshot(r)
+	return withAlterer(tables), err
+}
+
+// EncodeBinarySnapshot writes tables as gob, see meta.EncodeBinarySnapshot
+func EncodeBinarySnapshot(w io.Writer, tables map[string]*Table) error {
+	return meta.EncodeBinarySnapshot(w, tables)
+}
+
+// DecodeBinarySnapshot reads tables written by EncodeBinarySnapshot, see
+// meta.DecodeBinarySnapshot, their Alterer is ApplyAlter
+func DecodeBinarySnapshot(r io.Reader) (map[string]*Table, error) {
+	tables, err := meta.DecodeBinarySnapshot(r)
+	return withAlterer(tables), err
+}
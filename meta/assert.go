@@ -0,0 +1,204 @@
+package meta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// SchemaAssertion is a gorm plugin checking, when it is used, that the
+// connected database has the tables of the schema, so a service started
+// before its migrations ran fails at once rather than on its first query:
+//
+//	db.Use(&meta.SchemaAssertion{Tables: schema.MustTables()})
+//
+// The tables are read through db.Migrator() as ImportTables reads them, which
+// reports no table options, charsets or foreign keys, so the tables and
+// columns present and the types and nullability of the columns are compared.
+// It lives in meta so services loading a pre-built snapshot can assert it
+// without linking the TiDB parser.
+type SchemaAssertion struct {
+	Tables  map[string]*Table // the expected tables, e.g. of a parsed Dialector or a rawsqlgen file
+	LogOnly bool              // log the mismatches through the logger of the db instead of failing
+}
+
+// SchemaMismatchError is the error of a SchemaAssertion, listing how the
+// database differs from the schema
+type SchemaMismatchError struct {
+	Missing []string        // tables of the schema the database does not have
+	Tables  []TableMismatch // the tables of both that differ, by name
+}
+
+// TableMismatch is how a table of the database differs from that of the
+// schema
+type TableMismatch struct {
+	Name           string            // as declared in the schema
+	MissingColumns []gorm.ColumnType // of the schema the database does not have, in schema order
+	ExtraColumns   []gorm.ColumnType // of the database the schema does not have
+	ChangedColumns []ColumnMismatch
+}
+
+// ColumnMismatch is a column of both tables with another type or nullability
+type ColumnMismatch struct {
+	Name     string          // as declared in the schema
+	Live     gorm.ColumnType // the column of the database
+	Expected gorm.ColumnType // the column of the schema
+	Fields   []string        // type and nullable, those that differ
+}
+
+func (e *SchemaMismatchError) Error() string {
+	var b strings.Builder
+	b.WriteString("rawsql: the database does not match the schema, were the migrations run?")
+	if len(e.Missing) > 0 {
+		names := make([]string, 0, len(e.Missing))
+		for _, name := range e.Missing {
+			names = append(names, quoteName(name))
+		}
+		b.WriteString("\nmissing tables: " + strings.Join(names, ", "))
+	}
+	for _, table := range e.Tables {
+		b.WriteString("\n" + quoteName(table.Name))
+		for _, ct := range table.MissingColumns {
+			columnType, _ := ct.ColumnType()
+			fmt.Fprintf(&b, "\n  + column %s %s", quoteName(ct.Name()), columnType)
+		}
+		for _, ct := range table.ExtraColumns {
+			columnType, _ := ct.ColumnType()
+			fmt.Fprintf(&b, "\n  - column %s %s", quoteName(ct.Name()), columnType)
+		}
+		for _, column := range table.ChangedColumns {
+			changes := make([]string, 0, len(column.Fields))
+			for _, field := range column.Fields {
+				changes = append(changes, fmt.Sprintf("%s %s -> %s", field, assertionField(column.Live, field), assertionField(column.Expected, field)))
+			}
+			fmt.Fprintf(&b, "\n  ~ column %s: %s", quoteName(column.Name), strings.Join(changes, ", "))
+		}
+	}
+	return b.String()
+}
+
+func (a *SchemaAssertion) Name() string {
+	return "rawsql:schema_assertion"
+}
+
+// Initialize compares the tables, it returns a *SchemaMismatchError when
+// they differ unless LogOnly is set
+func (a *SchemaAssertion) Initialize(db *gorm.DB) error {
+	err := a.check(db)
+	if mismatch, ok := err.(*SchemaMismatchError); ok && a.LogOnly {
+		db.Logger.Warn(db.Statement.Context, "%s", mismatch)
+		return nil
+	}
+	return err
+}
+
+func (a *SchemaAssertion) check(db *gorm.DB) error {
+	existing, err := db.Migrator().GetTables()
+	if err != nil {
+		return fmt.Errorf("rawsql: schema assertion: %w", err)
+	}
+	liveNames := make(map[string]string, len(existing))
+	for _, name := range existing {
+		liveNames[strings.ToLower(name)] = name
+	}
+	names := make([]string, 0, len(a.Tables))
+	for name := range a.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mismatch := &SchemaMismatchError{}
+	var present []string
+	for _, name := range names {
+		if live, ok := liveNames[strings.ToLower(name)]; ok {
+			present = append(present, live)
+		} else {
+			mismatch.Missing = append(mismatch.Missing, name)
+		}
+	}
+	if len(present) > 0 {
+		live, err := ImportTables(db, present...)
+		if err != nil {
+			return fmt.Errorf("rawsql: schema assertion: %w", err)
+		}
+		for _, name := range names {
+			table, ok := live[liveNames[strings.ToLower(name)]]
+			if !ok {
+				continue
+			}
+			if diff := compareColumns(table, a.Tables[name]); diff != nil {
+				diff.Name = name
+				mismatch.Tables = append(mismatch.Tables, *diff)
+			}
+		}
+	}
+	if len(mismatch.Missing) > 0 || len(mismatch.Tables) > 0 {
+		return mismatch
+	}
+	return nil
+}
+
+// compareColumns compares what the migrator reports reliably: the columns
+// present, their types but for integer display widths, which MySQL 8 leaves
+// out, and their nullability. Column names are compared case-insensitively
+// as MySQL compares them. It returns nil when the tables match.
+func compareColumns(live, expected *Table) *TableMismatch {
+	diff := &TableMismatch{}
+	for _, ct := range expected.ColumnTypes {
+		liveColumn, ok := live.Column(ct.Name())
+		if !ok {
+			diff.MissingColumns = append(diff.MissingColumns, ct)
+			continue
+		}
+		var fields []string
+		if assertionType(liveColumn) != assertionType(ct) {
+			fields = append(fields, "type")
+		}
+		if assertionField(liveColumn, "nullable") != assertionField(ct, "nullable") {
+			fields = append(fields, "nullable")
+		}
+		if len(fields) > 0 {
+			diff.ChangedColumns = append(diff.ChangedColumns, ColumnMismatch{Name: ct.Name(), Live: liveColumn, Expected: ct, Fields: fields})
+		}
+	}
+	for _, ct := range live.ColumnTypes {
+		if _, ok := expected.Column(ct.Name()); !ok {
+			diff.ExtraColumns = append(diff.ExtraColumns, ct)
+		}
+	}
+	if len(diff.MissingColumns) == 0 && len(diff.ExtraColumns) == 0 && len(diff.ChangedColumns) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// assertionField renders the field of a ColumnMismatch
+func assertionField(ct gorm.ColumnType, field string) string {
+	if field == "type" {
+		columnType, _ := ct.ColumnType()
+		return columnType
+	}
+	if nullable, _ := ct.Nullable(); !nullable {
+		return "NOT NULL"
+	}
+	return "NULL"
+}
+
+var integerTypes = map[string]bool{"tinyint": true, "smallint": true, "mediumint": true, "int": true, "integer": true, "bigint": true}
+
+func assertionType(ct gorm.ColumnType) string {
+	columnType, _ := ct.ColumnType()
+	columnType = strings.ToLower(columnType)
+	if i := strings.Index(columnType, "("); i > 0 && integerTypes[strings.ToLower(ct.DatabaseTypeName())] {
+		if j := strings.Index(columnType[i:], ")"); j > 0 {
+			columnType = columnType[:i] + columnType[i+j+1:]
+		}
+	}
+	return columnType
+}
+
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
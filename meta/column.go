@@ -0,0 +1,275 @@
+package meta
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+)
+
+// migratorColumnType lets ColumnType embed migrator.ColumnType without the
+// field shadowing its ColumnType method
+type migratorColumnType = migrator.ColumnType
+
+// ColumnType is the gorm.ColumnType of the columns parsed by the built-in
+// rawsql Parser, it adds the metadata migrator.ColumnType has no field for
+type ColumnType struct {
+	migratorColumnType
+	OrdinalPositionValue int
+	UnsignedValue        sql.NullBool
+	CharsetValue         sql.NullString
+	CollationValue       sql.NullString
+	InvisibleValue       bool
+	BinaryValue          bool
+	SRIDValue            sql.NullInt64
+	CharLengthValue      sql.NullInt64
+	OctetLengthValue     sql.NullInt64
+	ColumnFormatValue    string
+	StorageValue         string
+	DefaultKindValue     DefaultKind
+	OnUpdateValue        string
+	GenerationExprValue  string
+	GeneratedStoredValue bool
+	DirectivesValue      map[string]string
+	MetadataValue        map[string]string
+}
+
+// DefaultKind tells how the default value of a column was written
+type DefaultKind string
+
+const (
+	DefaultInt        DefaultKind = "int"        // an integer literal, e.g. DEFAULT 0
+	DefaultUint       DefaultKind = "uint"       // an integer literal beyond int64
+	DefaultFloat      DefaultKind = "float"      // a decimal or floating point literal, e.g. DEFAULT 1.5
+	DefaultBool       DefaultKind = "bool"       // TRUE, FALSE, or an integer literal of a BOOL column
+	DefaultString     DefaultKind = "string"     // a quoted literal, e.g. DEFAULT '0'
+	DefaultExpression DefaultKind = "expression" // a function or expression, e.g. DEFAULT CURRENT_TIMESTAMP
+)
+
+// Expression is the typed default value of DefaultExpression defaults
+type Expression string
+
+// NewColumnType returns a ColumnType holding the values of ct
+func NewColumnType(ct migrator.ColumnType) *ColumnType {
+	return &ColumnType{migratorColumnType: ct}
+}
+
+// OrdinalPosition is the 1 based position of the column in its table, as
+// reported by information_schema.COLUMNS.ORDINAL_POSITION
+func (ct *ColumnType) OrdinalPosition() int {
+	return ct.OrdinalPositionValue
+}
+
+// Unsigned reports whether a numeric column is UNSIGNED, SERIAL columns are
+func (ct *ColumnType) Unsigned() (unsigned bool, ok bool) {
+	return ct.UnsignedValue.Bool, ct.UnsignedValue.Valid
+}
+
+// Charset is the character set of char and text columns, declared or
+// inherited from the table
+func (ct *ColumnType) Charset() (charset string, ok bool) {
+	return ct.CharsetValue.String, ct.CharsetValue.Valid
+}
+
+// Collation is the collation of char and text columns, declared or inherited
+// from the table
+func (ct *ColumnType) Collation() (collation string, ok bool) {
+	return ct.CollationValue.String, ct.CollationValue.Valid
+}
+
+// Invisible reports whether the column is INVISIBLE, which hides it from
+// SELECT * but not from explicit column lists
+func (ct *ColumnType) Invisible() bool {
+	return ct.InvisibleValue
+}
+
+// Binary reports whether the column was declared with the BINARY attribute,
+// which gives it the _bin collation of whichever charset it takes, also after
+// ALTER TABLE ... CONVERT TO
+func (ct *ColumnType) Binary() bool {
+	return ct.BinaryValue
+}
+
+// CharLength is the maximum length of a string column in characters, as
+// information_schema.COLUMNS.CHARACTER_MAXIMUM_LENGTH reports it: the declared
+// length of CHAR and VARCHAR, the longest element of an ENUM and the 64 KiB
+// of TEXT, which limits bytes rather than characters
+func (ct *ColumnType) CharLength() (length int64, ok bool) {
+	return ct.CharLengthValue.Int64, ct.CharLengthValue.Valid
+}
+
+// OctetLength is the maximum length of a string column in bytes given its
+// charset, as information_schema.COLUMNS.CHARACTER_OCTET_LENGTH reports it,
+// e.g. 400 for a utf8mb4 VARCHAR(100)
+func (ct *ColumnType) OctetLength() (length int64, ok bool) {
+	return ct.OctetLengthValue.Int64, ct.OctetLengthValue.Valid
+}
+
+// SRID is the spatial reference system a spatial column is restricted to by
+// its SRID attribute, ok is false when the column accepts any
+func (ct *ColumnType) SRID() (srid uint32, ok bool) {
+	return uint32(ct.SRIDValue.Int64), ct.SRIDValue.Valid
+}
+
+// VectorDimension is the dimension of a TiDB VECTOR(n) column, ok is false
+// for other columns and a VECTOR column taking vectors of any dimension
+func (ct *ColumnType) VectorDimension() (dimension int64, ok bool) {
+	if ct.DataTypeValue.String != "vector" {
+		return 0, false
+	}
+	columnType := ct.ColumnTypeValue.String
+	if !strings.HasPrefix(columnType, "vector(") || !strings.HasSuffix(columnType, ")") {
+		return 0, false
+	}
+	dimension, err := strconv.ParseInt(columnType[len("vector("):len(columnType)-1], 10, 64)
+	return dimension, err == nil
+}
+
+// EnumValues are the values of an ENUM or SET column in declaration order,
+// with their case, ok is false for other columns
+func (ct *ColumnType) EnumValues() (values []string, ok bool) {
+	if ct.DataTypeValue.String != "enum" && ct.DataTypeValue.String != "set" {
+		return nil, false
+	}
+	// e.g. enum('new','it''s'), quotes are doubled and backslashes kept
+	columnType := ct.ColumnTypeValue.String
+	start := strings.IndexByte(columnType, '(')
+	if start < 0 {
+		return nil, false
+	}
+	values = []string{}
+	var value strings.Builder
+	quoted := false
+	for i := start + 1; i < len(columnType); i++ {
+		switch c := columnType[i]; {
+		case c == '\'' && quoted && i+1 < len(columnType) && columnType[i+1] == '\'':
+			value.WriteByte(c)
+			i++
+		case c == '\'':
+			if quoted {
+				values = append(values, value.String())
+				value.Reset()
+			}
+			quoted = !quoted
+		case quoted:
+			value.WriteByte(c)
+		case c == ')':
+			return values, true
+		}
+	}
+	return values, true
+}
+
+// Directives are the -- rawsql:name=value comments before the definition of
+// the column, e.g. field-type: decimal.Decimal
+func (ct *ColumnType) Directives() map[string]string {
+	return ct.DirectivesValue
+}
+
+// Metadata is what the comment extractors of the Parser read from the comment
+// of the column, e.g. mask: email
+func (ct *ColumnType) Metadata() map[string]string {
+	return ct.MetadataValue
+}
+
+// ColumnFormat is the NDB COLUMN_FORMAT attribute, FIXED, DYNAMIC or DEFAULT,
+// empty when it was not given
+func (ct *ColumnType) ColumnFormat() string {
+	return ct.ColumnFormatValue
+}
+
+// Storage is the NDB STORAGE attribute, DISK or MEMORY, empty when it was not
+// given
+func (ct *ColumnType) Storage() string {
+	return ct.StorageValue
+}
+
+// OnUpdate is the ON UPDATE attribute of a temporal column, e.g.
+// CURRENT_TIMESTAMP(3), empty when it was not given
+func (ct *ColumnType) OnUpdate() string {
+	return ct.OnUpdateValue
+}
+
+// Generated returns the expression of a generated column and whether it is
+// STORED rather than VIRTUAL, ok is false for ordinary columns
+func (ct *ColumnType) Generated() (expression string, stored bool, ok bool) {
+	return ct.GenerationExprValue, ct.GeneratedStoredValue, ct.GenerationExprValue != ""
+}
+
+// Extra aggregates the auto increment, expression default, on update,
+// generated and invisible attributes of the column the way
+// information_schema.COLUMNS.EXTRA of MySQL 8 does, e.g.
+// "DEFAULT_GENERATED on update CURRENT_TIMESTAMP"
+func (ct *ColumnType) Extra() string {
+	var extra []string
+	if autoIncrement, _ := ct.AutoIncrement(); autoIncrement {
+		extra = append(extra, "auto_increment")
+	}
+	if ct.DefaultKindValue == DefaultExpression {
+		extra = append(extra, "DEFAULT_GENERATED")
+	}
+	if ct.OnUpdateValue != "" {
+		extra = append(extra, "on update "+ct.OnUpdateValue)
+	}
+	if _, stored, ok := ct.Generated(); ok && stored {
+		extra = append(extra, "STORED GENERATED")
+	} else if ok {
+		extra = append(extra, "VIRTUAL GENERATED")
+	}
+	if ct.InvisibleValue {
+		extra = append(extra, "INVISIBLE")
+	}
+	return strings.Join(extra, " ")
+}
+
+// DefaultKind tells how the default value was written, empty when there is no
+// default or it was not parsed by rawsql, e.g. imported from a database
+func (ct *ColumnType) DefaultKind() DefaultKind {
+	return ct.DefaultKindValue
+}
+
+// DefaultQuoted reports whether the default was written as a quoted literal,
+// DEFAULT 'CURRENT_TIMESTAMP' is while DEFAULT CURRENT_TIMESTAMP is not
+func (ct *ColumnType) DefaultQuoted() bool {
+	return ct.DefaultKindValue == DefaultString
+}
+
+// Default returns the default value typed after DefaultKind: int64, uint64,
+// float64, bool, string or Expression. Defaults of an unknown kind are
+// returned as the string DefaultValue reports.
+func (ct *ColumnType) Default() (value interface{}, ok bool) {
+	s, ok := ct.DefaultValue()
+	if !ok {
+		return nil, false
+	}
+	switch ct.DefaultKindValue {
+	case DefaultInt:
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v, true
+		}
+	case DefaultUint:
+		if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return v, true
+		}
+	case DefaultFloat:
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v, true
+		}
+	case DefaultBool:
+		return s != "0", true
+	case DefaultExpression:
+		return Expression(s), true
+	}
+	return s, true
+}
+
+// NumberColumns sets the ordinal position of every column from its index
+func NumberColumns(cols []gorm.ColumnType) {
+	for i, col := range cols {
+		if ct, ok := col.(*ColumnType); ok {
+			ct.OrdinalPositionValue = i + 1
+		}
+	}
+}
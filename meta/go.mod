@@ -0,0 +1,10 @@
+module gorm.io/rawsql/meta
+
+go 1.18
+
+require gorm.io/gorm v1.25.2
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+)
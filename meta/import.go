@@ -0,0 +1,134 @@
+package meta
+
+import (
+	"database/sql"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+)
+
+// ImportTables reads tables, or every table when none is given, through the
+// migrator of a live database. gorm migrators expose no foreign keys, table
+// options or charsets, so none are imported.
+func ImportTables(db *gorm.DB, tables ...string) (map[string]*Table, error) {
+	m := db.Migrator()
+	if len(tables) == 0 {
+		var err error
+		if tables, err = m.GetTables(); err != nil {
+			return nil, err
+		}
+	}
+
+	im := importer{strings: map[string]string{}}
+	imported := make(map[string]*Table, len(tables))
+	for _, name := range tables {
+		columns, err := m.ColumnTypes(name)
+		if err != nil {
+			return nil, err
+		}
+		indexes, err := m.GetIndexes(name)
+		if err != nil {
+			return nil, err
+		}
+		table := &Table{Name: im.intern(name), ColumnTypes: make([]gorm.ColumnType, 0, len(columns))}
+		// not every dialect reports table comments
+		if tableType, err := m.TableType(name); err == nil && tableType != nil {
+			comment, _ := tableType.Comment()
+			table.Comment = im.intern(comment)
+		}
+		for _, col := range columns {
+			table.ColumnTypes = append(table.ColumnTypes, im.column(col))
+		}
+		NumberColumns(table.ColumnTypes)
+		for _, idx := range indexes {
+			table.Indexes = append(table.Indexes, im.index(table.Name, idx))
+		}
+		imported[table.Name] = table
+	}
+	return imported, nil
+}
+
+// importer copies the strings reported by a driver once, so the imported
+// tables hold no reference to its buffers
+type importer struct {
+	strings map[string]string
+}
+
+func (im importer) intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	if v, ok := im.strings[s]; ok {
+		return v
+	}
+	v := string([]byte(s))
+	im.strings[v] = v
+	return v
+}
+
+// column copies a column reported by a migrator, keeping the metadata of
+// columns parsed by rawsql
+func (im importer) column(col gorm.ColumnType) *ColumnType {
+	str := func(v string, ok bool) sql.NullString { return sql.NullString{String: im.intern(v), Valid: ok} }
+	boolean := func(v, ok bool) sql.NullBool { return sql.NullBool{Bool: v, Valid: ok} }
+	integer := func(v int64, ok bool) sql.NullInt64 { return sql.NullInt64{Int64: v, Valid: ok} }
+
+	columnType, columnTypeOK := col.ColumnType()
+	precision, scale, decimalOK := col.DecimalSize()
+	ct := NewColumnType(migrator.ColumnType{
+		SQLColumnType:      &sql.ColumnType{},
+		NameValue:          str(col.Name(), true),
+		DataTypeValue:      str(strings.ToLower(col.DatabaseTypeName()), true),
+		ColumnTypeValue:    str(columnType, columnTypeOK),
+		PrimaryKeyValue:    boolean(col.PrimaryKey()),
+		UniqueValue:        boolean(col.Unique()),
+		AutoIncrementValue: boolean(col.AutoIncrement()),
+		LengthValue:        integer(col.Length()),
+		DecimalSizeValue:   integer(precision, decimalOK),
+		ScaleValue:         integer(scale, decimalOK),
+		NullableValue:      boolean(col.Nullable()),
+		ScanTypeValue:      col.ScanType(),
+		CommentValue:       str(col.Comment()),
+		DefaultValueValue:  str(col.DefaultValue()),
+	})
+	if parsed, ok := col.(*ColumnType); ok {
+		ct.UnsignedValue = parsed.UnsignedValue
+		ct.CharsetValue, ct.CollationValue = parsed.CharsetValue, parsed.CollationValue
+		ct.InvisibleValue, ct.BinaryValue = parsed.InvisibleValue, parsed.BinaryValue
+		ct.SRIDValue = parsed.SRIDValue
+		ct.CharLengthValue, ct.OctetLengthValue = parsed.CharLengthValue, parsed.OctetLengthValue
+		ct.ColumnFormatValue, ct.StorageValue = parsed.ColumnFormatValue, parsed.StorageValue
+		ct.DefaultKindValue = parsed.DefaultKindValue
+		ct.OnUpdateValue, ct.GenerationExprValue = parsed.OnUpdateValue, parsed.GenerationExprValue
+		ct.GeneratedStoredValue = parsed.GeneratedStoredValue
+		ct.DirectivesValue = parsed.DirectivesValue
+	} else if strings.Contains(strings.ToLower(columnType), "unsigned") {
+		ct.UnsignedValue = sql.NullBool{Bool: true, Valid: true}
+	}
+	return ct
+}
+
+func (im importer) index(table string, idx gorm.Index) gorm.Index {
+	columns := make([]string, 0, len(idx.Columns()))
+	for _, column := range idx.Columns() {
+		columns = append(columns, im.intern(column))
+	}
+	primaryKey, primaryKeyOK := idx.PrimaryKey()
+	unique, uniqueOK := idx.Unique()
+	imported := NewIndex(migrator.Index{
+		TableName:       table,
+		NameValue:       im.intern(idx.Name()),
+		ColumnList:      columns,
+		PrimaryKeyValue: sql.NullBool{Bool: primaryKey, Valid: primaryKeyOK},
+		UniqueValue:     sql.NullBool{Bool: unique, Valid: uniqueOK},
+		OptionValue:     idx.Option(),
+	})
+	if parsed, ok := idx.(*Index); ok {
+		imported.ClassValue, imported.CommentValue = parsed.ClassValue, parsed.CommentValue
+		imported.KeyBlockSizeValue, imported.ParserValue = parsed.KeyBlockSizeValue, parsed.ParserValue
+		imported.ConstraintValue, imported.UsingValue = parsed.ConstraintValue, parsed.UsingValue
+		imported.PrefixValue = parsed.PrefixValue
+	}
+	return imported
+}
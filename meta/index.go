@@ -0,0 +1,65 @@
+package meta
+
+import "gorm.io/gorm/migrator"
+
+// migratorIndex lets Index embed migrator.Index under an unexported name
+type migratorIndex = migrator.Index
+
+// Index is the gorm.Index of the indexes parsed by the built-in rawsql Parser,
+// it adds the index options migrator.Index has no field for
+type Index struct {
+	migratorIndex
+	ClassValue        string
+	ConstraintValue   string
+	UsingValue        string
+	CommentValue      string
+	KeyBlockSizeValue uint64
+	ParserValue       string
+	PrefixValue       []int
+}
+
+// NewIndex returns an Index holding the values of idx
+func NewIndex(idx migrator.Index) *Index {
+	return &Index{migratorIndex: idx}
+}
+
+// Class is FULLTEXT or SPATIAL for full-text and spatial indexes and empty
+// for the others, UNIQUE and PRIMARY KEY are reported by Unique and PrimaryKey
+func (idx *Index) Class() string {
+	return idx.ClassValue
+}
+
+// Constraint is the CONSTRAINT symbol a primary key or unique constraint was
+// declared with, MySQL names a unique index after it when no index name is
+// given and ignores it on primary keys
+func (idx *Index) Constraint() string {
+	return idx.ConstraintValue
+}
+
+// Using is the index type of the USING clause, BTREE or HASH, empty when the
+// clause was left out
+func (idx *Index) Using() string {
+	return idx.UsingValue
+}
+
+// Comment is the COMMENT index option
+func (idx *Index) Comment() string {
+	return idx.CommentValue
+}
+
+// KeyBlockSize is the KEY_BLOCK_SIZE index option, ok is false when it was not
+// given
+func (idx *Index) KeyBlockSize() (size uint64, ok bool) {
+	return idx.KeyBlockSizeValue, idx.KeyBlockSizeValue != 0
+}
+
+// PrefixLengths is the prefix length of each column, as in KEY (`name`(10)),
+// 0 for the columns indexed whole, nil when no column is a prefix
+func (idx *Index) PrefixLengths() []int {
+	return idx.PrefixValue
+}
+
+// Parser is the WITH PARSER plugin of a full-text index
+func (idx *Index) Parser() string {
+	return idx.ParserValue
+}
@@ -0,0 +1,347 @@
+package meta
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+)
+
+// SnapshotVersion is bumped whenever the serialized Table layout changes
+const SnapshotVersion = 22
+
+type snapshot struct {
+	Version int               `json:"version"`
+	Tables  map[string]*Table `json:"tables"`
+}
+
+// EncodeSnapshot writes tables as JSON, the result can be loaded with
+// DecodeSnapshot without parsing any sql
+func EncodeSnapshot(w io.Writer, tables map[string]*Table) error {
+	return json.NewEncoder(w).Encode(snapshot{Version: SnapshotVersion, Tables: tables})
+}
+
+// DecodeSnapshot reads tables written by EncodeSnapshot
+func DecodeSnapshot(r io.Reader) (map[string]*Table, error) {
+	var s snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return nil, fmt.Errorf("rawsql: unsupported snapshot version %d", s.Version)
+	}
+	if s.Tables == nil {
+		s.Tables = map[string]*Table{}
+	}
+	return s.Tables, nil
+}
+
+type tableJSON struct {
+	Name    string       `json:"name"`
+	Comment string       `json:"comment,omitempty"`
+	Columns []columnJSON `json:"columns"`
+	Indexes []indexJSON  `json:"indexes,omitempty"`
+
+	ForeignKeys []ForeignKey `json:"foreign_keys,omitempty"`
+	RawSQL      string       `json:"raw_sql,omitempty"`
+	Charset     string       `json:"charset,omitempty"`
+	Collation   string       `json:"collation,omitempty"`
+
+	AutoIncrement uint64            `json:"auto_increment,omitempty"`
+	Engine        string            `json:"engine,omitempty"`
+	Options       map[string]string `json:"options,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	Directives    map[string]string `json:"directives,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	AlterHints    []AlterHint       `json:"alter_hints,omitempty"`
+	PreviousNames []string          `json:"previous_names,omitempty"`
+	ColumnRenames []Rename          `json:"column_renames,omitempty"`
+}
+
+type columnJSON struct {
+	Name          string  `json:"name"`
+	DataType      string  `json:"data_type"`
+	ColumnType    string  `json:"column_type"`
+	PrimaryKey    *bool   `json:"primary_key,omitempty"`
+	Unique        *bool   `json:"unique,omitempty"`
+	AutoIncrement *bool   `json:"auto_increment,omitempty"`
+	Length        *int64  `json:"length,omitempty"`
+	DecimalSize   *int64  `json:"decimal_size,omitempty"`
+	Scale         *int64  `json:"scale,omitempty"`
+	Nullable      *bool   `json:"nullable,omitempty"`
+	ScanType      string  `json:"scan_type,omitempty"`
+	Comment       *string `json:"comment,omitempty"`
+	DefaultValue  *string `json:"default_value,omitempty"`
+	Unsigned      *bool   `json:"unsigned,omitempty"`
+	Charset       *string `json:"charset,omitempty"`
+	Collation     *string `json:"collation,omitempty"`
+	Invisible     bool    `json:"invisible,omitempty"`
+	Binary        bool    `json:"binary,omitempty"`
+	SRID          *int64  `json:"srid,omitempty"`
+	ColumnFormat  string  `json:"column_format,omitempty"`
+	Storage       string  `json:"storage,omitempty"`
+	DefaultKind   string  `json:"default_kind,omitempty"`
+	OnUpdate      string  `json:"on_update,omitempty"`
+	Generated     string  `json:"generated,omitempty"`
+	Stored        bool    `json:"stored,omitempty"`
+	CharLength    *int64  `json:"char_length,omitempty"`
+	OctetLength   *int64  `json:"octet_length,omitempty"`
+
+	Directives map[string]string `json:"directives,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+type indexJSON struct {
+	Table      string   `json:"table"`
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	PrimaryKey *bool    `json:"primary_key,omitempty"`
+	Unique     *bool    `json:"unique,omitempty"`
+	Option     string   `json:"option,omitempty"`
+
+	Class        string `json:"class,omitempty"`
+	Constraint   string `json:"constraint,omitempty"`
+	Using        string `json:"using,omitempty"`
+	Comment      string `json:"comment,omitempty"`
+	KeyBlockSize uint64 `json:"key_block_size,omitempty"`
+	Parser       string `json:"parser,omitempty"`
+	Prefix       []int  `json:"prefix,omitempty"`
+}
+
+func (t *Table) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.json())
+}
+
+func (t *Table) UnmarshalJSON(data []byte) error {
+	var tj tableJSON
+	if err := json.Unmarshal(data, &tj); err != nil {
+		return err
+	}
+	tj.apply(t)
+	return nil
+}
+
+func (t *Table) json() tableJSON {
+	tj := tableJSON{
+		Name:        t.Name,
+		Comment:     t.Comment,
+		Columns:     make([]columnJSON, 0, len(t.ColumnTypes)),
+		ForeignKeys: t.ForeignKeys,
+		RawSQL:      t.RawSQL,
+		Charset:     t.Charset,
+		Collation:   t.Collation,
+
+		AutoIncrement: t.AutoIncrement,
+		Engine:        t.Engine,
+		Options:       t.Options,
+		Attributes:    t.Attributes,
+		Directives:    t.Directives,
+		Metadata:      t.Metadata,
+		AlterHints:    t.AlterHints,
+		PreviousNames: t.PreviousNames,
+		ColumnRenames: t.ColumnRenames,
+	}
+	for _, ct := range t.ColumnTypes {
+		tj.Columns = append(tj.Columns, columnToJSON(ct))
+	}
+	for _, idx := range t.Indexes {
+		tj.Indexes = append(tj.Indexes, indexToJSON(idx))
+	}
+	return tj
+}
+
+func (tj tableJSON) apply(t *Table) {
+	t.Name, t.Comment, t.ForeignKeys, t.RawSQL = tj.Name, tj.Comment, tj.ForeignKeys, tj.RawSQL
+	t.Charset, t.Collation, t.AutoIncrement = tj.Charset, tj.Collation, tj.AutoIncrement
+	t.Engine, t.Options, t.Attributes, t.AlterHints = tj.Engine, tj.Options, tj.Attributes, tj.AlterHints
+	t.PreviousNames, t.ColumnRenames, t.Directives = tj.PreviousNames, tj.ColumnRenames, tj.Directives
+	t.Metadata = tj.Metadata
+	t.ColumnTypes = make([]gorm.ColumnType, 0, len(tj.Columns))
+	for _, cj := range tj.Columns {
+		t.ColumnTypes = append(t.ColumnTypes, cj.columnType())
+	}
+	NumberColumns(t.ColumnTypes)
+	t.Indexes = nil
+	for _, ij := range tj.Indexes {
+		t.Indexes = append(t.Indexes, ij.index())
+	}
+}
+
+func columnToJSON(ct gorm.ColumnType) columnJSON {
+	cj := columnJSON{Name: ct.Name(), DataType: ct.DatabaseTypeName()}
+	cj.ColumnType, _ = ct.ColumnType()
+	cj.PrimaryKey = boolPtr(ct.PrimaryKey())
+	cj.Unique = boolPtr(ct.Unique())
+	cj.AutoIncrement = boolPtr(ct.AutoIncrement())
+	cj.Nullable = boolPtr(ct.Nullable())
+	cj.Length = int64Ptr(ct.Length())
+	if precision, scale, ok := ct.DecimalSize(); ok {
+		cj.DecimalSize, cj.Scale = &precision, &scale
+	}
+	if t := ct.ScanType(); t != nil {
+		cj.ScanType = t.String()
+	}
+	cj.Comment = stringPtr(ct.Comment())
+	cj.DefaultValue = stringPtr(ct.DefaultValue())
+	if c, ok := ct.(*ColumnType); ok {
+		cj.Unsigned = boolPtr(c.Unsigned())
+		cj.Charset = stringPtr(c.Charset())
+		cj.Collation = stringPtr(c.Collation())
+		cj.Invisible = c.Invisible()
+		cj.Binary = c.Binary()
+		cj.ColumnFormat, cj.Storage = c.ColumnFormatValue, c.StorageValue
+		cj.DefaultKind = string(c.DefaultKindValue)
+		cj.OnUpdate, cj.Generated, cj.Stored = c.OnUpdateValue, c.GenerationExprValue, c.GeneratedStoredValue
+		cj.Directives, cj.Metadata = c.DirectivesValue, c.MetadataValue
+		if c.SRIDValue.Valid {
+			srid := c.SRIDValue.Int64
+			cj.SRID = &srid
+		}
+		if c.CharLengthValue.Valid && c.OctetLengthValue.Valid {
+			chars, octets := c.CharLengthValue.Int64, c.OctetLengthValue.Int64
+			cj.CharLength, cj.OctetLength = &chars, &octets
+		}
+	}
+	return cj
+}
+
+func (cj columnJSON) columnType() gorm.ColumnType {
+	ct := NewColumnType(migrator.ColumnType{
+		SQLColumnType:      &sql.ColumnType{},
+		NameValue:          sql.NullString{String: cj.Name, Valid: true},
+		DataTypeValue:      sql.NullString{String: cj.DataType, Valid: true},
+		ColumnTypeValue:    sql.NullString{String: cj.ColumnType, Valid: true},
+		PrimaryKeyValue:    nullBool(cj.PrimaryKey),
+		UniqueValue:        nullBool(cj.Unique),
+		AutoIncrementValue: nullBool(cj.AutoIncrement),
+		LengthValue:        nullInt64(cj.Length),
+		DecimalSizeValue:   nullInt64(cj.DecimalSize),
+		ScaleValue:         nullInt64(cj.Scale),
+		NullableValue:      nullBool(cj.Nullable),
+		ScanTypeValue:      scanTypes[cj.ScanType],
+		CommentValue:       nullString(cj.Comment),
+		DefaultValueValue:  nullString(cj.DefaultValue),
+	})
+	ct.UnsignedValue = nullBool(cj.Unsigned)
+	ct.CharsetValue = nullString(cj.Charset)
+	ct.CollationValue = nullString(cj.Collation)
+	ct.InvisibleValue = cj.Invisible
+	ct.BinaryValue = cj.Binary
+	ct.SRIDValue = nullInt64(cj.SRID)
+	ct.CharLengthValue, ct.OctetLengthValue = nullInt64(cj.CharLength), nullInt64(cj.OctetLength)
+	ct.ColumnFormatValue, ct.StorageValue = cj.ColumnFormat, cj.Storage
+	ct.DefaultKindValue = DefaultKind(cj.DefaultKind)
+	ct.OnUpdateValue, ct.GenerationExprValue, ct.GeneratedStoredValue = cj.OnUpdate, cj.Generated, cj.Stored
+	ct.DirectivesValue, ct.MetadataValue = cj.Directives, cj.Metadata
+	return ct
+}
+
+func indexToJSON(idx gorm.Index) indexJSON {
+	ij := indexJSON{
+		Table:      idx.Table(),
+		Name:       idx.Name(),
+		Columns:    idx.Columns(),
+		PrimaryKey: boolPtr(idx.PrimaryKey()),
+		Unique:     boolPtr(idx.Unique()),
+		Option:     idx.Option(),
+	}
+	if idx, ok := idx.(*Index); ok {
+		ij.Class, ij.Comment = idx.ClassValue, idx.CommentValue
+		ij.Constraint, ij.Using = idx.ConstraintValue, idx.UsingValue
+		ij.KeyBlockSize, ij.Parser = idx.KeyBlockSizeValue, idx.ParserValue
+		ij.Prefix = idx.PrefixValue
+	}
+	return ij
+}
+
+func (ij indexJSON) index() gorm.Index {
+	columns := ij.Columns
+	if columns == nil {
+		columns = []string{}
+	}
+	return &Index{
+		migratorIndex: migrator.Index{
+			TableName:       ij.Table,
+			NameValue:       ij.Name,
+			ColumnList:      columns,
+			PrimaryKeyValue: nullBool(ij.PrimaryKey),
+			UniqueValue:     nullBool(ij.Unique),
+			OptionValue:     ij.Option,
+		},
+		ClassValue:        ij.Class,
+		ConstraintValue:   ij.Constraint,
+		UsingValue:        ij.Using,
+		CommentValue:      ij.Comment,
+		KeyBlockSizeValue: ij.KeyBlockSize,
+		ParserValue:       ij.Parser,
+		PrefixValue:       ij.Prefix,
+	}
+}
+
+// scanTypes maps the scan types of parsed columns back from their names
+var scanTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, v := range []interface{}{int32(0), int64(0), uint32(0), uint64(0), false, "", float32(0), float64(0), time.Time{}, []float32(nil)} {
+		RegisterScanType(reflect.TypeOf(v))
+		RegisterScanType(reflect.PtrTo(reflect.TypeOf(v)))
+	}
+	// scan types of nullable columns under rawsql's Config.NullableScanType
+	for _, v := range []interface{}{sql.NullInt32{}, sql.NullInt64{}, sql.NullBool{}, sql.NullString{}, sql.NullFloat64{}, sql.NullTime{}} {
+		RegisterScanType(reflect.TypeOf(v))
+	}
+}
+
+// RegisterScanType lets snapshots restore columns scanned into t, e.g. the
+// Config.VectorScanType of rawsql, it is meant to be called from an init
+// function
+func RegisterScanType(t reflect.Type) {
+	scanTypes[t.String()] = t
+}
+
+func boolPtr(v, ok bool) *bool {
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func int64Ptr(v int64, ok bool) *int64 {
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func stringPtr(v string, ok bool) *string {
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
+func nullBool(v *bool) sql.NullBool {
+	if v == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *v, Valid: true}
+}
+
+func nullInt64(v *int64) sql.NullInt64 {
+	if v == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *v, Valid: true}
+}
+
+func nullString(v *string) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *v, Valid: true}
+}
@@ -0,0 +1,256 @@
+// Package meta holds the tables parsed by gorm.io/rawsql and their
+// snapshots. It does not depend on the TiDB parser, so programs that only load
+// pre-built snapshots stay small.
+package meta
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+)
+
+// Table is a table parsed from its CREATE TABLE and ALTER TABLE statements
+type Table struct {
+	ColumnTypes   []gorm.ColumnType // in table order after every ALTER TABLE, the built-in rawsql Parser returns *ColumnType
+	Indexes       []gorm.Index
+	ForeignKeys   []ForeignKey
+	Name          string
+	Comment       string // unescaped, see QuoteString for the reverse
+	Charset       string // default character set of the table, empty when not declared
+	Collation     string
+	AutoIncrement uint64 // the AUTO_INCREMENT=N table option, the next value of the auto increment column, 0 when not declared
+	Engine        string
+	Options       map[string]string // the other table options by upper case name, e.g. ROW_FORMAT: DYNAMIC
+	Attributes    map[string]string // the TiDB attributes of the last ALTER TABLE ... ATTRIBUTES, e.g. merge_option: deny
+	Directives    map[string]string // the -- rawsql:name=value comments before the CREATE and ALTER TABLE statements, e.g. model-name: UserAccount
+	Metadata      map[string]string // what the comment extractors of the Parser read from Comment, e.g. owner: billing
+	RawSQL        string            // the CREATE TABLE statement followed by the ALTER TABLE statements applied to the table
+	AlterHints    []AlterHint
+	PreviousNames []string // the names the table was renamed from, oldest first
+	ColumnRenames []Rename // the column renames in the order they were applied, see CurrentColumnName
+
+	Alterer func(t *Table, sql string) error `json:"-"` // what ApplyAlter applies statements with, set on the tables gorm.io/rawsql parses and decodes, see rawsql.ApplyAlter
+}
+
+// AlterHint holds the ALGORITHM and LOCK clauses of an ALTER TABLE, which
+// change how MySQL runs the statement but not the resulting table
+type AlterHint struct {
+	Algorithm string `json:"algorithm,omitempty"` // e.g. INPLACE, empty when not given
+	Lock      string `json:"lock,omitempty"`      // e.g. NONE
+}
+
+// PlacementPolicyOption is the Options key of the TiDB placement policy of a
+// table, the value is the policy name
+const PlacementPolicyOption = "PLACEMENT POLICY"
+
+// PlacementPolicy returns the name of the TiDB placement policy of the table,
+// empty when it has none
+func (t *Table) PlacementPolicy() string {
+	return t.Options[PlacementPolicyOption]
+}
+
+// Column returns the column of the table named name, compared
+// case-insensitively as MySQL compares column names
+func (t *Table) Column(name string) (gorm.ColumnType, bool) {
+	for _, ct := range t.ColumnTypes {
+		if strings.EqualFold(ct.Name(), name) {
+			return ct, true
+		}
+	}
+	return nil, false
+}
+
+// ColumnNames returns the names of the columns of the table in table order,
+// as they were declared
+func (t *Table) ColumnNames() []string {
+	names := make([]string, len(t.ColumnTypes))
+	for i, ct := range t.ColumnTypes {
+		names[i] = ct.Name()
+	}
+	return names
+}
+
+// AutoIncrementColumn returns the AUTO_INCREMENT column of the table, whose
+// next value is AutoIncrement
+func (t *Table) AutoIncrementColumn() (gorm.ColumnType, bool) {
+	for _, ct := range t.ColumnTypes {
+		if autoIncrement, _ := ct.AutoIncrement(); autoIncrement {
+			return ct, true
+		}
+	}
+	return nil, false
+}
+
+// PrimaryKeyColumns returns the primary key columns of the table in key order,
+// the first leads the clustered index, nil when the table has no primary key
+func (t *Table) PrimaryKeyColumns() []string {
+	for _, idx := range t.Indexes {
+		if pk, _ := idx.PrimaryKey(); pk {
+			return append([]string(nil), idx.Columns()...)
+		}
+	}
+	// a primary key declared on a column has no index
+	var columns []string
+	for _, ct := range t.ColumnTypes {
+		if pk, _ := ct.PrimaryKey(); pk {
+			columns = append(columns, ct.Name())
+		}
+	}
+	return columns
+}
+
+// IndexesFor returns the indexes of the table including column, in
+// declaration order, compared case-insensitively as MySQL does
+func (t *Table) IndexesFor(column string) []gorm.Index {
+	var indexes []gorm.Index
+	for _, idx := range t.Indexes {
+		for _, name := range idx.Columns() {
+			if strings.EqualFold(name, column) {
+				indexes = append(indexes, idx)
+				break
+			}
+		}
+	}
+	return indexes
+}
+
+// IsIndexed reports whether an index includes column, counting the PRIMARY
+// KEY and UNIQUE declared on the column itself, which have no index
+func (t *Table) IsIndexed(column string) bool {
+	if len(t.IndexesFor(column)) > 0 {
+		return true
+	}
+	if ct, ok := t.Column(column); ok {
+		pk, _ := ct.PrimaryKey()
+		unique, _ := ct.Unique()
+		return pk || unique
+	}
+	return false
+}
+
+// ForeignKey is a FOREIGN KEY constraint of a table
+type ForeignKey struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete,omitempty"` // referential action, e.g. CASCADE, empty when not declared
+	OnUpdate          string   `json:"on_update,omitempty"`
+}
+
+// Rename is a table or column renamed from From to To
+type Rename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ApplyAlter applies the ALTER TABLE statement sql to the table in place, e.g.
+// to a table decoded from a snapshot, with its Alterer. meta has no parser,
+// tables decoded by meta alone need Alterer set, e.g. to rawsql.ApplyAlter.
+func (t *Table) ApplyAlter(sql string) error {
+	if t.Alterer == nil {
+		return errors.New("meta: ApplyAlter needs the Alterer of the table, e.g. rawsql.ApplyAlter")
+	}
+	return t.Alterer(t, sql)
+}
+
+// CurrentColumnName follows the column renames of the table from the column
+// first known as name to its current name, name itself when it was never
+// renamed
+func (t *Table) CurrentColumnName(name string) string {
+	for _, r := range t.ColumnRenames {
+		if strings.EqualFold(r.From, name) {
+			name = r.To
+		}
+	}
+	return name
+}
+
+// Clone returns a deep copy of the table, so changing it or its columns and
+// indexes leaves t untouched. Columns and indexes of types other than
+// *ColumnType, *Index and the migrator ones are shared.
+func (t *Table) Clone() *Table {
+	c := *t
+	if t.ColumnTypes != nil {
+		c.ColumnTypes = CloneColumnTypes(t.ColumnTypes)
+	}
+	if t.Indexes != nil {
+		c.Indexes = CloneIndexes(t.Indexes)
+	}
+	if t.ForeignKeys != nil {
+		c.ForeignKeys = make([]ForeignKey, len(t.ForeignKeys))
+		for i, fk := range t.ForeignKeys {
+			fk.Columns = append([]string(nil), fk.Columns...)
+			fk.ReferencedColumns = append([]string(nil), fk.ReferencedColumns...)
+			c.ForeignKeys[i] = fk
+		}
+	}
+	c.Options = cloneMap(t.Options)
+	c.Attributes = cloneMap(t.Attributes)
+	c.Directives = cloneMap(t.Directives)
+	c.Metadata = cloneMap(t.Metadata)
+	c.AlterHints = append([]AlterHint(nil), t.AlterHints...)
+	c.PreviousNames = append([]string(nil), t.PreviousNames...)
+	c.ColumnRenames = append([]Rename(nil), t.ColumnRenames...)
+	return &c
+}
+
+// CloneColumnTypes returns deep copies of cols as Table.Clone makes them
+func CloneColumnTypes(cols []gorm.ColumnType) []gorm.ColumnType {
+	cloned := make([]gorm.ColumnType, len(cols))
+	for i, ct := range cols {
+		cloned[i] = cloneColumnType(ct)
+	}
+	return cloned
+}
+
+// CloneIndexes returns deep copies of indexes as Table.Clone makes them
+func CloneIndexes(indexes []gorm.Index) []gorm.Index {
+	cloned := make([]gorm.Index, len(indexes))
+	for i, idx := range indexes {
+		cloned[i] = cloneIndex(idx)
+	}
+	return cloned
+}
+
+func cloneColumnType(ct gorm.ColumnType) gorm.ColumnType {
+	switch ct := ct.(type) {
+	case *ColumnType:
+		c := *ct
+		c.DirectivesValue = cloneMap(ct.DirectivesValue)
+		c.MetadataValue = cloneMap(ct.MetadataValue)
+		return &c
+	case *migrator.ColumnType:
+		c := *ct
+		return &c
+	}
+	return ct
+}
+
+func cloneIndex(idx gorm.Index) gorm.Index {
+	switch idx := idx.(type) {
+	case *Index:
+		c := *idx
+		c.ColumnList = append([]string{}, idx.ColumnList...)
+		c.PrefixValue = append([]int(nil), idx.PrefixValue...)
+		return &c
+	case *migrator.Index:
+		c := *idx
+		c.ColumnList = append([]string{}, idx.ColumnList...)
+		return &c
+	}
+	return idx
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
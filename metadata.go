@@ -0,0 +1,159 @@
+package rawsql
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CommentExtractor reads metadata off the comment of a table or column, named
+// in Config.CommentExtractors once registered with RegisterCommentExtractor
+type CommentExtractor interface {
+	// Extract returns the key/value pairs comment holds, nil when it has none
+	Extract(comment string) map[string]string
+}
+
+// CommentExtractorFunc is a CommentExtractor of a function
+type CommentExtractorFunc func(comment string) map[string]string
+
+func (f CommentExtractorFunc) Extract(comment string) map[string]string {
+	return f(comment)
+}
+
+// RegexpExtractor returns a CommentExtractor setting a key per match of re:
+// the text of its key group, or key when re has none, lower cased. The value
+// is the text of its value group, or of its first group, or true when re has
+// no group, e.g. RegexpExtractor(regexp.MustCompile(`@(?P<key>\w+)=(?P<value>\S+)`), "")
+// reads @owner=billing.
+func RegexpExtractor(re *regexp.Regexp, key string) CommentExtractor {
+	keyGroup, valueGroup := re.SubexpIndex("key"), re.SubexpIndex("value")
+	if valueGroup < 0 && re.NumSubexp() > 0 && keyGroup != 1 {
+		valueGroup = 1
+	}
+	return CommentExtractorFunc(func(comment string) map[string]string {
+		var metadata map[string]string
+		for _, m := range re.FindAllStringSubmatch(comment, -1) {
+			k, v := key, "true"
+			if keyGroup >= 0 {
+				k = m[keyGroup]
+			}
+			if valueGroup >= 0 {
+				v = m[valueGroup]
+			}
+			if k == "" {
+				continue
+			}
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata[strings.ToLower(k)] = v
+		}
+		return metadata
+	})
+}
+
+// SensitivityExtractor is the name of the built-in CommentExtractor reading
+// the markers of ColumnSensitivity: the classifications into sensitivity, e.g.
+// PII,PCI, and the masking rule into mask
+const SensitivityExtractor = "sensitivity"
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = map[string]CommentExtractor{SensitivityExtractor: CommentExtractorFunc(sensitivityMetadata)}
+)
+
+// RegisterCommentExtractor makes a CommentExtractor available by name, it is
+// meant to be called from an init function and panics when name is already
+// registered
+func RegisterCommentExtractor(name string, extractor CommentExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	if extractor == nil {
+		panic("rawsql: RegisterCommentExtractor extractor is nil")
+	}
+	if _, dup := extractors[name]; dup {
+		panic("rawsql: RegisterCommentExtractor called twice for extractor " + name)
+	}
+	extractors[name] = extractor
+}
+
+// CommentExtractors returns the names of the registered comment extractors,
+// sorted
+func CommentExtractors() []string {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	names := make([]string, 0, len(extractors))
+	for name := range extractors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// commentExtractors returns the extractors Config.CommentExtractors names, in
+// order
+func (config *Config) commentExtractors() ([]CommentExtractor, error) {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+	var selected []CommentExtractor
+	for _, name := range config.CommentExtractors {
+		extractor, ok := extractors[name]
+		if !ok {
+			return nil, fmt.Errorf("rawsql: unknown comment extractor %q (forgotten import?)", name)
+		}
+		selected = append(selected, extractor)
+	}
+	return selected, nil
+}
+
+// extractMetadata merges what extractors read from comment, later extractors
+// overriding the keys of earlier ones
+func extractMetadata(extractors []CommentExtractor, comment string) map[string]string {
+	if comment == "" {
+		return nil
+	}
+	var metadata map[string]string
+	for _, extractor := range extractors {
+		for k, v := range extractor.Extract(comment) {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata[k] = v
+		}
+	}
+	return metadata
+}
+
+// applyMetadata sets the Metadata of table and its columns from their
+// comments, again whenever an ALTER TABLE changes them
+func (d *defaultParser) applyMetadata(table *Table) {
+	if len(d.config.CommentExtractors) == 0 {
+		return
+	}
+	// the names were checked by Initialize
+	extractors, _ := d.config.commentExtractors()
+	table.Metadata = extractMetadata(extractors, table.Comment)
+	for _, ct := range table.ColumnTypes {
+		if c, ok := ct.(*ColumnType); ok {
+			comment, _ := c.Comment()
+			c.MetadataValue = extractMetadata(extractors, comment)
+		}
+	}
+}
+
+func sensitivityMetadata(comment string) map[string]string {
+	s := sensitivityOf(comment)
+	if !s.Sensitive() {
+		return nil
+	}
+	metadata := make(map[string]string)
+	if len(s.Classes) > 0 {
+		metadata["sensitivity"] = strings.Join(s.Classes, ",")
+	}
+	if s.Mask != "" {
+		metadata["mask"] = s.Mask
+	}
+	return metadata
+}
@@ -0,0 +1,26 @@
+package rawsql
+
+import "time"
+
+// Metrics receives counters and timings of every load and watch reload, the
+// methods map onto Prometheus or OpenTelemetry counters and histograms.
+// Loads served from CacheDir parse nothing and are not reported.
+type Metrics interface {
+	StatementsParsed(n int)        // statements applied to the schema
+	StatementsSkipped(n int)       // statements ignored, e.g. DML, SET and unsupported statements
+	StatementsFailed(n int)        // statements failing the load, a syntax error, a semantic error or a Strict warning
+	ParseDuration(d time.Duration) // time spent on a load, failed or not
+}
+
+// observe reports a load to Metrics, failed loads count one failed statement
+func (dialector Dialector) observe(report *ParseReport, start time.Time, ok bool) {
+	if dialector.Metrics == nil {
+		return
+	}
+	dialector.Metrics.StatementsParsed(report.Statements - report.Skipped)
+	dialector.Metrics.StatementsSkipped(report.Skipped)
+	if !ok {
+		dialector.Metrics.StatementsFailed(1)
+	}
+	dialector.Metrics.ParseDuration(time.Since(start))
+}
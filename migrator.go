@@ -6,6 +6,7 @@ import (
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/migrator"
+	"gorm.io/rawsql/meta"
 )
 
 type Migrator struct {
@@ -18,7 +19,7 @@ func (m Migrator) TableType(value interface{}) (tableType gorm.TableType, err er
 		var (
 			schema, tableName = m.CurrentSchema(stmt, stmt.Table)
 		)
-		table, ok := m.tables[tableName]
+		table, ok := m.store.get()[tableName]
 		if ok && table != nil {
 			tableType = &migrator.TableType{
 				SchemaValue:  schema,
@@ -36,9 +37,9 @@ func (m Migrator) ColumnTypes(value interface{}) ([]gorm.ColumnType, error) {
 		var (
 			_, tableName = m.CurrentSchema(stmt, stmt.Table)
 		)
-		table, ok := m.tables[tableName]
+		table, ok := m.store.get()[tableName]
 		if ok && table != nil {
-			columnTypes = table.ColumnTypes
+			columnTypes = meta.CloneColumnTypes(table.ColumnTypes)
 		}
 		return nil
 	})
@@ -51,9 +52,9 @@ func (m Migrator) GetIndexes(value interface{}) ([]gorm.Index, error) {
 		var (
 			_, tableName = m.CurrentSchema(stmt, stmt.Table)
 		)
-		table, ok := m.tables[tableName]
+		table, ok := m.store.get()[tableName]
 		if ok && table != nil {
-			indexes = table.Indexes
+			indexes = meta.CloneIndexes(table.Indexes)
 		}
 		return nil
 	})
@@ -61,8 +62,9 @@ func (m Migrator) GetIndexes(value interface{}) ([]gorm.Index, error) {
 }
 
 func (m Migrator) GetTables() (tableList []string, err error) {
-	tableList = make([]string, 0, len(m.tables))
-	for tb, _ := range m.tables {
+	tables := m.store.get()
+	tableList = make([]string, 0, len(tables))
+	for tb, _ := range tables {
 		tableList = append(tableList, tb)
 	}
 	return tableList, nil
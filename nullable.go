@@ -0,0 +1,87 @@
+package rawsql
+
+import (
+	"database/sql"
+	"reflect"
+)
+
+// NullableStyle is how the scan types of nullable columns allow for NULL, see
+// Config.NullableScanType
+type NullableStyle int
+
+const (
+	NullableAsValue   NullableStyle = iota // the type of the NOT NULL column, e.g. int64, leaving NULL to the code generator
+	NullableAsPointer                      // a pointer to it, e.g. *int64
+	NullableAsSQLNull                      // the database/sql Null type, e.g. sql.NullInt64, or a pointer for types without one, e.g. *uint64
+)
+
+// sqlNullTypes are the database/sql Null types of scan types, each stands for
+// a single scan type so the Null type of a column can be turned back
+var sqlNullTypes = map[reflect.Type]reflect.Type{
+	intT:    reflect.TypeOf(sql.NullInt32{}),
+	longT:   reflect.TypeOf(sql.NullInt64{}),
+	boolT:   reflect.TypeOf(sql.NullBool{}),
+	stringT: reflect.TypeOf(sql.NullString{}),
+	doubleT: reflect.TypeOf(sql.NullFloat64{}),
+	timeT:   reflect.TypeOf(sql.NullTime{}),
+}
+
+// sqlNullValues maps the database/sql Null types back to their scan types
+var sqlNullValues = map[reflect.Type]reflect.Type{}
+
+func init() {
+	for t, null := range sqlNullTypes {
+		sqlNullValues[null] = t
+	}
+}
+
+// nullableScanType returns the scan type of a column whose NOT NULL scan type
+// is t
+func (style NullableStyle) nullableScanType(t reflect.Type) reflect.Type {
+	switch style {
+	case NullableAsPointer:
+		return reflect.PtrTo(t)
+	case NullableAsSQLNull:
+		if null, ok := sqlNullTypes[t]; ok {
+			return null
+		}
+		return reflect.PtrTo(t)
+	}
+	return t
+}
+
+// applyNullable sets the scan types of the columns of table following
+// Config.NullableScanType, again whenever an ALTER TABLE changes whether they
+// are nullable
+func (d *defaultParser) applyNullable(table *Table) {
+	style := d.config.NullableScanType
+	if style == NullableAsValue {
+		return
+	}
+	for _, ct := range table.ColumnTypes {
+		c, ok := ct.(*ColumnType)
+		if !ok || c.ScanTypeValue == nil {
+			continue
+		}
+		nullable, _ := c.Nullable()
+		c.ScanTypeValue = style.scanType(c.ScanTypeValue, nullable)
+	}
+}
+
+// scanType returns the scan type of the values of a column whose scan type
+// is t, t of a nullable column when nullable is false, e.g. for the values
+// the column is compared with
+func (style NullableStyle) scanType(t reflect.Type, nullable bool) reflect.Type {
+	if style == NullableAsValue || t == nil {
+		return t
+	}
+	if value, ok := sqlNullValues[t]; ok {
+		t = value
+	} else if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if nullable {
+		t = style.nullableScanType(t)
+	}
+	return t
+}
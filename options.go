@@ -0,0 +1,111 @@
+package rawsql
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+	"gorm.io/rawsql/meta"
+)
+
+// applyTableOptions records the table options of a CREATE or ALTER TABLE,
+// charsets are handled by tableCharset
+func (d *defaultParser) applyTableOptions(table *Table, options []*ast.TableOption) {
+	for _, opt := range options {
+		switch opt.Tp {
+		case ast.TableOptionCharset, ast.TableOptionCollate:
+		case ast.TableOptionEngine:
+			table.Engine = d.intern(opt.StrValue)
+		case ast.TableOptionComment:
+			table.Comment = d.intern(opt.StrValue)
+		case ast.TableOptionAutoIncrement:
+			table.AutoIncrement = opt.UintValue
+		case ast.TableOptionPlacementPolicy:
+			// PLACEMENT POLICY=DEFAULT detaches the table from its policy
+			if strings.EqualFold(opt.StrValue, "default") {
+				delete(table.Options, meta.PlacementPolicyOption)
+				continue
+			}
+			if table.Options == nil {
+				table.Options = make(map[string]string)
+			}
+			table.Options[meta.PlacementPolicyOption] = d.intern(opt.StrValue)
+		default:
+			name, value := tableOption(opt)
+			if name == "" {
+				continue
+			}
+			if table.Options == nil {
+				table.Options = make(map[string]string)
+			}
+			table.Options[d.intern(name)] = d.intern(value)
+		}
+	}
+}
+
+// tableOption splits the restored text of an option, e.g. ROW_FORMAT = DYNAMIC
+func tableOption(opt *ast.TableOption) (name, value string) {
+	var b strings.Builder
+	if err := opt.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &b)); err != nil {
+		return "", ""
+	}
+	text := b.String()
+	// options the tidb parser ignores restore as a comment
+	if i := strings.Index(text, "/*"); i >= 0 {
+		text = text[:i]
+	}
+	name, value = text, ""
+	if i := strings.Index(text, "="); i >= 0 {
+		name, value = text[:i], text[i+1:]
+	}
+	return strings.ToUpper(strings.TrimSpace(name)), strings.TrimSpace(value)
+}
+
+// alterTableOptions applies the table options of an ALTER TABLE, CONVERT TO
+// CHARACTER SET also converts every character column
+func (d *defaultParser) alterTableOptions(table *Table, spec *ast.AlterTableSpec) {
+	d.applyTableOptions(table, spec.Options)
+
+	charset, collation := tableCharset(spec.Options)
+	if charset == "" {
+		return
+	}
+	table.Charset, table.Collation = d.intern(charset), d.intern(collation)
+	convert := false
+	for _, opt := range spec.Options {
+		convert = convert || opt.Tp == ast.TableOptionCharset && opt.UintValue == ast.TableOptionCharsetWithConvertTo
+	}
+	if !convert {
+		return
+	}
+	for _, col := range table.ColumnTypes {
+		if ct, ok := col.(*ColumnType); ok && charTypes[ct.DatabaseTypeName()] {
+			ct.CharsetValue, ct.CollationValue = sql.NullString{}, sql.NullString{}
+			d.inheritCharset(table, ct)
+		}
+	}
+}
+
+// alterAttributes replaces the TiDB attributes of table with those of an
+// ALTER TABLE ... ATTRIBUTES, a comma separated list of key=value pairs, and
+// removes them for ATTRIBUTES=DEFAULT
+func (d *defaultParser) alterAttributes(table *Table, spec *ast.AttributesSpec) {
+	table.Attributes = nil
+	if spec.Default {
+		return
+	}
+	for _, attr := range strings.Split(spec.Attributes, ",") {
+		name, value := strings.TrimSpace(attr), ""
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+		}
+		if name == "" {
+			continue
+		}
+		if table.Attributes == nil {
+			table.Attributes = make(map[string]string)
+		}
+		table.Attributes[d.intern(name)] = d.intern(value)
+	}
+}
@@ -0,0 +1,91 @@
+package rawsql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CycleError is returned when foreign keys reference each other in a loop, so
+// no order creates every referenced table first
+type CycleError struct {
+	Tables []string // the tables of one cycle, each referencing the next and the last the first
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("rawsql: foreign key cycle %s -> %s", strings.Join(e.Tables, " -> "), e.Tables[0])
+}
+
+// TablesInDependencyOrder returns the tables of the last load or watch reload
+// so that every table comes after the tables its foreign keys reference, see
+// the TablesInDependencyOrder function
+func (dialector Dialector) TablesInDependencyOrder() ([]*Table, error) {
+	if dialector.store == nil {
+		return TablesInDependencyOrder(nil)
+	}
+	tables := make(map[string]*Table)
+	for name, table := range dialector.store.get() {
+		tables[name] = table.Clone()
+	}
+	return TablesInDependencyOrder(tables)
+}
+
+// TablesInDependencyOrder sorts tables so that every table comes after the
+// tables its foreign keys reference, the order CREATE TABLE statements or seed
+// data can be executed in. Ties are broken by name, self references and
+// references to tables not in tables are ignored, and a *CycleError is
+// returned when the references form a loop.
+func TablesInDependencyOrder(tables map[string]*Table) ([]*Table, error) {
+	g := NewGraph(tables)
+	pending := make(map[string]int, len(g.tables))
+	ready := make([]string, 0, len(g.tables))
+	for _, name := range g.tables {
+		if pending[name] = len(g.references[name]); pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	ordered := make([]*Table, 0, len(g.tables))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, tables[name])
+		delete(pending, name)
+		for _, dependent := range g.referencedBy[name] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(pending) > 0 {
+		return nil, &CycleError{Tables: findCycle(pending, g.references)}
+	}
+	return ordered, nil
+}
+
+// findCycle follows the references between the tables left unordered, each of
+// them still references another one so the walk always ends up in a loop
+func findCycle(pending map[string]int, references map[string][]string) []string {
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	visited := map[string]int{}
+	path := make([]string, 0)
+	for name := names[0]; ; {
+		if at, ok := visited[name]; ok {
+			return path[at:]
+		}
+		visited[name] = len(path)
+		path = append(path, name)
+		next := ""
+		for _, ref := range references[name] {
+			if _, ok := pending[ref]; ok && (next == "" || ref < next) {
+				next = ref
+			}
+		}
+		name = next
+	}
+}
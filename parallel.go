@@ -0,0 +1,142 @@
+package rawsql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// parseConcurrently turns every sql into statements using up to workers
+// goroutines. Only the syntax parsing runs concurrently, the results keep the
+// input order so statements can be applied one file after another, and the
+// error of the first failing sql in input order is returned.
+func (d *defaultParser) parseConcurrently(sqls []string, workers int) ([][]ast.StmtNode, []time.Duration, error) {
+	results := make([][]ast.StmtNode, len(sqls))
+	durations := make([]time.Duration, len(sqls))
+	errs := make([]error, len(sqls))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				start := time.Now()
+				results[i], errs[i] = d.parseStmts(sqls[i])
+				durations[i] = time.Since(start)
+			}
+		}()
+	}
+	for i := range sqls {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return results, durations, nil
+}
+
+func (dialector Dialector) parseSQL(ctx context.Context, streamAt int, report *ParseReport) error {
+	sqls := dialector.SQL
+	dropped := make([]int, len(sqls))
+	if dialector.SkipDML {
+		sqls = make([]string, len(dialector.SQL))
+		for i, sql := range dialector.SQL {
+			filtered, n, err := filterDML(sql)
+			if err != nil {
+				return err
+			}
+			sqls[i], dropped[i] = filtered, n
+		}
+	}
+
+	d, ok := dialector.Parser.(*defaultParser)
+	if !ok || dialector.Parallelism <= 1 || len(sqls) <= 1 {
+		for i, sql := range sqls {
+			if err := dialector.streamReadersAt(ctx, i, streamAt, report); err != nil {
+				return err
+			}
+			span := dialector.startParseSpan(ctx)
+			start, before, warnings := time.Now(), dialector.parseStats(), dialector.parseWarnings()
+			err := dialector.Parser.ParseSQL(sql)
+			report.add(dialector.sqlName(i), before, dialector.parseStats(), dropped[i], dialector.newWarnings(warnings), time.Since(start))
+			dialector.endParseSpan(span, report, err)
+			if err != nil {
+				return err
+			}
+		}
+		return dialector.streamReadersAt(ctx, len(sqls), streamAt, report)
+	}
+
+	workers := dialector.Parallelism
+	if workers > len(sqls) {
+		workers = len(sqls)
+	}
+	stmts, durations, err := d.parseConcurrently(sqls, workers)
+	if err != nil {
+		return err
+	}
+	for i, nodes := range stmts {
+		if err = dialector.streamReadersAt(ctx, i, streamAt, report); err != nil {
+			return err
+		}
+		span := dialector.startParseSpan(ctx)
+		start, before, warnings := time.Now(), d.stats, len(d.warnings)
+		err = d.applyStmts(nodes)
+		report.add(dialector.sqlName(i), before, d.stats, dropped[i], d.warnings[warnings:], durations[i]+time.Since(start))
+		dialector.endParseSpan(span, report, err)
+		if err != nil {
+			return err
+		}
+	}
+	return dialector.streamReadersAt(ctx, len(stmts), streamAt, report)
+}
+
+func (dialector Dialector) streamReadersAt(ctx context.Context, i, streamAt int, report *ParseReport) error {
+	if i != streamAt {
+		return nil
+	}
+	for i, r := range dialector.Readers {
+		if r == nil {
+			continue
+		}
+		span := dialector.startParseSpan(ctx)
+		start, before, warnings := time.Now(), dialector.parseStats(), dialector.parseWarnings()
+		dropped, err := parseReader(dialector.Parser, r, dialector.SkipDML)
+		report.add(readerName(i), before, dialector.parseStats(), dropped, dialector.newWarnings(warnings), time.Since(start))
+		dialector.endParseSpan(span, report, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startParseSpan starts the rawsql.parse span of an sql content, the
+// built-in Parser starts a rawsql.statement span below it per statement
+func (dialector Dialector) startParseSpan(ctx context.Context) Span {
+	ctx, span := dialector.startSpan(ctx, "rawsql.parse")
+	if d, ok := dialector.Parser.(*defaultParser); ok && dialector.Tracer != nil {
+		d.spanContext = ctx
+	}
+	return span
+}
+
+// endParseSpan ends the span of the sql content report last added
+func (dialector Dialector) endParseSpan(span Span, report *ParseReport, err error) {
+	if d, ok := dialector.Parser.(*defaultParser); ok {
+		d.spanContext = nil
+	}
+	file := report.Files[len(report.Files)-1]
+	span.SetAttribute("rawsql.source", file.Name)
+	span.SetAttribute("rawsql.statements", file.Statements)
+	endSpan(span, err)
+}
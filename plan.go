@@ -0,0 +1,231 @@
+package rawsql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// AlterSQL returns the statements turning the old version of the table into
+// the new one: the removed foreign keys are dropped first, the other changes
+// are made by a single ALTER TABLE and the added foreign keys come last, as
+// MySQL cannot drop and add a foreign key of the same name at once. It is
+// empty when the diff is or was not made by CompareTables.
+func (diff TableDiff) AlterSQL() []string {
+	if diff.from == nil || diff.to == nil || diff.Empty() {
+		return nil
+	}
+	var stmts []string
+	if len(diff.RemovedForeignKeys) > 0 {
+		clauses := make([]string, len(diff.RemovedForeignKeys))
+		for i, fk := range diff.RemovedForeignKeys {
+			clauses[i] = "DROP FOREIGN KEY " + quoteName(fk.Name)
+		}
+		stmts = append(stmts, alterSQL(diff.from.Name, clauses))
+	}
+	if clauses := diff.alterClauses(); len(clauses) > 0 {
+		stmts = append(stmts, alterSQL(diff.from.Name, clauses))
+	}
+	if len(diff.AddedForeignKeys) > 0 {
+		clauses := make([]string, len(diff.AddedForeignKeys))
+		for i, fk := range diff.AddedForeignKeys {
+			clauses[i] = "ADD " + foreignKeySQL(fk)
+		}
+		stmts = append(stmts, alterSQL(diff.to.Name, clauses))
+	}
+	return stmts
+}
+
+func alterSQL(table string, clauses []string) string {
+	return "ALTER TABLE " + quoteName(table) + " " + strings.Join(clauses, ", ") + ";"
+}
+
+// alterClauses returns the clauses of the ALTER TABLE making every change but
+// those of the foreign keys, indexes are dropped before their columns and
+// added after them
+func (diff TableDiff) alterClauses() (clauses []string) {
+	var options []string
+	for _, change := range diff.Changes {
+		value := change.To
+		switch change.Field {
+		case "name":
+			clauses = append(clauses, "RENAME TO "+value)
+			continue
+		case "engine":
+			options = append(options, "ENGINE="+value)
+			continue
+		case "charset":
+			options = append(options, "DEFAULT CHARSET="+value)
+			continue
+		case "collation":
+			options = append(options, "COLLATE="+value)
+			continue
+		case "comment":
+			if value == "" {
+				value = "''"
+			}
+			options = append(options, "COMMENT="+value)
+			continue
+		}
+		switch {
+		case value == "":
+			options = append(options, change.Field+"=DEFAULT")
+		case value == change.Field:
+			options = append(options, change.Field)
+		default:
+			options = append(options, change.Field+"="+value)
+		}
+	}
+
+	// a primary key declared on columns has no index, it is dropped and added
+	// again as a whole when its columns change, one of a PRIMARY index changes
+	// with the other indexes
+	columnKey := !hasPrimaryIndex(diff.from) && !hasPrimaryIndex(diff.to) && diff.columnKeyChanged()
+	if columnKey && len(diff.from.PrimaryKeyColumns()) > 0 {
+		clauses = append(clauses, "DROP PRIMARY KEY")
+	}
+	dropIndex := func(idx gorm.Index) {
+		if pk, _ := idx.PrimaryKey(); pk {
+			clauses = append(clauses, "DROP PRIMARY KEY")
+		} else if idx.Name() != "" {
+			clauses = append(clauses, "DROP INDEX "+quoteName(idx.Name()))
+		}
+	}
+	for _, idx := range diff.RemovedIndexes {
+		dropIndex(idx)
+	}
+	for _, idx := range diff.ChangedIndexes {
+		dropIndex(idx.From)
+	}
+	for _, ct := range diff.RemovedColumns {
+		clauses = append(clauses, "DROP COLUMN "+quoteName(ct.Name()))
+	}
+
+	var added []string
+	for _, column := range diff.ChangedColumns {
+		clause := "MODIFY COLUMN " + columnSQL(diff.to, column.To)
+		if column.From.Name() != column.To.Name() {
+			clause = "CHANGE COLUMN " + quoteName(column.From.Name()) + " " + columnSQL(diff.to, column.To)
+		}
+		clauses = append(clauses, clause)
+		// unique keys declared on the column are indexes MODIFY COLUMN adds
+		// but does not drop
+		for _, change := range column.Changes {
+			if change.Field == "unique" && change.To == "" {
+				clauses = append(clauses, "DROP INDEX "+quoteName(column.From.Name()))
+			}
+		}
+	}
+	for _, ct := range diff.AddedColumns {
+		clauses = append(clauses, "ADD COLUMN "+columnSQL(diff.to, ct)+columnPosition(diff.to, ct))
+	}
+	if columns := diff.to.PrimaryKeyColumns(); columnKey && len(columns) > 0 {
+		added = append(added, "ADD PRIMARY KEY ("+quoteNames(columns)+")")
+	}
+	clauses = append(clauses, added...)
+	for _, idx := range diff.ChangedIndexes {
+		clauses = append(clauses, "ADD "+IndexSQL(idx.To))
+	}
+	for _, idx := range diff.AddedIndexes {
+		clauses = append(clauses, "ADD "+IndexSQL(idx))
+	}
+	return append(clauses, options...)
+}
+
+// columnKeyChanged reports whether a column joins or leaves the primary key
+// declared on the columns
+func (diff TableDiff) columnKeyChanged() bool {
+	for _, column := range diff.ChangedColumns {
+		for _, change := range column.Changes {
+			if change.Field == "primary key" {
+				return true
+			}
+		}
+	}
+	for _, columns := range [][]gorm.ColumnType{diff.AddedColumns, diff.RemovedColumns} {
+		for _, ct := range columns {
+			if pk, _ := ct.PrimaryKey(); pk {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasPrimaryIndex(table *Table) bool {
+	for _, idx := range table.Indexes {
+		if pk, _ := idx.PrimaryKey(); pk {
+			return true
+		}
+	}
+	return false
+}
+
+// columnPosition returns the FIRST or AFTER clause placing ct where it is in
+// table
+func columnPosition(table *Table, ct gorm.ColumnType) string {
+	i := columnIndex(table.ColumnTypes, ct.Name())
+	switch {
+	case i == 0:
+		return " FIRST"
+	case i > 0 && i < len(table.ColumnTypes)-1:
+		return " AFTER " + quoteName(table.ColumnTypes[i-1].Name())
+	}
+	// added columns go last
+	return ""
+}
+
+// ApplyOptions configures ApplyPlan
+type ApplyOptions struct {
+	DryRun          bool             // log the statements without executing them
+	ContinueOnError bool             // execute the statements following a failed one instead of stopping
+	Logger          logger.Interface // receives every statement and how it went, defaults to the logger of the db
+}
+
+// AppliedStatement is a statement of a plan ApplyPlan went through
+type AppliedStatement struct {
+	SQL      string
+	Duration time.Duration
+	Skipped  bool  // not executed under ApplyOptions.DryRun
+	Err      error // of executing it
+}
+
+// ApplyPlan executes the statements of a plan, e.g. the AlterSQL of the
+// diffs between the parsed tables and those of a live database, through db in
+// order. It stops at the first statement failing unless ContinueOnError is
+// set, and returns the statements it went through with the first error.
+func ApplyPlan(db *gorm.DB, plan []string, opts ApplyOptions) (applied []AppliedStatement, err error) {
+	log := opts.Logger
+	if log == nil {
+		log = db.Logger
+	}
+	if log == nil {
+		log = logger.Discard
+	}
+	ctx := db.Statement.Context
+	for i, stmt := range plan {
+		if opts.DryRun {
+			log.Info(ctx, "rawsql: dry run %d/%d: %s", i+1, len(plan), stmt)
+			applied = append(applied, AppliedStatement{SQL: stmt, Skipped: true})
+			continue
+		}
+		start := time.Now()
+		e := db.Exec(stmt).Error
+		applied = append(applied, AppliedStatement{SQL: stmt, Duration: time.Since(start), Err: e})
+		if e == nil {
+			log.Info(ctx, "rawsql: applied %d/%d in %s: %s", i+1, len(plan), time.Since(start), stmt)
+			continue
+		}
+		log.Error(ctx, "rawsql: failed %d/%d: %s: %v", i+1, len(plan), stmt, e)
+		if err == nil {
+			err = fmt.Errorf("rawsql: apply %s: %w", stmt, e)
+		}
+		if !opts.ContinueOnError {
+			break
+		}
+	}
+	return applied, err
+}
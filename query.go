@@ -0,0 +1,740 @@
+package rawsql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/opcode"
+	"gorm.io/gorm"
+)
+
+// CheckQuery checks the SELECT, INSERT, REPLACE, UPDATE and DELETE
+// statements of sql against the parsed tables, the way MySQL would check them
+// on a migrated database: it reports unknown tables and columns, ambiguous
+// column names, literals the types of their columns do not take, NULL values
+// of NOT NULL columns and INSERT statements leaving out NOT NULL columns
+// without a default. Other statements are skipped, the error is only about
+// sql that does not parse.
+func (dialector Dialector) CheckQuery(sql string) ([]Issue, error) {
+	stmtNodes, err := dialector.parseQueries(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, node := range stmtNodes {
+		c := newQueryChecker(dialector.store.get(), node)
+		c.checkStmt(node)
+		issues = append(issues, c.issues...)
+	}
+	return issues, nil
+}
+
+// parseQueries parses sql as the parser of the dialector does, without
+// applying it to the parsed tables
+func (dialector Dialector) parseQueries(sql string) ([]ast.StmtNode, error) {
+	config := *dialector.Config
+	config.Parser, config.OnStatement, config.Logger, config.Strict, config.BaselineLock = nil, nil, nil, false, false
+	return newDefaultParse(&config).(*defaultParser).parseStmts(sql)
+}
+
+// queryChecker resolves the tables and columns of a statement
+type queryChecker struct {
+	tables  map[string]*Table
+	node    ast.StmtNode
+	columns map[*ast.ColumnNameExpr]resultColumn // the resolved columns of parsed tables
+	params  map[ast.ParamMarkerExpr]queryParam
+	issues  []Issue
+}
+
+// queryParam is what a ? placeholder is compared with or written to
+type queryParam struct {
+	column   resultColumn
+	assigned bool         // written to the column, so it takes NULL when the column does
+	scanType reflect.Type // of placeholders not standing for a column, e.g. LIMIT ?
+}
+
+func newQueryChecker(tables map[string]*Table, node ast.StmtNode) *queryChecker {
+	return &queryChecker{
+		tables:  tables,
+		node:    node,
+		columns: make(map[*ast.ColumnNameExpr]resultColumn),
+		params:  make(map[ast.ParamMarkerExpr]queryParam),
+	}
+}
+
+func (c *queryChecker) issue(table, format string, args ...interface{}) {
+	c.issues = append(c.issues, Issue{Table: table, Statement: stmtSummary(c.node), Message: fmt.Sprintf(format, args...)})
+}
+
+// resultColumn is a column of a table or a derived table, column is nil
+// when it is no column of a parsed table, e.g. COUNT(*)
+type resultColumn struct {
+	name     string
+	table    string // the parsed table of column
+	column   gorm.ColumnType
+	nullable bool         // NULL even when column is NOT NULL, e.g. of the right table of a LEFT JOIN
+	expr     ast.ExprNode // the select field of derived columns
+}
+
+// querySource is a table of a FROM clause
+type querySource struct {
+	name     string // the alias, or the name of the table
+	table    *Table // nil for derived tables, CTEs and unknown tables
+	columns  []resultColumn
+	opaque   bool // columns are not known, e.g. of unknown tables or derived tables selecting t.* of those
+	nullable bool // of the outer side of a LEFT or RIGHT JOIN
+}
+
+func tableSource(name string, table *Table) *querySource {
+	s := &querySource{name: name, table: table}
+	for _, ct := range table.ColumnTypes {
+		s.columns = append(s.columns, resultColumn{name: ct.Name(), table: table.Name, column: ct})
+	}
+	return s
+}
+
+func (s *querySource) tableName() string {
+	if s.table != nil {
+		return s.table.Name
+	}
+	return s.name
+}
+
+func (s *querySource) column(name string) (resultColumn, bool) {
+	for _, column := range s.columns {
+		if strings.EqualFold(column.name, name) {
+			column.nullable = column.nullable || s.nullable
+			return column, true
+		}
+	}
+	return resultColumn{}, false
+}
+
+// queryScope holds the tables a query block sees, the blocks it is nested in
+// are its parents
+type queryScope struct {
+	parent  *queryScope
+	sources []*querySource
+	ctes    map[string]*querySource
+	using   map[string]bool         // columns of JOIN ... USING, which are not ambiguous
+	aliases map[string]resultColumn // the select fields by lower case alias once GROUP BY, HAVING and ORDER BY may use them
+}
+
+func (scope *queryScope) source(name string) *querySource {
+	for s := scope; s != nil; s = s.parent {
+		for _, source := range s.sources {
+			if strings.EqualFold(source.name, name) {
+				return source
+			}
+		}
+	}
+	return nil
+}
+
+func (scope *queryScope) cte(name string) *querySource {
+	for s := scope; s != nil; s = s.parent {
+		if cte, ok := s.ctes[strings.ToLower(name)]; ok {
+			return cte
+		}
+	}
+	return nil
+}
+
+// checkStmt checks node and returns its result columns, false when they
+// are not all known
+func (c *queryChecker) checkStmt(node ast.StmtNode) ([]resultColumn, bool) {
+	switch stmt := node.(type) {
+	case *ast.SelectStmt, *ast.SetOprStmt:
+		return c.checkQuery(stmt, nil)
+	case *ast.InsertStmt:
+		c.checkInsert(stmt)
+	case *ast.UpdateStmt:
+		scope := &queryScope{parent: c.withScope(stmt.With, nil)}
+		c.addTableRefs(scope, stmt.TableRefs)
+		for _, assignment := range stmt.List {
+			if column := c.resolve(scope, assignment.Column); column.column != nil {
+				c.checkAssignment(column, assignment.Expr)
+			}
+			c.checkExpr(scope, assignment.Expr)
+		}
+		c.checkExpr(scope, stmt.Where)
+		c.checkOrderBy(scope, stmt.Order)
+		c.checkLimit(stmt.Limit)
+	case *ast.DeleteStmt:
+		scope := &queryScope{parent: c.withScope(stmt.With, nil)}
+		c.addTableRefs(scope, stmt.TableRefs)
+		if stmt.Tables != nil {
+			for _, name := range stmt.Tables.Tables {
+				if scope.source(name.Name.O) == nil {
+					c.issue(name.Name.O, "unknown table %s in the tables to delete from", name.Name.O)
+				}
+			}
+		}
+		c.checkExpr(scope, stmt.Where)
+		c.checkOrderBy(scope, stmt.Order)
+		c.checkLimit(stmt.Limit)
+	}
+	return nil, true
+}
+
+// checkQuery checks a SELECT or UNION and returns its result columns, false
+// when they are not all known
+func (c *queryChecker) checkQuery(node ast.Node, parent *queryScope) ([]resultColumn, bool) {
+	switch node := node.(type) {
+	case *ast.SelectStmt:
+		return c.checkSelect(node, parent)
+	case *ast.SetOprStmt:
+		scope := c.withScope(node.With, parent)
+		columns, ok := c.checkSetOprList(node.SelectList, scope)
+		if node.OrderBy != nil {
+			result := &queryScope{parent: parent, sources: []*querySource{{columns: columns, opaque: !ok}}}
+			c.checkOrderBy(result, node.OrderBy)
+		}
+		c.checkLimit(node.Limit)
+		return columns, ok
+	case *ast.SetOprSelectList:
+		return c.checkSetOprList(node, parent)
+	}
+	return nil, false
+}
+
+// checkSetOprList checks the blocks of a UNION, whose result columns are
+// named by its first block
+func (c *queryChecker) checkSetOprList(list *ast.SetOprSelectList, parent *queryScope) (columns []resultColumn, ok bool) {
+	if list == nil {
+		return nil, false
+	}
+	scope := c.withScope(list.With, parent)
+	for i, sel := range list.Selects {
+		blockColumns, blockOK := c.checkQuery(sel, scope)
+		if i == 0 {
+			columns, ok = blockColumns, blockOK
+		}
+	}
+	return columns, ok
+}
+
+func (c *queryChecker) checkSelect(sel *ast.SelectStmt, parent *queryScope) ([]resultColumn, bool) {
+	outer := c.withScope(sel.With, parent)
+	scope := &queryScope{parent: outer}
+	if sel.From != nil {
+		c.addTableRefs(scope, sel.From)
+	}
+	c.checkExpr(scope, sel.Where)
+
+	var columns []resultColumn
+	ok := true
+	if sel.Fields != nil {
+		for _, field := range sel.Fields.Fields {
+			if field.WildCard != nil {
+				fieldColumns, fieldOK := c.wildCard(scope, field.WildCard)
+				columns, ok = append(columns, fieldColumns...), ok && fieldOK
+				continue
+			}
+			c.checkExpr(scope, field.Expr)
+			column := resultColumn{expr: field.Expr}
+			if expr, isColumn := field.Expr.(*ast.ColumnNameExpr); isColumn {
+				column = c.columns[expr]
+				column.name = expr.Name.Name.O
+			}
+			if field.AsName.O != "" {
+				column.name = field.AsName.O
+			} else if column.name == "" {
+				// MySQL names the other fields after their text
+				column.name = field.Text()
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	scope.aliases = make(map[string]resultColumn)
+	for _, column := range columns {
+		scope.aliases[strings.ToLower(column.name)] = column
+	}
+	if sel.GroupBy != nil {
+		for _, item := range sel.GroupBy.Items {
+			c.checkExpr(scope, item.Expr)
+		}
+	}
+	if sel.Having != nil {
+		c.checkExpr(scope, sel.Having.Expr)
+	}
+	c.checkOrderBy(scope, sel.OrderBy)
+	c.checkLimit(sel.Limit)
+	return columns, ok
+}
+
+// wildCard returns the columns * or t.* selects
+func (c *queryChecker) wildCard(scope *queryScope, wildCard *ast.WildCardField) (columns []resultColumn, ok bool) {
+	if wildCard.Table.O != "" {
+		source := scope.source(wildCard.Table.O)
+		if source == nil {
+			c.issue(wildCard.Table.O, "unknown table %s", wildCard.Table.O)
+			return nil, false
+		}
+		return source.columns, !source.opaque
+	}
+	ok = true
+	for _, source := range scope.sources {
+		columns, ok = append(columns, source.columns...), ok && !source.opaque
+	}
+	return columns, ok
+}
+
+// checkLimit records the placeholders of LIMIT ? OFFSET ?
+func (c *queryChecker) checkLimit(limit *ast.Limit) {
+	if limit == nil {
+		return
+	}
+	for _, expr := range []ast.ExprNode{limit.Count, limit.Offset} {
+		if param, ok := expr.(ast.ParamMarkerExpr); ok {
+			c.params[param] = queryParam{scanType: longT}
+		}
+	}
+}
+
+func (c *queryChecker) checkOrderBy(scope *queryScope, orderBy *ast.OrderByClause) {
+	if orderBy == nil {
+		return
+	}
+	for _, item := range orderBy.Items {
+		c.checkExpr(scope, item.Expr)
+	}
+}
+
+// withScope returns the scope of the CTEs of with, parent when there are none
+func (c *queryChecker) withScope(with *ast.WithClause, parent *queryScope) *queryScope {
+	if with == nil {
+		return parent
+	}
+	scope := &queryScope{parent: parent, ctes: make(map[string]*querySource)}
+	for _, cte := range with.CTEs {
+		source := &querySource{name: cte.Name.O, opaque: true}
+		if with.IsRecursive {
+			// the recursive part refers to the CTE itself
+			scope.ctes[cte.Name.L] = source
+		}
+		columns, ok := c.checkQuery(cte.Query.Query, scope)
+		if len(cte.ColNameList) > 0 {
+			renamed := make([]resultColumn, len(cte.ColNameList))
+			for i, name := range cte.ColNameList {
+				renamed[i].name = name.O
+				if i < len(columns) {
+					renamed[i].column = columns[i].column
+				}
+			}
+			columns = renamed
+		}
+		source.columns, source.opaque = columns, !ok && len(cte.ColNameList) == 0
+		scope.ctes[cte.Name.L] = source
+	}
+	return scope
+}
+
+// addTableRefs adds the tables of a FROM clause to scope
+func (c *queryChecker) addTableRefs(scope *queryScope, refs *ast.TableRefsClause) {
+	if refs != nil && refs.TableRefs != nil {
+		c.addJoin(scope, refs.TableRefs)
+	}
+}
+
+func (c *queryChecker) addJoin(scope *queryScope, node ast.ResultSetNode) {
+	switch node := node.(type) {
+	case *ast.Join:
+		before := len(scope.sources)
+		c.addJoin(scope, node.Left)
+		middle := len(scope.sources)
+		if node.Right != nil {
+			c.addJoin(scope, node.Right)
+		}
+		outer := scope.sources[middle:]
+		if node.Tp == ast.RightJoin {
+			outer = scope.sources[before:middle]
+		}
+		if node.Tp != ast.CrossJoin {
+			for _, source := range outer {
+				source.nullable = true
+			}
+		}
+		for _, name := range node.Using {
+			for _, sources := range [][]*querySource{scope.sources[before:middle], scope.sources[middle:]} {
+				if !hasColumn(sources, name.Name.O) {
+					c.issue("", "unknown column %s in USING", name.Name.O)
+				}
+			}
+			if scope.using == nil {
+				scope.using = make(map[string]bool)
+			}
+			scope.using[name.Name.L] = true
+		}
+		// NATURAL JOIN joins USING the columns both sides have
+		if node.NaturalJoin {
+			for _, source := range scope.sources[before:middle] {
+				for _, column := range source.columns {
+					if !hasColumn(scope.sources[middle:], column.name) {
+						continue
+					}
+					if scope.using == nil {
+						scope.using = make(map[string]bool)
+					}
+					scope.using[strings.ToLower(column.name)] = true
+				}
+			}
+		}
+		if node.On != nil {
+			c.checkExpr(scope, node.On.Expr)
+		}
+	case *ast.TableSource:
+		switch source := node.Source.(type) {
+		case *ast.TableName:
+			name := source.Name.O
+			if node.AsName.O != "" {
+				name = node.AsName.O
+			}
+			scope.sources = append(scope.sources, c.table(scope, source, name))
+		default:
+			// derived tables do not see the tables of their FROM clause
+			columns, ok := c.checkQuery(source, scope.parent)
+			scope.sources = append(scope.sources, &querySource{name: node.AsName.O, columns: columns, opaque: !ok})
+		}
+	}
+}
+
+func hasColumn(sources []*querySource, name string) bool {
+	for _, source := range sources {
+		if _, ok := source.column(name); ok || source.opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// table returns the source of a table named in a FROM clause: a CTE, a parsed
+// table or, reported as unknown, an opaque source so its columns are not
+// reported too
+func (c *queryChecker) table(scope *queryScope, name *ast.TableName, as string) *querySource {
+	if name.Schema.O == "" {
+		if cte := scope.cte(name.Name.O); cte != nil {
+			return &querySource{name: as, columns: cte.columns, opaque: cte.opaque}
+		}
+	}
+	if table := c.lookupTable(name.Name.O); table != nil {
+		return tableSource(as, table)
+	}
+	c.issue(name.Name.O, "unknown table %s", name.Name.O)
+	return &querySource{name: as, opaque: true}
+}
+
+func (c *queryChecker) lookupTable(name string) *Table {
+	if table, ok := c.tables[name]; ok {
+		return table
+	}
+	for tableName, table := range c.tables {
+		if strings.EqualFold(tableName, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+// resolve reports name when it is no column of the tables of scope, and
+// returns the column it is, whose column is nil unless it is one of a
+// parsed table
+func (c *queryChecker) resolve(scope *queryScope, name *ast.ColumnName) resultColumn {
+	if name.Table.O != "" {
+		source := scope.source(name.Table.O)
+		if source == nil {
+			c.issue(name.Table.O, "unknown table %s of column %s.%s", name.Table.O, name.Table.O, name.Name.O)
+			return resultColumn{}
+		}
+		column, ok := source.column(name.Name.O)
+		if !ok && !source.opaque {
+			c.issue(source.tableName(), "unknown column %s.%s", name.Table.O, name.Name.O)
+		}
+		return column
+	}
+
+	for s := scope; s != nil; s = s.parent {
+		var found []*querySource
+		var column resultColumn
+		opaque := false
+		for _, source := range s.sources {
+			if col, ok := source.column(name.Name.O); ok {
+				found, column = append(found, source), col
+			}
+			opaque = opaque || source.opaque
+		}
+		switch {
+		case len(found) > 1 && !s.using[name.Name.L]:
+			c.issue(found[0].tableName(), "ambiguous column %s, of %s and %s", name.Name.O, found[0].name, found[1].name)
+			return resultColumn{}
+		case len(found) > 0:
+			return column
+		}
+		if alias, ok := s.aliases[name.Name.L]; ok {
+			return c.aliasColumn(alias)
+		}
+		if opaque {
+			return resultColumn{}
+		}
+	}
+	c.issue("", "unknown column %s", name.Name.O)
+	return resultColumn{}
+}
+
+// aliasColumn returns the column a select field named by its alias compares
+// as: the column of MIN and MAX, the field itself otherwise
+func (c *queryChecker) aliasColumn(alias resultColumn) resultColumn {
+	if aggregate, ok := alias.expr.(*ast.AggregateFuncExpr); ok {
+		switch strings.ToLower(aggregate.F) {
+		case ast.AggFuncMin, ast.AggFuncMax:
+			if arg, ok := aggregate.Args[0].(*ast.ColumnNameExpr); ok {
+				return c.columns[arg]
+			}
+		}
+	}
+	return alias
+}
+
+// checkExpr resolves the columns of expr and checks the literals compared
+// with them
+func (c *queryChecker) checkExpr(scope *queryScope, expr ast.ExprNode) {
+	if expr != nil {
+		expr.Accept(&exprChecker{c: c, scope: scope})
+	}
+}
+
+type exprChecker struct {
+	c     *queryChecker
+	scope *queryScope
+}
+
+func (v *exprChecker) Enter(n ast.Node) (ast.Node, bool) {
+	switch n := n.(type) {
+	case *ast.SubqueryExpr:
+		v.c.checkQuery(n.Query, v.scope)
+		return n, true
+	case *ast.ColumnNameExpr:
+		if column := v.c.resolve(v.scope, n.Name); column.column != nil || column.expr != nil {
+			v.c.columns[n] = column
+		}
+		return n, true
+	}
+	return n, false
+}
+
+// Leave checks the comparisons once their columns are resolved
+func (v *exprChecker) Leave(n ast.Node) (ast.Node, bool) {
+	switch n := n.(type) {
+	case *ast.BinaryOperationExpr:
+		switch n.Op {
+		case opcode.EQ, opcode.NE, opcode.LT, opcode.LE, opcode.GT, opcode.GE, opcode.NullEQ:
+			v.c.checkRows(n.Op, n.L, n.R)
+		}
+	case *ast.PatternInExpr:
+		for _, value := range n.List {
+			v.c.checkRows(opcode.In, n.Expr, value)
+		}
+	case *ast.BetweenExpr:
+		v.c.checkComparison(opcode.GE, n.Expr, n.Left)
+		v.c.checkComparison(opcode.LE, n.Expr, n.Right)
+	case *ast.PatternLikeOrIlikeExpr:
+		if param, ok := n.Pattern.(ast.ParamMarkerExpr); ok {
+			v.c.params[param] = queryParam{scanType: stringT}
+		}
+	}
+	return n, true
+}
+
+// checkRows checks the comparison of l and r both ways, pairing the
+// elements of row constructors, e.g. (a, b) = (?, ?)
+func (c *queryChecker) checkRows(op opcode.Op, l, r ast.ExprNode) {
+	lRow, lOK := l.(*ast.RowExpr)
+	rRow, rOK := r.(*ast.RowExpr)
+	if lOK && rOK && len(lRow.Values) == len(rRow.Values) {
+		for i := range lRow.Values {
+			c.checkRows(op, lRow.Values[i], rRow.Values[i])
+		}
+		return
+	}
+	c.checkComparison(op, l, r)
+	c.checkComparison(op, r, l)
+}
+
+// checkComparison checks the literal value compared with the column expr
+func (c *queryChecker) checkComparison(op opcode.Op, expr, value ast.ExprNode) {
+	name, ok := expr.(*ast.ColumnNameExpr)
+	if !ok {
+		return
+	}
+	column := c.columns[name]
+	if column.column == nil {
+		// a COUNT named by its alias
+		if aggregate, ok := column.expr.(*ast.AggregateFuncExpr); ok && strings.ToLower(aggregate.F) == ast.AggFuncCount {
+			if param, ok := value.(ast.ParamMarkerExpr); ok {
+				c.params[param] = queryParam{scanType: longT}
+			}
+		}
+		return
+	}
+	if param, ok := value.(ast.ParamMarkerExpr); ok {
+		c.params[param] = queryParam{column: column}
+		return
+	}
+	v, ok := value.(ast.ValueExpr)
+	if !ok {
+		return
+	}
+	ct := column.column
+	switch literal := v.GetValue(); literal.(type) {
+	case nil:
+		if op == opcode.EQ || op == opcode.NE {
+			c.issue("", "comparison of column %s with NULL is never true, use IS NULL", ct.Name())
+		}
+	case string:
+		c.checkLiteral(ct, literal.(string))
+	default:
+		if dataType := ct.DatabaseTypeName(); charTypes[dataType] && dataType != "enum" && dataType != "set" {
+			c.issue("", "column %s is %s, comparing it with the number %v converts its values and skips its indexes", ct.Name(), ct.DatabaseTypeName(), literal)
+		}
+	}
+}
+
+// checkAssignment checks the literal value written to column
+func (c *queryChecker) checkAssignment(column resultColumn, value ast.ExprNode) {
+	if param, ok := value.(ast.ParamMarkerExpr); ok {
+		c.params[param] = queryParam{column: column, assigned: true}
+		return
+	}
+	v, ok := value.(ast.ValueExpr)
+	if !ok {
+		return
+	}
+	ct := column.column
+	switch literal := v.GetValue(); literal.(type) {
+	case nil:
+		nullable, _ := ct.Nullable()
+		autoIncrement, _ := ct.AutoIncrement()
+		if !nullable && !autoIncrement {
+			c.issue("", "NULL for NOT NULL column %s", ct.Name())
+		}
+	case string:
+		c.checkLiteral(ct, literal.(string))
+	}
+}
+
+var (
+	dateLiteral = regexp.MustCompile(`^\d{2,4}-\d{1,2}-\d{1,2}([ T]\d{1,2}:\d{1,2}(:\d{1,2}(\.\d{1,6})?)?)?$`)
+	timeLiteral = regexp.MustCompile(`^-?(\d+ )?\d+(:\d{1,2}(:\d{1,2}(\.\d{1,6})?)?)?$`)
+	digits      = regexp.MustCompile(`^\d+(\.\d{1,6})?$`)
+)
+
+// checkLiteral checks the string literal s is a value of the column ct
+func (c *queryChecker) checkLiteral(ct gorm.ColumnType, s string) {
+	dataType := ct.DatabaseTypeName()
+	switch dataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "decimal", "numeric", "float", "double", "real":
+		if _, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+			c.issue("", "column %s is %s, %q is no number", ct.Name(), dataType, s)
+		}
+	case "date", "datetime", "timestamp":
+		if !dateLiteral.MatchString(s) && !digits.MatchString(s) {
+			c.issue("", "column %s is %s, %q is no date", ct.Name(), dataType, s)
+		}
+	case "time":
+		if !timeLiteral.MatchString(s) {
+			c.issue("", "column %s is time, %q is no time", ct.Name(), s)
+		}
+	case "year":
+		if !digits.MatchString(s) || len(s) > 4 {
+			c.issue("", "column %s is year, %q is no year", ct.Name(), s)
+		}
+	case "enum":
+		if column, ok := ct.(*ColumnType); ok {
+			values, _ := column.EnumValues()
+			if nameIndex(values, s) < 0 {
+				c.issue("", "%q is no value of the ENUM column %s", s, ct.Name())
+			}
+		}
+	}
+}
+
+func (c *queryChecker) checkInsert(stmt *ast.InsertStmt) {
+	scope := &queryScope{}
+	c.addTableRefs(scope, stmt.Table)
+	if len(scope.sources) == 0 || scope.sources[0].table == nil {
+		return
+	}
+	table := scope.sources[0].table
+
+	var columns []resultColumn
+	if len(stmt.Columns) > 0 {
+		for _, name := range stmt.Columns {
+			columns = append(columns, c.resolve(scope, name))
+		}
+		for _, ct := range table.ColumnTypes {
+			if requiresInsertValue(ct) && !insertsColumn(stmt.Columns, ct.Name()) {
+				c.issue(table.Name, "NOT NULL column %s without a default is left out", ct.Name())
+			}
+		}
+	} else {
+		for _, column := range scope.sources[0].columns {
+			if ct, ok := column.column.(*ColumnType); !ok || !ct.InvisibleValue {
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	for _, row := range stmt.Lists {
+		if len(row) != len(columns) {
+			c.issue(table.Name, "%d values for %d columns", len(row), len(columns))
+			continue
+		}
+		for i, value := range row {
+			if columns[i].column != nil {
+				c.checkAssignment(columns[i], value)
+			}
+			c.checkExpr(scope, value)
+		}
+	}
+	if stmt.Select != nil {
+		if selected, ok := c.checkQuery(stmt.Select, nil); ok && len(selected) != len(columns) {
+			c.issue(table.Name, "%d selected columns for %d columns", len(selected), len(columns))
+		}
+	}
+	for _, assignment := range stmt.OnDuplicate {
+		if column := c.resolve(scope, assignment.Column); column.column != nil {
+			c.checkAssignment(column, assignment.Expr)
+		}
+		c.checkExpr(scope, assignment.Expr)
+	}
+}
+
+// requiresInsertValue reports whether an INSERT in strict mode needs a value
+// for ct: it is NOT NULL and neither has a default nor is filled in by MySQL
+func requiresInsertValue(ct gorm.ColumnType) bool {
+	nullable, _ := ct.Nullable()
+	_, hasDefault := ct.DefaultValue()
+	autoIncrement, _ := ct.AutoIncrement()
+	if nullable || hasDefault || autoIncrement {
+		return false
+	}
+	if column, ok := ct.(*ColumnType); ok && column.GenerationExprValue != "" {
+		return false
+	}
+	return true
+}
+
+func insertsColumn(columns []*ast.ColumnName, name string) bool {
+	for _, column := range columns {
+		if strings.EqualFold(column.Name.O, name) {
+			return true
+		}
+	}
+	return false
+}
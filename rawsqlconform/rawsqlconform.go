@@ -0,0 +1,195 @@
+// Package rawsqlconform checks that rawsql reads DDL the way gorm's MySQL
+// migrator reads the tables a live server makes of it, users run it over
+// their own schemas against a scratch database:
+//
+//	func TestSchemaConformance(t *testing.T) {
+//		live, _ := gorm.Open(mysql.Open(os.Getenv("SCRATCH_DSN")))
+//		rawsqlconform.Run(t, live, schemaSQL)
+//	}
+package rawsqlconform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/rawsql"
+)
+
+// Mismatch is a value rawsql parsed differently than the migrator of the
+// live database reports it
+type Mismatch struct {
+	Table  string
+	Object string // column or index name, e.g. column email or index PRIMARY
+	Field  string // e.g. ColumnType, Nullable, or exists for a column or index missing on one side
+	RawSQL string
+	Live   string
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s %s %s: rawsql %s, live %s", m.Table, m.Object, m.Field, m.RawSQL, m.Live)
+}
+
+// Run executes ddl in live, compares the tables with those rawsql parses from
+// ddl and fails t with every mismatch
+func Run(t testing.TB, live *gorm.DB, ddl ...string) {
+	t.Helper()
+	mismatches, err := Compare(live, ddl...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mismatches {
+		t.Error(m)
+	}
+}
+
+// Compare executes the statements of ddl in live, opened on an empty scratch
+// database with gorm.io/driver/mysql, and returns where the ColumnTypes and
+// Indexes of the parsed tables differ from those live.Migrator() reports, by
+// table name
+func Compare(live *gorm.DB, ddl ...string) ([]Mismatch, error) {
+	dialector := rawsql.New(rawsql.Config{SQL: append([]string(nil), ddl...)}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		return nil, err
+	}
+	for _, content := range ddl {
+		for _, stmt := range rawsql.SplitStatements(content) {
+			if err := live.Exec(stmt).Error; err != nil {
+				return nil, fmt.Errorf("rawsqlconform: live database rejected %s: %w", stmt, err)
+			}
+		}
+	}
+
+	tables := dialector.Parser.GetTables()
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var mismatches []Mismatch
+	for _, name := range names {
+		columns, err := live.Migrator().ColumnTypes(name)
+		if err != nil {
+			return nil, fmt.Errorf("rawsqlconform: columns of %s: %w", name, err)
+		}
+		indexes, err := live.Migrator().GetIndexes(name)
+		if err != nil {
+			return nil, fmt.Errorf("rawsqlconform: indexes of %s: %w", name, err)
+		}
+		mismatches = append(mismatches, compareColumns(name, tables[name].ColumnTypes, columns)...)
+		mismatches = append(mismatches, compareIndexes(name, tables[name].Indexes, indexes)...)
+	}
+	return mismatches, nil
+}
+
+func compareColumns(table string, parsed, live []gorm.ColumnType) (mismatches []Mismatch) {
+	byName := make(map[string]gorm.ColumnType, len(live))
+	for _, ct := range live {
+		byName[strings.ToLower(ct.Name())] = ct
+	}
+	for _, ct := range parsed {
+		object := "column " + ct.Name()
+		other, ok := byName[strings.ToLower(ct.Name())]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Table: table, Object: object, Field: "exists", RawSQL: "true", Live: "false"})
+			continue
+		}
+		delete(byName, strings.ToLower(ct.Name()))
+		want, got := columnFields(ct), columnFields(other)
+		for _, field := range columnFieldNames {
+			if want[field] != got[field] {
+				mismatches = append(mismatches, Mismatch{Table: table, Object: object, Field: field, RawSQL: want[field], Live: got[field]})
+			}
+		}
+	}
+	for _, ct := range live {
+		if _, ok := byName[strings.ToLower(ct.Name())]; ok {
+			mismatches = append(mismatches, Mismatch{Table: table, Object: "column " + ct.Name(), Field: "exists", RawSQL: "false", Live: "true"})
+		}
+	}
+	return mismatches
+}
+
+// columnFieldNames are the gorm.ColumnType methods compared, in order
+var columnFieldNames = []string{"DatabaseTypeName", "ColumnType", "PrimaryKey", "AutoIncrement", "Length", "DecimalSize", "Nullable", "Unique", "DefaultValue", "Comment"}
+
+// columnFields renders the values of ct by method name, booleans and comments
+// left unset count as false and empty as migrators leave them unset too
+func columnFields(ct gorm.ColumnType) map[string]string {
+	value := func(v interface{}, ok bool) string {
+		if !ok {
+			return "(unset)"
+		}
+		return fmt.Sprint(v)
+	}
+	columnType, columnTypeOK := ct.ColumnType()
+	pk, _ := ct.PrimaryKey()
+	autoIncrement, _ := ct.AutoIncrement()
+	length, lengthOK := ct.Length()
+	precision, scale, decimalOK := ct.DecimalSize()
+	nullable, nullableOK := ct.Nullable()
+	unique, _ := ct.Unique()
+	defaultValue, defaultOK := ct.DefaultValue()
+	comment, _ := ct.Comment()
+	return map[string]string{
+		"DatabaseTypeName": strings.ToLower(ct.DatabaseTypeName()),
+		"ColumnType":       value(columnType, columnTypeOK),
+		"PrimaryKey":       fmt.Sprint(pk),
+		"AutoIncrement":    fmt.Sprint(autoIncrement),
+		"Length":           value(length, lengthOK),
+		"DecimalSize":      value(fmt.Sprintf("%d,%d", precision, scale), decimalOK),
+		"Nullable":         value(nullable, nullableOK),
+		"Unique":           fmt.Sprint(unique),
+		"DefaultValue":     value(defaultValue, defaultOK),
+		"Comment":          comment,
+	}
+}
+
+func compareIndexes(table string, parsed, live []gorm.Index) (mismatches []Mismatch) {
+	byName := make(map[string]gorm.Index, len(live))
+	for _, idx := range live {
+		byName[indexName(idx)] = idx
+	}
+	for _, idx := range parsed {
+		name := indexName(idx)
+		object := "index " + name
+		other, ok := byName[name]
+		if !ok {
+			mismatches = append(mismatches, Mismatch{Table: table, Object: object, Field: "exists", RawSQL: "true", Live: "false"})
+			continue
+		}
+		delete(byName, name)
+		want, got := indexFields(idx), indexFields(other)
+		for _, field := range []string{"Columns", "PrimaryKey", "Unique"} {
+			if want[field] != got[field] {
+				mismatches = append(mismatches, Mismatch{Table: table, Object: object, Field: field, RawSQL: want[field], Live: got[field]})
+			}
+		}
+	}
+	for _, idx := range live {
+		if _, ok := byName[indexName(idx)]; ok {
+			mismatches = append(mismatches, Mismatch{Table: table, Object: "index " + indexName(idx), Field: "exists", RawSQL: "false", Live: "true"})
+		}
+	}
+	return mismatches
+}
+
+// indexName is the name MySQL gives idx, the primary key is PRIMARY
+func indexName(idx gorm.Index) string {
+	if pk, _ := idx.PrimaryKey(); pk {
+		return "PRIMARY"
+	}
+	return strings.ToLower(idx.Name())
+}
+
+func indexFields(idx gorm.Index) map[string]string {
+	pk, _ := idx.PrimaryKey()
+	unique, _ := idx.Unique()
+	return map[string]string{
+		"Columns":    strings.Join(idx.Columns(), ", "),
+		"PrimaryKey": fmt.Sprint(pk),
+		"Unique":     fmt.Sprint(unique || pk),
+	}
+}
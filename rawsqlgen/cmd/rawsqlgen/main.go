@@ -0,0 +1,36 @@
+// Command rawsqlgen writes the tables of sql files and directories into a Go
+// source file, see package gorm.io/rawsql/rawsqlgen:
+//
+//	rawsqlgen -pkg schema -o schema_gen.go migrations/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/rawsql"
+	"gorm.io/rawsql/rawsqlgen"
+)
+
+func main() {
+	var opts rawsqlgen.Options
+	output := flag.String("o", "schema_gen.go", "file to write")
+	flag.StringVar(&opts.Package, "pkg", os.Getenv("GOPACKAGE"), "package of the generated file, defaults to the package running go:generate")
+	flag.StringVar(&opts.Func, "func", "Tables", "name of the generated function returning the tables")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: rawsqlgen [flags] file.sql|dir ...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.Source = strings.Join(flag.Args(), " ")
+	if err := rawsqlgen.GenerateFile(*output, rawsql.Config{FilePath: flag.Args()}, opts); err != nil {
+		fmt.Fprintln(os.Stderr, "rawsqlgen:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,103 @@
+// Package rawsqlgen writes the tables rawsql parses into a Go source file, so
+// services include their schema without shipping its sql or the parser: the
+// generated package only imports gorm.io/rawsql/meta.
+//
+//	//go:generate go run gorm.io/rawsql/rawsqlgen/cmd/rawsqlgen -pkg schema -o schema_gen.go ../migrations
+//
+//	tables, err := schema.Tables()
+package rawsqlgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/rawsql"
+)
+
+// Options configure Generate
+type Options struct {
+	Package string // package of the generated file, required
+	Func    string // name of the generated function returning the tables, defaults to Tables
+	Source  string // where the tables were parsed from, e.g. migrations/*.sql, mentioned in the header
+}
+
+// Generate writes the Go source file declaring opts.Func, which returns a
+// fresh copy of tables on every call, and Must followed by opts.Func, which
+// panics instead of failing. The tables are embedded as a JSON snapshot, a
+// line per line of the indented snapshot so regenerating after a schema
+// change gives a readable diff.
+func Generate(w io.Writer, tables map[string]*rawsql.Table, opts Options) error {
+	if !token.IsIdentifier(opts.Package) {
+		return fmt.Errorf("rawsqlgen: invalid package name %q", opts.Package)
+	}
+	if opts.Func == "" {
+		opts.Func = "Tables"
+	}
+	if !token.IsIdentifier(opts.Func) {
+		return fmt.Errorf("rawsqlgen: invalid function name %q", opts.Func)
+	}
+	var snapshot, indented bytes.Buffer
+	if err := rawsql.EncodeSnapshot(&snapshot, tables); err != nil {
+		return err
+	}
+	if err := json.Indent(&indented, bytes.TrimSpace(snapshot.Bytes()), "", "\t"); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by rawsqlgen. DO NOT EDIT.\n")
+	if opts.Source != "" {
+		fmt.Fprintf(&buf, "// Source: %s\n", opts.Source)
+	}
+	fmt.Fprintf(&buf, "\npackage %s\n\n", opts.Package)
+	buf.WriteString("import (\n\t\"strings\"\n\n\t\"gorm.io/rawsql/meta\"\n)\n\n")
+	fmt.Fprintf(&buf, "// %s decodes the %d tables of the schema, by name\n", opts.Func, len(tables))
+	fmt.Fprintf(&buf, "func %s() (map[string]*meta.Table, error) {\n\treturn meta.DecodeSnapshot(strings.NewReader(snapshot))\n}\n\n", opts.Func)
+	fmt.Fprintf(&buf, "// Must%[1]s is like %[1]s but panics when the snapshot cannot be decoded\n", opts.Func)
+	fmt.Fprintf(&buf, "func Must%[1]s() map[string]*meta.Table {\n\ttables, err := %[1]s()\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\treturn tables\n}\n\n", opts.Func)
+	fmt.Fprintf(&buf, "// snapshot is the meta.EncodeSnapshot of the tables, version %d\nconst snapshot = \"\" +\n", rawsql.SnapshotVersion)
+	lines := strings.Split(indented.String(), "\n")
+	for i, line := range lines {
+		if i < len(lines)-1 {
+			line += "\n"
+		}
+		fmt.Fprintf(&buf, "\t%s", strconv.Quote(line))
+		if i < len(lines)-1 {
+			buf.WriteString(" +")
+		}
+		buf.WriteString("\n")
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("rawsqlgen: format the generated source: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// GenerateFile parses the sql config reads and writes the Go source file of
+// its tables to path, leaving the file as it is when nothing changed so
+// builds do not see it modified
+func GenerateFile(path string, config rawsql.Config, opts Options) error {
+	db, err := gorm.Open(rawsql.New(config), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = Generate(&buf, db.Dialector.(*rawsql.Dialector).Parser.GetTables(), opts); err != nil {
+		return err
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return nil
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
@@ -0,0 +1,17 @@
+module gorm.io/rawsql/rawsqlotel
+
+go 1.20
+
+require (
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+	gorm.io/rawsql v0.0.0
+)
+
+// rawsqlotel is its own module so rawsql does not require OpenTelemetry. It
+// is tagged rawsqlotel/vX.Y.Z after rawsql and the requirement above raised
+// to that tag, see Releasing in README.md.
+replace (
+	gorm.io/rawsql => ../
+	gorm.io/rawsql/meta => ../meta
+)
@@ -0,0 +1,68 @@
+// Package rawsqlotel reports the spans of rawsql to OpenTelemetry:
+//
+//	db, err := gorm.Open(rawsql.New(rawsql.Config{
+//		FilePath: "schema.sql",
+//		Tracer:   rawsqlotel.NewTracer(otel.GetTracerProvider()),
+//	}))
+//
+// It is a module of its own so rawsql does not require OpenTelemetry.
+package rawsqlotel
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/rawsql"
+)
+
+// InstrumentationName names the tracer NewTracer gets of its provider
+const InstrumentationName = "gorm.io/rawsql"
+
+// NewTracer returns the rawsql.Tracer starting its spans with the tracer of
+// tp named InstrumentationName
+func NewTracer(tp trace.TracerProvider) rawsql.Tracer {
+	return tracer{tp.Tracer(InstrumentationName)}
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string) (context.Context, rawsql.Span) {
+	ctx, s := t.tracer.Start(ctx, name)
+	return ctx, span{s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+// SetAttribute keeps the strings, integers and booleans rawsql sets as
+// such, any other value is formatted
+func (s span) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.span.SetAttributes(attribute.String(key, v))
+	case int:
+		s.span.SetAttributes(attribute.Int(key, v))
+	case int64:
+		s.span.SetAttributes(attribute.Int64(key, v))
+	case bool:
+		s.span.SetAttributes(attribute.Bool(key, v))
+	default:
+		s.span.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+// RecordError records err and marks the span failed
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
@@ -0,0 +1,81 @@
+package rawsql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// failedStmt stands for a statement Config.RecoverSyntaxErrors skipped as it
+// does not parse, applyStmts reports it as a warning
+type failedStmt struct {
+	ast.StmtNode
+	line int // of the start of the statement in its sql content
+	err  error
+}
+
+// parseEach parses the statements of sql one by one, resynchronizing at the
+// semicolon following a statement that does not parse, which becomes a
+// failedStmt
+func (d *defaultParser) parseEach(p *parser.Parser, sql string) []ast.StmtNode {
+	var stmtNodes []ast.StmtNode
+	for _, span := range statementSpans(sql) {
+		text := sql[span.start:span.end]
+		nodes, _, err := p.Parse(text, d.config.ParseCharset, d.config.ParseCollation)
+		if err != nil {
+			failed := &failedStmt{StmtNode: &ast.DoStmt{}, line: strings.Count(sql[:span.start], "\n") + 1, err: err}
+			failed.SetText(nil, text)
+			stmtNodes = append(stmtNodes, failed)
+			continue
+		}
+		stmtNodes = append(stmtNodes, nodes...)
+	}
+	return stmtNodes
+}
+
+// stmtSpan is the byte range of a statement, from its first token to its
+// semicolon
+type stmtSpan struct {
+	start, end int
+}
+
+// statementSpans splits sql at the semicolons outside of strings, quoted
+// identifiers and comments, skipping empty statements
+func statementSpans(sql string) (spans []stmtSpan) {
+	first := -1
+	tokens := sqlTokens(sql)
+	for i, tok := range tokens {
+		if first < 0 {
+			first = i
+		}
+		semicolon := tok.kind == 'p' && tok.text == ";"
+		if !semicolon && i < len(tokens)-1 {
+			continue
+		}
+		if !semicolon || first < i {
+			spans = append(spans, stmtSpan{start: tokens[first].start, end: tok.end})
+		}
+		first = -1
+	}
+	return spans
+}
+
+// SplitStatements splits sql into its statements at the semicolons outside of
+// strings, quoted identifiers and comments, each keeps its semicolon, e.g. to
+// execute sql a statement at a time
+func SplitStatements(sql string) []string {
+	spans := statementSpans(sql)
+	stmts := make([]string, len(spans))
+	for i, span := range spans {
+		stmts[i] = sql[span.start:span.end]
+	}
+	return stmts
+}
+
+// applyFailed reports a statement Config.RecoverSyntaxErrors skipped
+func (d *defaultParser) applyFailed(failed *failedStmt) {
+	d.stats.Skipped++
+	d.warn(failed, fmt.Sprintf("statement at line %d does not parse: %v", failed.line, failed.err))
+}
@@ -0,0 +1,95 @@
+package rawsql
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// Registry shares parsed schemas between the components of a process by
+// name, e.g. service/database, so each schema is parsed once. Every schema
+// keeps its own Parser and tables, registering the same name twice fails.
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[string]*gorm.DB
+}
+
+// DefaultRegistry is the process wide Registry
+var DefaultRegistry = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{schemas: map[string]*gorm.DB{}}
+}
+
+// Register parses the schema of config and makes it available by name, the
+// returned db is the one Lookup returns
+func (r *Registry) Register(name string, config Config, opts ...gorm.Option) (*gorm.DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.schemas[name]; dup {
+		return nil, fmt.Errorf("rawsql: schema %q is already registered", name)
+	}
+	db, err := gorm.Open(New(config), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("rawsql: register schema %q: %w", name, err)
+	}
+	r.schemas[name] = db
+	return db, nil
+}
+
+// Lookup returns the db of the schema registered by name, its dialector is a
+// *Dialector holding the parsed tables
+func (r *Registry) Lookup(name string) (db *gorm.DB, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	db, ok = r.schemas[name]
+	return db, ok
+}
+
+// Names returns the names of the registered schemas, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.schemas))
+	for name := range r.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Unregister removes the schema registered by name and stops its watcher, see
+// Config.Watch, the dbs already looked up keep their tables
+func (r *Registry) Unregister(name string) error {
+	r.mu.Lock()
+	db, ok := r.schemas[name]
+	delete(r.schemas, name)
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("rawsql: schema %q is not registered", name)
+	}
+	return closeSchema(db)
+}
+
+// Close unregisters every schema, returning the first error stopping them
+func (r *Registry) Close() (err error) {
+	r.mu.Lock()
+	schemas := r.schemas
+	r.schemas = map[string]*gorm.DB{}
+	r.mu.Unlock()
+	for _, db := range schemas {
+		if e := closeSchema(db); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func closeSchema(db *gorm.DB) error {
+	if dialector, ok := db.Dialector.(*Dialector); ok {
+		return dialector.Close()
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package rawsql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RemoteSource is a sql file fetched over HTTP(S) when the dialector is initialized
+type RemoteSource struct {
+	URL    string
+	SHA256 string // optional hex encoded checksum the fetched content must match
+}
+
+const defaultRemoteTimeout = 30 * time.Second
+
+func (dialector Dialector) remoteTOSQL() error {
+	if len(dialector.Remote) == 0 {
+		return nil
+	}
+	client := dialector.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	timeout := dialector.RemoteTimeout
+	if timeout <= 0 {
+		timeout = defaultRemoteTimeout
+	}
+	for _, src := range dialector.Remote {
+		if src.URL == "" {
+			continue
+		}
+		content, err := fetch(dialector.context(), client, src.URL, timeout)
+		if err != nil {
+			return err
+		}
+		if src.SHA256 != "" {
+			sum := sha256.Sum256(content)
+			if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, src.SHA256) {
+				return fmt.Errorf("checksum mismatch for %s: got %s, want %s", src.URL, got, src.SHA256)
+			}
+		}
+		name := src.URL
+		if u, err := url.Parse(src.URL); err == nil {
+			name = u.Path
+		}
+		if err = dialector.appendSQL(name, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fetch(ctx context.Context, client *http.Client, rawURL string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: unexpected status %s", rawURL, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
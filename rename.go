@@ -0,0 +1,128 @@
+package rawsql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+)
+
+// renameColumn records the rename of a column from old to name and renames it
+// in the indexes and foreign keys of the table and of the tables referencing it
+func (d *defaultParser) renameColumn(table *Table, old, name string) {
+	if old == name {
+		return
+	}
+	table.ColumnRenames = append(table.ColumnRenames, Rename{From: d.intern(old), To: d.intern(name)})
+	for _, idx := range table.Indexes {
+		if _, columns := indexFields(idx); columns != nil {
+			renameIn(columns, old, name)
+		}
+	}
+	for _, fk := range table.ForeignKeys {
+		renameIn(fk.Columns, old, name)
+	}
+	for _, other := range d.tables {
+		for _, fk := range other.ForeignKeys {
+			if fk.ReferencedTable == table.Name {
+				renameIn(fk.ReferencedColumns, old, name)
+			}
+		}
+	}
+}
+
+func renameIn(names []string, old, name string) {
+	for i, n := range names {
+		if strings.EqualFold(n, old) {
+			names[i] = name
+		}
+	}
+}
+
+// alterRenameColumn applies RENAME COLUMN old TO new, the column keeps its
+// definition and place
+func (d *defaultParser) alterRenameColumn(node ast.StmtNode, table *Table, spec *ast.AlterTableSpec) {
+	old, name := spec.OldColumnName.Name.O, d.intern(spec.NewColumnName.Name.O)
+	i := columnIndex(table.ColumnTypes, old)
+	if i < 0 {
+		panic(fmt.Sprintf("column %s of table %s not exists", old, table.Name))
+	}
+	if j := columnIndex(table.ColumnTypes, name); j >= 0 && j != i {
+		panic(fmt.Sprintf("duplicated column %s of table %s", name, table.Name))
+	}
+	ct, ok := table.ColumnTypes[i].(*ColumnType)
+	if !ok {
+		d.warn(node, fmt.Sprintf("unsupported alter table clause %s", alterSpecText(spec)))
+		return
+	}
+	old = ct.Name()
+	ct.NameValue.String = name
+	d.renameColumn(table, old, name)
+}
+
+// renameTable moves table old to name, as RENAME TABLE and ALTER TABLE ...
+// RENAME TO do, updating the foreign keys referencing it. It reports whether
+// a tracked table was renamed, skipped temporary and ghost tables are not.
+func (d *defaultParser) renameTable(old, name string) bool {
+	if _, ok := d.temporary[old]; ok {
+		delete(d.temporary, old)
+		d.temporary[name] = struct{}{}
+		return false
+	}
+	if d.isGhostTable(old) || d.isGhostTable(name) {
+		return false
+	}
+	table, has := d.tables[old]
+	if !has {
+		panic(fmt.Sprintf("table %s not exists", old))
+	}
+	if _, has = d.tables[name]; has && !strings.EqualFold(old, name) {
+		panic(fmt.Sprintf("duplicated table %s", name))
+	}
+	delete(d.tables, old)
+	d.tables[name] = table
+	table.Name = name
+	table.PreviousNames = append(table.PreviousNames, old)
+	for _, idx := range table.Indexes {
+		if tableName, _ := indexFields(idx); tableName != nil {
+			*tableName = name
+		}
+	}
+	for _, other := range d.tables {
+		for i := range other.ForeignKeys {
+			if other.ForeignKeys[i].ReferencedTable == old {
+				other.ForeignKeys[i].ReferencedTable = name
+			}
+		}
+	}
+	return true
+}
+
+// applyRenameTable applies every old TO new pair of a RENAME TABLE in order,
+// a pair may rename a table renamed by a previous one
+func (d *defaultParser) applyRenameTable(rename *ast.RenameTableStmt) {
+	for _, pair := range rename.TableToTables {
+		old, name := pair.OldTable.Name.String(), d.intern(pair.NewTable.Name.String())
+		if d.renameTable(old, name) {
+			d.tables[name].RawSQL += fmt.Sprintf("\nRENAME TABLE %s TO %s;", quoteName(old), quoteName(name))
+		}
+	}
+}
+
+func quoteName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// indexFields returns the table name and columns of the indexes created by
+// rawsql, nil for indexes of other types
+func indexFields(idx gorm.Index) (table *string, columns []string) {
+	switch idx := idx.(type) {
+	case *Index:
+		return &idx.TableName, idx.ColumnList
+	case *migrator.Index:
+		return &idx.TableName, idx.ColumnList
+	}
+	return nil, nil
+}
@@ -0,0 +1,174 @@
+package rawsql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+)
+
+// ErrUnsupported is wrapped by the error returned in Strict mode when
+// statements or clauses the parser does not understand are encountered
+var ErrUnsupported = errors.New("rawsql: unsupported sql")
+
+// Warning is a statement, or part of one, the parser ignored or applied in a
+// way worth knowing about
+type Warning struct {
+	Source      string // name of the sql source as in FileReport
+	Statement   string // shortened statement text
+	Message     string
+	Notice      bool // informational only, e.g. DROP TABLE IF EXISTS of a missing table, never fails Strict parsing
+	Transaction int  // number of the BEGIN ... COMMIT block the statement is in, counted from 1 across the sql, 0 outside one
+}
+
+func (w Warning) String() string {
+	if w.Source == "" {
+		return fmt.Sprintf("%s: %s", w.Message, w.Statement)
+	}
+	return fmt.Sprintf("%s: %s: %s", w.Source, w.Message, w.Statement)
+}
+
+// strictError returns the error of Strict parsing the statements of
+// warnings, nil when all of them are notices
+func strictError(warnings []Warning) error {
+	var unsupported []string
+	for _, w := range warnings {
+		if !w.Notice {
+			unsupported = append(unsupported, fmt.Sprintf("%s (%s)", w.Statement, w.Message))
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnsupported, strings.Join(unsupported, "; "))
+	}
+	return nil
+}
+
+const maxStatementSummary = 80
+
+// stmtSummary returns the text of node on a single line, shortened and copied
+// so it does not keep the parsed sql alive
+func stmtSummary(node ast.Node) string {
+	return summarize(restoreText(node.Text()))
+}
+
+func summarize(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > maxStatementSummary {
+		runes := []rune(text)
+		text = string(runes[:maxStatementSummary]) + "..."
+	}
+	return string([]byte(text))
+}
+
+// alterSpecText restores a single ALTER TABLE clause for messages
+func alterSpecText(spec *ast.AlterTableSpec) string {
+	var b strings.Builder
+	if err := spec.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags, &b)); err != nil || b.Len() == 0 {
+		return fmt.Sprintf("#%d", spec.Tp)
+	}
+	return summarize(b.String())
+}
+
+// ParseStats counts what the parser ingested, custom parsers not implementing
+// ReportingParser leave it empty
+type ParseStats struct {
+	Statements int // statements seen, including skipped ones
+	Skipped    int // statements ignored because they do not change the schema, e.g. DML
+	Tables     int // tables created
+	Columns    int // columns created by CREATE TABLE and ALTER TABLE ... ADD COLUMN
+	Indexes    int // indexes created
+}
+
+func (s ParseStats) sub(o ParseStats) ParseStats {
+	return ParseStats{
+		Statements: s.Statements - o.Statements,
+		Skipped:    s.Skipped - o.Skipped,
+		Tables:     s.Tables - o.Tables,
+		Columns:    s.Columns - o.Columns,
+		Indexes:    s.Indexes - o.Indexes,
+	}
+}
+
+func (s *ParseStats) addStats(o ParseStats) {
+	s.Statements += o.Statements
+	s.Skipped += o.Skipped
+	s.Tables += o.Tables
+	s.Columns += o.Columns
+	s.Indexes += o.Indexes
+}
+
+// FileReport describes the parsing of a single sql source
+type FileReport struct {
+	Name string // file path, url, loader source name, sql[i] or reader[i]
+	ParseStats
+	Duration time.Duration
+}
+
+// ParseReport describes what the dialector ingested and where time was spent
+type ParseReport struct {
+	ParseStats
+	Files    []FileReport
+	Warnings []Warning // ignored statements and clauses, Strict turns them into errors
+	Cached   bool      // tables were loaded from CacheDir without parsing
+	Duration time.Duration
+}
+
+func (r *ParseReport) add(name string, before, after ParseStats, dropped int, warnings []Warning, duration time.Duration) {
+	stats := after.sub(before)
+	stats.Statements += dropped
+	stats.Skipped += dropped
+	r.addStats(stats)
+	r.Files = append(r.Files, FileReport{Name: name, ParseStats: stats, Duration: duration})
+	for _, w := range warnings {
+		w.Source = name
+		r.Warnings = append(r.Warnings, w)
+	}
+}
+
+// Report returns the ParseReport of the last load or watch reload
+func (dialector Dialector) Report() *ParseReport {
+	if dialector.store == nil {
+		return nil
+	}
+	dialector.store.mu.RLock()
+	defer dialector.store.mu.RUnlock()
+	return dialector.store.report
+}
+
+func (dialector Dialector) parseStats() ParseStats {
+	if p, ok := dialector.Parser.(ReportingParser); ok {
+		return p.Stats()
+	}
+	return ParseStats{}
+}
+
+func (dialector Dialector) parseWarnings() int {
+	if p, ok := dialector.Parser.(ReportingParser); ok {
+		return len(p.Warnings())
+	}
+	return 0
+}
+
+// newWarnings returns the warnings issued after the parser had since of them
+func (dialector Dialector) newWarnings(since int) []Warning {
+	if p, ok := dialector.Parser.(ReportingParser); ok {
+		return p.Warnings()[since:]
+	}
+	return nil
+}
+
+// sqlName names dialector.SQL[i] for reports
+func (dialector Dialector) sqlName(i int) string {
+	if userSQL := len(dialector.SQL) - len(dialector.store.names); i >= userSQL {
+		return dialector.store.names[i-userSQL]
+	}
+	return fmt.Sprintf("sql[%d]", i)
+}
+
+func readerName(i int) string {
+	return fmt.Sprintf("reader[%d]", i)
+}
@@ -0,0 +1,69 @@
+package rawsql
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Sensitivity is what the markers in the comment of a column tell about the
+// data it holds, for fixture generators to redact and documentation to
+// annotate
+type Sensitivity struct {
+	Classes []string // upper cased classification markers in comment order, e.g. PII or PCI
+	Mask    string   // masking rule of a mask:rule marker, e.g. email, lower cased
+}
+
+// Sensitive reports whether the comment marks the column at all
+func (s Sensitivity) Sensitive() bool {
+	return len(s.Classes) > 0 || s.Mask != ""
+}
+
+// Is reports whether the comment has the classification marker class, e.g.
+// PII, regardless of case
+func (s Sensitivity) Is(class string) bool {
+	for _, c := range s.Classes {
+		if strings.EqualFold(c, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitivityMarker matches the classification markers, which have to be
+// written upper cased so prose such as "not sensitive" is no marker, and the
+// mask:rule markers
+var sensitivityMarker = regexp.MustCompile(`\b(PII|PHI|PCI|SENSITIVE|SECRET)\b|(?i:\bmask:([a-z][a-z0-9_-]*))`)
+
+// ColumnSensitivity reads the sensitivity markers of the comment of ct, e.g.
+// COMMENT 'contact address, PII, mask:email'. A mask marker without a
+// classification still makes the column Sensitive.
+func ColumnSensitivity(ct gorm.ColumnType) Sensitivity {
+	comment, _ := ct.Comment()
+	return sensitivityOf(comment)
+}
+
+func sensitivityOf(comment string) (s Sensitivity) {
+	for _, m := range sensitivityMarker.FindAllStringSubmatch(comment, -1) {
+		switch {
+		case m[1] != "" && !s.Is(m[1]):
+			s.Classes = append(s.Classes, m[1])
+		case m[2] != "" && s.Mask == "":
+			s.Mask = strings.ToLower(m[2])
+		}
+	}
+	return s
+}
+
+// SensitiveColumns returns the ColumnSensitivity of the columns of table
+// their comments mark, by column name
+func SensitiveColumns(table *Table) map[string]Sensitivity {
+	columns := make(map[string]Sensitivity)
+	for _, ct := range table.ColumnTypes {
+		if s := ColumnSensitivity(ct); s.Sensitive() {
+			columns[ct.Name()] = s
+		}
+	}
+	return columns
+}
@@ -0,0 +1,58 @@
+package rawsql
+
+import (
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// applySet handles SET statements, which never change the schema. Only
+// FOREIGN_KEY_CHECKS is remembered, see Config.HonorForeignKeyChecks.
+func (d *defaultParser) applySet(set *ast.SetStmt) {
+	for _, v := range set.Variables {
+		if v != nil && v.IsSystem && !v.IsGlobal && strings.EqualFold(v.Name, "foreign_key_checks") {
+			d.foreignKeyChecksOff = isOff(v.Value)
+		}
+	}
+}
+
+// isOff reports whether a system variable value turns it off, values that
+// cannot be evaluated such as @OLD_FOREIGN_KEY_CHECKS restore the default
+func isOff(value ast.ExprNode) bool {
+	switch v := value.(type) {
+	case ast.ValueExpr:
+		switch val := v.GetValue().(type) {
+		case int64:
+			return val == 0
+		case uint64:
+			return val == 0
+		case string:
+			return strings.EqualFold(val, "off") || val == "0"
+		}
+	case *ast.ColumnNameExpr:
+		return strings.EqualFold(v.Name.Name.O, "off")
+	}
+	return false
+}
+
+// applyTransaction tracks the BEGIN, START TRANSACTION, COMMIT and ROLLBACK
+// statements migration files wrap their DDL in, they never change the schema
+// but number the blocks warnings are reported in, see Warning.Transaction
+func (d *defaultParser) applyTransaction(node ast.StmtNode) {
+	switch stmt := node.(type) {
+	case *ast.BeginStmt:
+		// BEGIN inside a transaction commits it and starts another
+		d.transactions++
+		d.transaction = d.transactions
+	case *ast.CommitStmt:
+		d.transaction = 0
+	case *ast.RollbackStmt:
+		if stmt.SavepointName != "" {
+			return
+		}
+		if d.transaction != 0 {
+			d.notice(node, "ROLLBACK does not undo DDL, MySQL commits every ALTER, CREATE and DROP TABLE implicitly")
+		}
+		d.transaction = 0
+	}
+}
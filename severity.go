@@ -0,0 +1,230 @@
+package rawsql
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Severity grades the changes of a TableDiff so release pipelines can gate on
+// them, see TableDiff.Entries
+type Severity int
+
+const (
+	Safe          Severity = iota // applies to existing rows and code as is, e.g. a new nullable column
+	NeedsBackfill                 // applies once existing rows are fixed up or rewritten, e.g. a new unique index or a column becoming NOT NULL
+	Breaking                      // fails on existing rows or breaks code using the old schema, e.g. a dropped column
+)
+
+var severityNames = [...]string{Safe: "safe", NeedsBackfill: "needs-backfill", Breaking: "breaking"}
+
+func (s Severity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText writes the name of the severity, e.g. needs-backfill
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the name of a severity as MarshalText writes it
+func (s *Severity) UnmarshalText(text []byte) error {
+	for severity, name := range severityNames {
+		if name == string(text) {
+			*s = Severity(severity)
+			return nil
+		}
+	}
+	return fmt.Errorf("rawsql: unknown severity %q", text)
+}
+
+// DiffEntry is a line of a TableDiff as String renders it, with its severity
+type DiffEntry struct {
+	Op       string   `json:"op"`   // +, - or ~
+	Text     string   `json:"text"` // e.g. column `bio` text
+	Severity Severity `json:"severity"`
+	Reason   string   `json:"reason,omitempty"` // why the change is not safe, empty when it is
+}
+
+// Severity returns the highest severity of the entries of the diff, Safe
+// when it is empty
+func (diff TableDiff) Severity() Severity {
+	severity := Safe
+	for _, entry := range diff.Entries() {
+		if entry.Severity > severity {
+			severity = entry.Severity
+		}
+	}
+	return severity
+}
+
+// Entries returns the changes of the diff in the order String renders them,
+// each graded by rules such as: a new NOT NULL column without a default is
+// Breaking as the INSERTs of existing code leave it out, a column becoming
+// NOT NULL NeedsBackfill of its NULL rows, and a dropped column is Breaking.
+func (diff TableDiff) Entries() []DiffEntry {
+	from, to := diff.table(diff.from), diff.table(diff.to)
+	var entries []DiffEntry
+	add := func(op, text string, severity Severity, reason string) {
+		entries = append(entries, DiffEntry{Op: op, Text: text, Severity: severity, Reason: reason})
+	}
+	for _, change := range diff.Changes {
+		severity, reason := tableChangeSeverity(change)
+		add("~", changeText(change), severity, reason)
+	}
+	for _, ct := range diff.AddedColumns {
+		severity, reason := addedColumnSeverity(ct)
+		add("+", "column "+columnSQL(to, ct), severity, reason)
+	}
+	for _, ct := range diff.RemovedColumns {
+		add("-", "column "+columnSQL(from, ct), Breaking, "the column and its data are dropped")
+	}
+	for _, column := range diff.ChangedColumns {
+		changes := make([]string, len(column.Changes))
+		severity, reason := Safe, ""
+		for i, change := range column.Changes {
+			changes[i] = changeText(change)
+			if s, r := columnChangeSeverity(column, change); s > severity {
+				severity, reason = s, r
+			}
+		}
+		add("~", "column "+quoteName(column.Name)+": "+strings.Join(changes, ", "), severity, reason)
+	}
+	for _, idx := range diff.AddedIndexes {
+		severity, reason := Safe, ""
+		if uniqueIndex(idx) {
+			severity, reason = NeedsBackfill, "existing duplicates must be removed first"
+		}
+		add("+", "index "+IndexSQL(idx), severity, reason)
+	}
+	for _, idx := range diff.RemovedIndexes {
+		severity, reason := Safe, ""
+		if pk, _ := idx.PrimaryKey(); pk {
+			severity, reason = Breaking, "rows lose their primary key"
+		}
+		add("-", "index "+IndexSQL(idx), severity, reason)
+	}
+	for _, idx := range diff.ChangedIndexes {
+		severity, reason := Safe, ""
+		if uniqueIndex(idx.To) {
+			severity, reason = NeedsBackfill, "existing duplicates must be removed first"
+		}
+		add("~", "index "+IndexSQL(idx.From)+" -> "+IndexSQL(idx.To), severity, reason)
+	}
+	for _, fk := range diff.AddedForeignKeys {
+		add("+", "foreign key "+foreignKeySQL(fk), NeedsBackfill, "existing rows must reference existing rows")
+	}
+	for _, fk := range diff.RemovedForeignKeys {
+		add("-", "foreign key "+foreignKeySQL(fk), Safe, "")
+	}
+	return entries
+}
+
+func tableChangeSeverity(change FieldChange) (Severity, string) {
+	switch change.Field {
+	case "name":
+		return Breaking, "code using the old name fails"
+	case "engine":
+		return NeedsBackfill, "the table is rebuilt"
+	}
+	// the default charset and collation only apply to new columns
+	return Safe, ""
+}
+
+func addedColumnSeverity(ct gorm.ColumnType) (Severity, string) {
+	nullable, ok := ct.Nullable()
+	_, hasDefault := ct.DefaultValue()
+	autoIncrement, _ := ct.AutoIncrement()
+	unique, _ := ct.Unique()
+	pk, _ := ct.PrimaryKey()
+	generated, stored := false, false
+	if c, ok := ct.(*ColumnType); ok {
+		_, stored, generated = c.Generated()
+	}
+	switch {
+	case generated && stored:
+		return NeedsBackfill, "the stored column is computed for existing rows"
+	case generated || autoIncrement || (!ok || nullable) && !pk:
+		return Safe, ""
+	case !hasDefault:
+		return Breaking, "NOT NULL without a default, INSERTs leaving it out fail"
+	case unique || pk:
+		return Breaking, "existing rows all get the default, which must be unique"
+	}
+	return NeedsBackfill, "existing rows get the default"
+}
+
+func columnChangeSeverity(column ColumnDiff, change FieldChange) (Severity, string) {
+	switch change.Field {
+	case "name":
+		return Breaking, "code using the old name fails"
+	case "type":
+		if widens(column.From, column.To) {
+			return Safe, ""
+		}
+		return Breaking, "existing values may not fit the new type"
+	case "charset", "collation":
+		return NeedsBackfill, "existing values are converted"
+	case "srid":
+		return Breaking, "existing values of another SRID are rejected"
+	case "generated":
+		return NeedsBackfill, "the column is computed again for existing rows"
+	case "nullable":
+		if change.To == "NOT NULL" {
+			return NeedsBackfill, "existing NULLs must be filled in first"
+		}
+	case "auto increment":
+		if change.To == "" {
+			return Breaking, "INSERTs leaving the column out fail"
+		}
+	case "primary key":
+		return Breaking, "the primary key changes"
+	case "unique":
+		if change.To != "" {
+			return NeedsBackfill, "existing duplicates must be removed first"
+		}
+	}
+	return Safe, ""
+}
+
+// integerRanks orders the integer types by range
+var integerRanks = map[string]int{"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "integer": 4, "bigint": 5}
+
+// widens reports whether every value of a column of type from fits type to:
+// longer strings, larger integers and decimals, and enums and sets with
+// values appended
+func widens(from, to gorm.ColumnType) bool {
+	fromType, _ := from.ColumnType()
+	toType, _ := to.ColumnType()
+	fromName, toName := strings.ToLower(from.DatabaseTypeName()), strings.ToLower(to.DatabaseTypeName())
+	if strings.Contains(fromType, "unsigned") != strings.Contains(toType, "unsigned") {
+		return false
+	}
+	if fromRank, ok := integerRanks[fromName]; ok {
+		return integerRanks[toName] >= fromRank
+	}
+	if fromName != toName {
+		return false
+	}
+	switch fromName {
+	case "enum", "set":
+		return strings.HasPrefix(toType, strings.TrimSuffix(fromType, ")"))
+	case "decimal":
+		fromPrecision, fromScale, _ := from.DecimalSize()
+		toPrecision, toScale, _ := to.DecimalSize()
+		return toScale >= fromScale && toPrecision-toScale >= fromPrecision-fromScale
+	}
+	fromLength, _ := from.Length()
+	toLength, _ := to.Length()
+	return toLength >= fromLength
+}
+
+func uniqueIndex(idx gorm.Index) bool {
+	pk, _ := idx.PrimaryKey()
+	unique, _ := idx.Unique()
+	return pk || unique
+}
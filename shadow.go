@@ -0,0 +1,210 @@
+package rawsql
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/rawsql/meta"
+)
+
+// Shadow verifies the built-in Parser against a real server: Verify executes
+// sql in a scratch database and compares the tables the server made of it,
+// read back from its information_schema, with those the Parser made
+type Shadow struct {
+	DB     *gorm.DB // connected to an empty scratch database, e.g. of a Docker MySQL or TiDB, the tables created are left there
+	Schema string   // database the statements create their tables in, defaults to the current database of DB
+	Config Config   // parses the sql, its sources are left out
+}
+
+// ShadowReport lists the differences Shadow.Verify found
+type ShadowReport struct {
+	Diffs   []TableDiff // of the tables the server made differently, from the parsed table to the server one, by name
+	Missing []string    // parsed tables the server does not have
+	Extra   []string    // tables of the server the Parser did not make
+}
+
+// OK reports whether the server made the same tables as the Parser
+func (r ShadowReport) OK() bool {
+	return len(r.Diffs) == 0 && len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// Verify executes the statements of every sql content in order, parses the
+// same contents and compares the results. information_schema has no table
+// options but the engine, collation and comment, so the other options and
+// the charsets and collations the server defaults are left out of the
+// comparison. A statement the server rejects is returned as the error.
+func (s Shadow) Verify(contents ...string) (report ShadowReport, err error) {
+	config := s.Config
+	config.SQL, config.FilePath, config.DirPath, config.Glob = contents, nil, nil, nil
+	config.FS, config.Readers, config.Remote, config.Loaders = nil, nil, nil, nil
+	config.Watch, config.CacheDir, config.ExportDir = false, "", ""
+	db, err := gorm.Open(New(config), &gorm.Config{Logger: s.DB.Logger})
+	if err != nil {
+		return report, err
+	}
+	parsed := db.Dialector.(*Dialector).Parser.GetTables()
+
+	for _, content := range contents {
+		for _, span := range statementSpans(content) {
+			stmt := content[span.start:span.end]
+			if err = s.DB.Exec(stmt).Error; err != nil {
+				return report, fmt.Errorf("rawsql: shadow database rejected %s: %w", summarize(stmt), err)
+			}
+		}
+	}
+	server, err := s.serverTables()
+	if err != nil {
+		return report, err
+	}
+
+	serverNames := make(map[string]string, len(server))
+	for name := range server {
+		serverNames[strings.ToLower(name)] = name
+	}
+	names := make([]string, 0, len(parsed))
+	for name := range parsed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		serverName, ok := serverNames[strings.ToLower(name)]
+		if !ok {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+		delete(serverNames, strings.ToLower(name))
+		table, err := ParseTable(CreateTableSQL(parsed[name]))
+		if err != nil {
+			return report, err
+		}
+		shadowKeys(table)
+		diff := CompareTables(table, server[serverName])
+		diff.Changes = shadowChanges(diff.Changes)
+		if !diff.Empty() {
+			report.Diffs = append(report.Diffs, diff)
+		}
+	}
+	for _, name := range serverNames {
+		report.Extra = append(report.Extra, name)
+	}
+	sort.Strings(report.Extra)
+	return report, nil
+}
+
+// shadowKeys turns the PRIMARY KEY and UNIQUE declared on the columns of
+// table into the indexes information_schema reports for them
+func shadowKeys(table *Table) {
+	var primary []string
+	for _, ct := range table.ColumnTypes {
+		c, ok := ct.(*ColumnType)
+		if !ok {
+			continue
+		}
+		if pk, _ := c.PrimaryKey(); pk {
+			primary = append(primary, c.Name())
+			c.NullableValue.Bool = false
+		}
+		if unique, _ := c.Unique(); unique {
+			c.UniqueValue = sql.NullBool{}
+			table.Indexes = append(table.Indexes, meta.NewIndex(migrator.Index{
+				TableName: table.Name, NameValue: c.Name(), ColumnList: []string{c.Name()},
+				UniqueValue: sql.NullBool{Bool: true, Valid: true},
+			}))
+		}
+	}
+	for _, idx := range table.Indexes {
+		if pk, _ := idx.PrimaryKey(); pk {
+			return
+		}
+	}
+	if len(primary) > 0 {
+		table.Indexes = append(table.Indexes, meta.NewIndex(migrator.Index{
+			TableName: table.Name, ColumnList: primary,
+			PrimaryKeyValue: sql.NullBool{Bool: true, Valid: true},
+		}))
+	}
+}
+
+// shadowChanges keeps the table changes information_schema can tell, the
+// server fills in the charset and collation the sql leaves out
+func shadowChanges(changes []FieldChange) (kept []FieldChange) {
+	for _, change := range changes {
+		switch change.Field {
+		case "engine", "comment":
+			kept = append(kept, change)
+		case "charset", "collation":
+			if change.From != "" {
+				kept = append(kept, change)
+			}
+		}
+	}
+	return kept
+}
+
+const shadowSchema = "TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE())"
+
+// serverTables reads the tables of the scratch database back from its
+// information_schema, the way InformationSchema loads exports of it
+func (s Shadow) serverTables() (map[string]*Table, error) {
+	var rows [4][]infoRow
+	for i, query := range []string{
+		"SELECT * FROM information_schema.COLUMNS WHERE " + shadowSchema,
+		"SELECT * FROM information_schema.STATISTICS WHERE " + shadowSchema,
+		"SELECT k.*, r.UPDATE_RULE, r.DELETE_RULE FROM information_schema.KEY_COLUMN_USAGE k " +
+			"LEFT JOIN information_schema.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA " +
+			"AND r.TABLE_NAME = k.TABLE_NAME AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME WHERE k." + shadowSchema,
+		"SELECT * FROM information_schema.TABLES WHERE TABLE_TYPE = 'BASE TABLE' AND " + shadowSchema,
+	} {
+		var err error
+		if rows[i], err = s.queryRows(query); err != nil {
+			return nil, err
+		}
+	}
+
+	d := newDefaultParse(nil).(*defaultParser)
+	stmtNodes, err := d.parseStmts(infoSchemaSQL(rows[0], rows[1], rows[2], rows[3], true))
+	if err != nil {
+		return nil, fmt.Errorf("rawsql: parse the shadow database tables: %w", err)
+	}
+	if err = d.applyStmts(stmtNodes); err != nil {
+		return nil, err
+	}
+	return d.tables, nil
+}
+
+// queryRows runs query with the schema of the scratch database, NULL values
+// are left out of the rows
+func (s Shadow) queryRows(query string) ([]infoRow, error) {
+	rows, err := s.DB.Raw(query, s.Schema).Rows()
+	if err != nil {
+		return nil, fmt.Errorf("rawsql: query the shadow database: %w", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []infoRow
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(infoRow, len(columns))
+		for i, value := range values {
+			if value.Valid {
+				row[strings.ToUpper(columns[i])] = value.String
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
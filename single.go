@@ -0,0 +1,66 @@
+package rawsql
+
+import (
+	"fmt"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// ParseTable parses sql holding exactly one CREATE TABLE statement into its
+// table with the default options. Every call uses a parser of its own, so it
+// is safe for concurrent use and leaves all Dialectors untouched.
+func ParseTable(sql string) (*Table, error) {
+	d := newDefaultParse(nil).(*defaultParser)
+	stmtNodes, err := d.parseStmts(sql)
+	if err != nil {
+		return nil, err
+	}
+	if len(stmtNodes) != 1 {
+		return nil, fmt.Errorf("rawsql: expected one CREATE TABLE statement, got %d statements", len(stmtNodes))
+	}
+	create, ok := stmtNodes[0].(*ast.CreateTableStmt)
+	if !ok {
+		return nil, fmt.Errorf("rawsql: expected a CREATE TABLE statement, got %s", stmtSummary(stmtNodes[0]))
+	}
+	if err = d.applyStmts(stmtNodes); err != nil {
+		return nil, err
+	}
+	return d.tables[create.Table.Name.String()], nil
+}
+
+// ApplyAlter applies the ALTER TABLE statement sql to table in place, it is
+// the Alterer of the tables rawsql parses and decodes, see Table.ApplyAlter:
+// sql must hold exactly one ALTER TABLE statement of table, clauses the parser
+// does not support are ignored as they are without Config.Strict
+func ApplyAlter(table *Table, sql string) (err error) {
+	d := newDefaultParse(nil).(*defaultParser)
+	stmtNodes, err := d.parseStmts(sql)
+	if err != nil {
+		return err
+	}
+	if len(stmtNodes) != 1 {
+		return fmt.Errorf("rawsql: expected one ALTER TABLE statement, got %d statements", len(stmtNodes))
+	}
+	alter, ok := stmtNodes[0].(*ast.AlterTableStmt)
+	if !ok {
+		return fmt.Errorf("rawsql: expected an ALTER TABLE statement, got %s", stmtSummary(stmtNodes[0]))
+	}
+	if name := alter.Table.Name.String(); name != table.Name {
+		return fmt.Errorf("rawsql: ALTER TABLE %s applied to table %s", name, table.Name)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rawsql: apply alter: %v", r)
+		}
+	}()
+	d.tables[table.Name] = table
+	return d.applyStmts(stmtNodes)
+}
+
+// withAlterer sets the Alterer of tables to ApplyAlter
+func withAlterer(tables map[string]*Table) map[string]*Table {
+	for _, table := range tables {
+		table.Alterer = ApplyAlter
+	}
+	return tables
+}
@@ -0,0 +1,143 @@
+package rawsql
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// spatialTypes are the MySQL spatial column types, the tidb parser supports
+// none of them
+var spatialTypes = map[string]bool{
+	"geometry": true, "point": true, "linestring": true, "polygon": true,
+	"multipoint": true, "multilinestring": true, "multipolygon": true,
+	"geometrycollection": true, "geomcollection": true,
+}
+
+// spatialColumn is a column declared with a spatial type
+type spatialColumn struct {
+	dataType string
+	srid     sql.NullInt64
+}
+
+var (
+	spatialTypeMarker = regexp.MustCompile(`longblob/\*rawsql:(\w+)\*/`)
+	sridMarker        = regexp.MustCompile(`/\*rawsql:(SRID \d+)\*/`)
+)
+
+// spatialIndexMarker replaces the SPATIAL keyword of index definitions
+const spatialIndexMarker = "/*rawsql:SPATIAL*/"
+
+// rewriteSpatial replaces the spatial column types of sql with a LONGBLOB,
+// which is how MySQL stores them, and hides the SRID attribute and the
+// SPATIAL keyword of indexes in comments so it can be parsed. The original
+// type follows in a comment, restoreSpatial puts it back.
+func rewriteSpatial(sql string) string {
+	if !containsFold(sql, "POINT") && !containsFold(sql, "GEOM") && !containsFold(sql, "POLYGON") &&
+		!containsFold(sql, "LINESTRING") && !containsFold(sql, "SPATIAL") {
+		return sql
+	}
+	var (
+		b    strings.Builder
+		last int
+		prev sqlToken
+	)
+	tokens := sqlTokens(sql)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.kind == 'w' && spatialTypes[strings.ToLower(tok.text)] && prev.isName() && !prev.is("AS"):
+			b.WriteString(sql[last:tok.start])
+			b.WriteString("longblob/*rawsql:" + tok.text + "*/")
+			last = tok.end
+		case tok.is("SRID") && i+1 < len(tokens) && isNumber(tokens[i+1].text):
+			b.WriteString(sql[last:tok.start])
+			b.WriteString("/*rawsql:SRID " + tokens[i+1].text + "*/")
+			last = tokens[i+1].end
+			i++
+		case tok.is("SPATIAL") && i+1 < len(tokens) && (tokens[i+1].is("KEY") || tokens[i+1].is("INDEX")):
+			b.WriteString(sql[last:tok.start])
+			b.WriteString(spatialIndexMarker)
+			last = tok.end
+		}
+		prev = tok
+	}
+	if last == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+	return b.String()
+}
+
+// restoreSpatial undoes rewriteSpatial in statement text
+func restoreSpatial(text string) string {
+	if !strings.Contains(text, "rawsql:") {
+		return text
+	}
+	text = spatialTypeMarker.ReplaceAllString(text, "$1")
+	text = strings.ReplaceAll(text, spatialIndexMarker, "SPATIAL")
+	return sridMarker.ReplaceAllString(text, "$1")
+}
+
+// restoreText undoes the rewrites parseStmts applies before parsing
+func restoreText(text string) string {
+	return restoreVector(restoreSpatial(restoreInvisible(text)))
+}
+
+// spatialColumns returns the columns text declares with a spatial type by
+// their lower cased names, with the SRID they were given
+func spatialColumns(text string) map[string]spatialColumn {
+	if !strings.Contains(text, "longblob/*rawsql:") {
+		return nil
+	}
+	columns := make(map[string]spatialColumn)
+	var (
+		prev    sqlToken
+		current string
+		depth   int
+	)
+	tokens := sqlTokens(restoreText(text))
+	for i, tok := range tokens {
+		switch {
+		case tok.kind == 'p' && tok.text == "(":
+			depth++
+		case tok.kind == 'p' && tok.text == ")":
+			depth--
+		case tok.kind == 'p' && (tok.text == "," || tok.text == ";") && depth <= 1:
+			current = ""
+		case tok.kind == 'w' && spatialTypes[strings.ToLower(tok.text)] && prev.isName() && !prev.is("AS"):
+			current = strings.ToLower(prev.text)
+			columns[current] = spatialColumn{dataType: strings.ToLower(tok.text)}
+		case tok.is("SRID") && current != "" && i+1 < len(tokens):
+			if srid, err := strconv.ParseInt(tokens[i+1].text, 10, 64); err == nil {
+				column := columns[current]
+				column.srid = sql.NullInt64{Int64: srid, Valid: true}
+				columns[current] = column
+			}
+		}
+		prev = tok
+	}
+	return columns
+}
+
+// spatialIndexes returns the lower cased names of the SPATIAL indexes text
+// declares
+func spatialIndexes(text string) map[string]bool {
+	if !strings.Contains(text, spatialIndexMarker) {
+		return nil
+	}
+	indexes := make(map[string]bool)
+	tokens := sqlTokens(restoreText(text))
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i].is("SPATIAL") && tokens[i+2].isName() {
+			indexes[strings.ToLower(tokens[i+2].text)] = true
+		}
+	}
+	return indexes
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 32)
+	return err == nil
+}
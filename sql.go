@@ -1,71 +1,308 @@
 package rawsql
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
 	_ "github.com/pingcap/tidb/pkg/parser/test_driver"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
 	"gorm.io/gorm/migrator"
 	"gorm.io/gorm/schema"
 )
 
 type Config struct {
-	DriverName string   //mysql
-	FilePath   []string //create table sql file or file path
-	SQL        []string //create table sql content
+	DriverName string      //mysql
+	FilePath   []string    //create table sql file or file path
+	SQL        []string    //create table sql content
+	FS         fs.FS       //filesystem holding sql files, e.g. embed.FS
+	FSPatterns []string    //glob patterns matched against FS, defaults to the whole FS
+	Readers    []io.Reader //sql streams, e.g. os.Stdin
+	Tables     []*Table    //predefined tables the sql alters and references without creating them, e.g. tables of another service, copied into the built-in Parser
+	DirPath    []string    //directories searched recursively for .sql, .sql.gz and archive files
+	Glob       []string    //file glob patterns, e.g. "migrations/*.sql"
+
+	FileOrder func(a, b string) bool //less func ordering files found by DirPath and Glob, defaults to LexicalOrder
+
+	Remote        []RemoteSource  //sql files fetched over HTTP(S)
+	RemoteTimeout time.Duration   //timeout per remote fetch, defaults to 30s
+	HTTPClient    *http.Client    //client used for Remote, defaults to http.DefaultClient
+	Loaders       []Loader        //custom sql sources, e.g. object storage
+	Context       context.Context //context passed to Loaders and remote fetches, defaults to context.Background()
+
+	Watch     bool                                      //reparse file based sources whenever they change on disk
+	OnReload  func(tables map[string]*Table, err error) //called after each watch reload
+	NewParser func() Parser                             //creates the parser for each watch reload, required with Watch and a custom Parser
+
+	Backend           string                //name of the registered backend creating the Parser when none is given, defaults to TiDBBackend
+	NewTiDBParser     func() *parser.Parser //creates the pooled tidb parsers used by the built-in Parser, defaults to parser.New
+	SQLMode           string                //sql mode of the built-in Parser, e.g. "ANSI_QUOTES,NO_BACKSLASH_ESCAPES"
+	ParseCharset      string                //charset of the sql text, defaults to utf8mb4
+	ParseCollation    string                //collation of the sql text, defaults to the charset default
+	VectorScanType    reflect.Type          //scan type of TiDB VECTOR columns, defaults to []float32, register other types with meta.RegisterScanType to keep them in snapshots
+	CommentExtractors []string              //names of the registered CommentExtractors setting the Metadata of tables and columns from their comments, in order, see RegisterCommentExtractor
+	TypeMappings      []TypeMapping         //scan types of the columns matching them by name and type, e.g. Money for the decimal *_amount columns, the first matching one applies
+	NullableScanType  NullableStyle         //scan type of nullable columns, e.g. NullableAsPointer for *int64 or NullableAsSQLNull for sql.NullInt64, defaults to the NOT NULL type
+	TargetVersion     string                //server the sql is validated against, e.g. "mysql-5.7", "mysql-8.0.12" or "tidb-7.5", set to warn about DDL features it lacks, ValidateIdentifiers defaults to mysql-8.0
+
+	CacheDir    string //directory caching parsed tables keyed by the sql content hash
+	ExportDir   string //directory receiving a normalized .sql file per parsed table and an index, see Export
+	Parallelism int    //number of sql contents parsed concurrently by the built-in parser, statements are still applied in order
+
+	StreamReaders       bool //parse Readers one statement at a time instead of reading them whole, disables CacheDir for them
+	SkipDML             bool //drop INSERT, REPLACE, UPDATE and DELETE statements before parsing, e.g. for dumps with data
+	RecoverSyntaxErrors bool //skip the statements that do not parse and go on after the next semicolon, reporting them as warnings, instead of rejecting the whole sql content
+	Strict              bool //fail on statements and clauses the built-in Parser ignores instead of reporting them as warnings
+	BaselineLock        bool //fail with ErrBaselineTable and a diff when a table of the first sql content is created again by a later one
+
+	Logger      logger.Interface                         //receives skipped statements, applied ALTERs, warnings and timings, e.g. db.Logger, nothing is logged when nil
+	Metrics     Metrics                                  //receives statement counters and parse durations, e.g. for Prometheus
+	Tracer      Tracer                                   //starts spans around reading, parsing and building the tables, see Tracer
+	OnStatement func(stmt ast.StmtNode, raw string) bool //called with every statement the built-in Parser parsed and its original text before applying it, returning false skips the statement and counts it in Report.Skipped, disables CacheDir
+
+	SkipTemporaryTables   bool             //ignore CREATE TEMPORARY TABLE and the ALTER and DROP statements on those tables
+	HonorForeignKeyChecks bool             //let SET FOREIGN_KEY_CHECKS = 0 silence warnings about foreign keys referencing tables created later or dropped
+	DroppedReferences     ForeignKeyPolicy //what happens to foreign keys referencing a dropped table, defaults to KeepForeignKeys
+
+	SkipGhostTables    bool     //ignore the tables of online schema change tools, e.g. _users_gho
+	GhostTablePatterns []string //path.Match patterns of the ignored table names, defaults to DefaultGhostTablePatterns
+	UUIDColumnPatterns []string //path.Match patterns of the lower cased names of the char(36) and binary(16) columns Dialector.UUIDColumns reports, defaults to DefaultUUIDColumnPatterns
+
 	Parser
 }
 
 type Dialector struct {
 	*Config
-	tables map[string]*Table
+	store *tableStore
+}
+
+// tableStore holds the parsed tables shared by a dialector and its migrators,
+// the map is swapped as a whole on reload and never mutated once published
+type tableStore struct {
+	mu      sync.RWMutex
+	tables  map[string]*Table
+	report  *ParseReport
+	watcher *watcher
+	names   []string // names of the loaded sql, following the sql given in Config.SQL
+	backend Backend  // created the Parser, nil when it was given in Config.Parser
+}
+
+func (s *tableStore) get() map[string]*Table {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tables
+}
+
+func (s *tableStore) set(tables map[string]*Table, report *ParseReport) {
+	s.mu.Lock()
+	s.tables, s.report = tables, report
+	s.mu.Unlock()
 }
 
 func New(config Config) gorm.Dialector {
-	return &Dialector{Config: &config, tables: map[string]*Table{}}
+	return &Dialector{Config: &config, store: &tableStore{tables: map[string]*Table{}}}
 }
 
 func (dialector Dialector) Name() string {
 	return dialector.DriverName
 }
 
-func (dialector Dialector) Initialize(db *gorm.DB) error {
+func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 	if dialector.DriverName == "" {
 		dialector.DriverName = "mysql"
 	}
 	if dialector.SQL == nil {
 		dialector.SQL = make([]string, 0)
 	}
-	if dialector.tables == nil {
-		dialector.tables = make(map[string]*Table)
+	if dialector.store == nil {
+		return errors.New("rawsql: dialector must be created with New")
+	}
+	if _, err := mysql.GetSQLMode(dialector.SQLMode); err != nil {
+		return err
+	}
+	if _, err := parseServerVersion(dialector.TargetVersion); err != nil {
+		return err
+	}
+	if err := dialector.validateGhostTablePatterns(); err != nil {
+		return err
+	}
+	if err := dialector.validateUUIDColumnPatterns(); err != nil {
+		return err
+	}
+	if err := dialector.validateTypeMappings(); err != nil {
+		return err
+	}
+	if err := dialector.validateTables(); err != nil {
+		return err
+	}
+	if _, err := dialector.commentExtractors(); err != nil {
+		return err
 	}
 	if dialector.Parser == nil {
-		dialector.Parser = newDefaultParse()
+		backend, err := dialector.backend()
+		if err != nil {
+			return err
+		}
+		dialector.Parser = backend(dialector.Config)
+		dialector.store.backend = backend
+	} else if d, ok := dialector.Parser.(*defaultParser); ok {
+		d.useConfig(dialector.Config)
 	}
-	if err := dialector.fileTOSQL(); err != nil {
+	ctx, span := dialector.startSpan(dialector.context(), "rawsql.load")
+	defer func() { endSpan(span, err) }()
+
+	userSQL := len(dialector.SQL)
+	fileSQL, streamAt, err := dialector.readSources(ctx)
+	if err != nil {
 		return err
 	}
 
-	if err := dialector.sqlTOTable(); err != nil {
+	if err := dialector.sqlTOTable(ctx, streamAt); err != nil {
 		return err
 	}
 
+	if dialector.ExportDir != "" {
+		if err := Export(dialector.ExportDir, dialector.store.get()); err != nil {
+			return err
+		}
+	}
+
+	// release the file and stream contents, the parsed tables hold copies of what they need
+	streamSQL := append([]string(nil), dialector.SQL[fileSQL:]...)
+	streamNames := append([]string(nil), dialector.store.names[fileSQL-userSQL:]...)
+	for i := userSQL; i < len(dialector.SQL); i++ {
+		dialector.SQL[i] = ""
+	}
+	dialector.SQL = dialector.SQL[:userSQL]
+	dialector.store.names = nil
+
+	if dialector.Watch {
+		return dialector.watch(dialector.SQL, streamSQL, streamNames)
+	}
+
 	return nil
 }
 
-func (dialector Dialector) sqlTOTable() error {
-	for _, sql := range dialector.SQL {
-		if err := dialector.Parser.ParseSQL(sql); err != nil {
-			return err
+// validateTables checks Config.Tables can be seeded
+func (dialector Dialector) validateTables() error {
+	names := make(map[string]struct{}, len(dialector.Tables))
+	for i, table := range dialector.Tables {
+		if table == nil || table.Name == "" {
+			return fmt.Errorf("rawsql: Config.Tables[%d] has no name", i)
+		}
+		if _, dup := names[table.Name]; dup {
+			return fmt.Errorf("rawsql: Config.Tables holds table %s twice", table.Name)
+		}
+		names[table.Name] = struct{}{}
+	}
+	return nil
+}
+
+// readSources reads every source but the streamed Readers into dialector.SQL,
+// it returns the length of dialector.SQL after the file based sources and the
+// index Readers are streamed at, -1 when they are read whole
+func (dialector Dialector) readSources(ctx context.Context) (fileSQL, streamAt int, err error) {
+	_, span := dialector.startSpan(ctx, "rawsql.read")
+	defer func() {
+		span.SetAttribute("rawsql.sources", len(dialector.store.names))
+		endSpan(span, err)
+	}()
+
+	if err = dialector.filesTOSQL(); err != nil {
+		return 0, 0, err
+	}
+	fileSQL, streamAt = len(dialector.SQL), -1
+	if dialector.StreamReaders {
+		if dialector.Watch && len(dialector.Readers) > 0 {
+			return 0, 0, errors.New("rawsql: streamed Readers cannot be replayed by Watch")
+		}
+		streamAt = fileSQL
+	} else if err = dialector.readerTOSQL(); err != nil {
+		return 0, 0, err
+	}
+	if err = dialector.remoteTOSQL(); err != nil {
+		return 0, 0, err
+	}
+	if err = dialector.loaderTOSQL(); err != nil {
+		return 0, 0, err
+	}
+	return fileSQL, streamAt, nil
+}
+
+// filesTOSQL reads every file based source, it is rerun on each watch reload
+func (dialector Dialector) filesTOSQL() error {
+	if err := dialector.fileTOSQL(); err != nil {
+		return err
+	}
+
+	if err := dialector.dirTOSQL(); err != nil {
+		return err
+	}
+
+	return dialector.fsTOSQL()
+}
+
+// sqlTOTable parses dialector.SQL, when streamAt is not negative Readers are
+// streamed right before the sql at that index
+func (dialector Dialector) sqlTOTable(ctx context.Context, streamAt int) error {
+	start := time.Now()
+	cache := dialector.CacheDir != "" && (streamAt < 0 || len(dialector.Readers) == 0) && dialector.OnStatement == nil
+	if cache {
+		if tables, warnings, ok := dialector.loadCache(); ok {
+			// the warnings of the parse fail Strict loads as they did uncached
+			if dialector.Strict {
+				if err := strictError(warnings); err != nil {
+					return err
+				}
+			}
+			report := &ParseReport{Warnings: warnings, Cached: true, Duration: time.Since(start)}
+			dialector.store.set(tables, report)
+			dialector.logReport(report)
+			return nil
 		}
 	}
 
-	tables := dialector.Parser.GetTables()
-	for _, v := range tables {
-		dialector.tables[v.Name] = v
+	report, parsed := &ParseReport{}, false
+	// deferred so loads failing with a panic are reported too
+	defer func() { dialector.observe(report, start, parsed) }()
+	if err := dialector.parseSQL(ctx, streamAt, report); err != nil {
+		return err
+	}
+	parsed = true
+
+	_, span := dialector.startSpan(ctx, "rawsql.build")
+	defer span.End()
+	tables := make(map[string]*Table)
+	for _, v := range dialector.Parser.GetTables() {
+		tables[v.Name] = v
+	}
+	report.Duration = time.Since(start)
+	dialector.store.set(tables, report)
+	dialector.logReport(report)
+	span.SetAttribute("rawsql.tables", len(tables))
+
+	if cache {
+		if err := dialector.saveCache(tables, report.Warnings); err != nil {
+			span.RecordError(err)
+			return err
+		}
 	}
 
 	return nil
@@ -113,7 +350,167 @@ func (dialector Dialector) readFile(fileName string) error {
 	if err != nil {
 		return err
 	}
-	dialector.SQL = append(dialector.SQL, string(content))
+	return dialector.appendSQL(fileName, content)
+}
+
+func (dialector Dialector) appendSQL(fileName string, content []byte) error {
+	sources, err := decodeSQL(fileName, content, dialector.fileOrder())
+	if err != nil {
+		return err
+	}
+	for _, src := range sources {
+		dialector.addSQL(src.Name, string(src.Content))
+	}
+	return nil
+}
+
+// addSQL appends sql to be parsed, name identifies it in the ParseReport
+func (dialector Dialector) addSQL(name, sql string) {
+	dialector.SQL = append(dialector.SQL, sql)
+	dialector.store.names = append(dialector.store.names, name)
+}
+
+func (dialector Dialector) fileOrder() func(a, b string) bool {
+	if dialector.FileOrder == nil {
+		return LexicalOrder
+	}
+	return dialector.FileOrder
+}
+
+func (dialector Dialector) dirTOSQL() error {
+	less := dialector.fileOrder()
+	for _, dir := range dialector.DirPath {
+		if dir == "" {
+			continue
+		}
+		files := make([]string, 0)
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isSQLFile(path) {
+				return err
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err = dialector.readSortedFiles(files, less); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range dialector.Glob {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		files := make([]string, 0, len(matches))
+		for _, f := range matches {
+			if v, err := os.Stat(f); err == nil && !v.IsDir() {
+				files = append(files, f)
+			}
+		}
+		if err = dialector.readSortedFiles(files, less); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dialector Dialector) readSortedFiles(files []string, less func(a, b string) bool) error {
+	sort.SliceStable(files, func(i, j int) bool {
+		return less(files[i], files[j])
+	})
+	for _, f := range files {
+		if err := dialector.readFile(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isSQLFile(name string) bool {
+	return isSQLEntry(name) || isArchive(name)
+}
+
+// LexicalOrder orders files by their path, byte-wise.
+func LexicalOrder(a, b string) bool {
+	return a < b
+}
+
+// NumericPrefixOrder orders files by the leading number of each path element,
+// so 2_users.sql sorts before 10_orders.sql. Elements without a numeric prefix
+// fall back to LexicalOrder.
+func NumericPrefixOrder(a, b string) bool {
+	as, bs := strings.Split(filepath.ToSlash(a), "/"), strings.Split(filepath.ToSlash(b), "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aok := numericPrefix(as[i])
+		bn, bok := numericPrefix(bs[i])
+		if aok && bok && an != bn {
+			return an < bn
+		}
+		return as[i] < bs[i]
+	}
+	return len(as) < len(bs)
+}
+
+func numericPrefix(name string) (uint64, bool) {
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(name[:end], 10, 64)
+	return n, err == nil
+}
+
+func (dialector Dialector) fsTOSQL() error {
+	if dialector.FS == nil {
+		return nil
+	}
+	patterns := dialector.FSPatterns
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(dialector.FS, pattern)
+		if err != nil {
+			return err
+		}
+		for _, name := range matches {
+			err = fs.WalkDir(dialector.FS, name, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				content, err := fs.ReadFile(dialector.FS, path)
+				if err != nil {
+					return err
+				}
+				return dialector.appendSQL(path, content)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (dialector Dialector) readerTOSQL() error {
+	for i, r := range dialector.Readers {
+		if r == nil {
+			continue
+		}
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		dialector.addSQL(readerName(i), string(content))
+	}
 	return nil
 }
 
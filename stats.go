@@ -0,0 +1,35 @@
+package rawsql
+
+import "strings"
+
+// TableStats summarizes a parsed table for dashboards and reports
+type TableStats struct {
+	Columns     int
+	Indexes     int // as in Table.Indexes, the primary key and the indexes of foreign keys included
+	ForeignKeys int
+	RowWidth    int64 // estimated maximum row size in bytes, as RowSizeLimits checks it
+	PrimaryKey  bool
+	Timestamps  bool // has the created_at and updated_at columns gorm fills in
+}
+
+// Stats returns the TableStats of table
+func Stats(table *Table) TableStats {
+	stats := TableStats{
+		Columns:     len(table.ColumnTypes),
+		Indexes:     len(table.Indexes),
+		ForeignKeys: len(table.ForeignKeys),
+		RowWidth:    rowWidth(table),
+		PrimaryKey:  len(table.PrimaryKeyColumns()) > 0,
+	}
+	var created, updated bool
+	for _, ct := range table.ColumnTypes {
+		switch strings.ToLower(ct.Name()) {
+		case "created_at":
+			created = true
+		case "updated_at":
+			updated = true
+		}
+	}
+	stats.Timestamps = created && updated
+	return stats
+}
@@ -0,0 +1,276 @@
+package rawsql
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// stmtScanner splits sql read from a reader into single statements, keeping
+// only the statement being scanned in memory. It understands quoted strings
+// and identifiers, line and block comments, and mysqldump style DELIMITER lines.
+type stmtScanner struct {
+	r         *bufio.Reader
+	delimiter string
+	buf       bytes.Buffer
+	err       error
+}
+
+func newStmtScanner(r io.Reader) *stmtScanner {
+	return &stmtScanner{r: bufio.NewReaderSize(r, 64*1024), delimiter: ";"}
+}
+
+// Next returns the next non empty statement without its delimiter, io.EOF
+// once the reader is exhausted
+func (s *stmtScanner) Next() (string, error) {
+	for {
+		stmt, err := s.scan()
+		if strings.TrimSpace(stmt) != "" {
+			return strings.TrimSpace(stmt), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func (s *stmtScanner) scan() (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.buf.Reset()
+	lineStart := true
+	for {
+		if lineStart && strings.TrimSpace(s.buf.String()) == "" {
+			if ok, err := s.delimiterLine(); err != nil {
+				return s.fail(err)
+			} else if ok {
+				s.buf.Reset()
+				continue
+			}
+		}
+
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return s.fail(err)
+		}
+		lineStart = c == '\n'
+
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			s.buf.WriteByte(c)
+			if err = s.quoted(c); err != nil {
+				return s.fail(err)
+			}
+		case c == '#':
+			s.buf.WriteByte(c)
+			if err = s.lineComment(); err != nil {
+				return s.fail(err)
+			}
+			lineStart = true
+		case c == '-' && s.peekIs("-"):
+			next, _ := s.r.Peek(2)
+			if len(next) == 1 || next[1] == ' ' || next[1] == '\t' || next[1] == '\n' || next[1] == '\r' {
+				s.buf.WriteByte(c)
+				if err = s.lineComment(); err != nil {
+					return s.fail(err)
+				}
+				lineStart = true
+			} else {
+				s.buf.WriteByte(c)
+			}
+		case c == '/' && s.peekIs("*"):
+			s.buf.WriteByte(c)
+			if err = s.blockComment(); err != nil {
+				return s.fail(err)
+			}
+		case c == s.delimiter[0] && s.peekIs(s.delimiter[1:]):
+			s.r.Discard(len(s.delimiter) - 1)
+			return s.buf.String(), nil
+		default:
+			s.buf.WriteByte(c)
+		}
+	}
+}
+
+func (s *stmtScanner) fail(err error) (string, error) {
+	s.err = err
+	return s.buf.String(), err
+}
+
+func (s *stmtScanner) peekIs(str string) bool {
+	if str == "" {
+		return true
+	}
+	next, _ := s.r.Peek(len(str))
+	return string(next) == str
+}
+
+// delimiterLine consumes a DELIMITER line at the current position, if any
+func (s *stmtScanner) delimiterLine() (bool, error) {
+	const keyword = "DELIMITER"
+	next, _ := s.r.Peek(len(keyword) + 1)
+	if len(next) <= len(keyword) || !strings.EqualFold(string(next[:len(keyword)]), keyword) ||
+		(next[len(keyword)] != ' ' && next[len(keyword)] != '\t') {
+		return false, nil
+	}
+	line, err := s.r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if d := strings.TrimSpace(line[len(keyword):]); d != "" {
+		s.delimiter = d
+	}
+	return true, nil
+}
+
+func (s *stmtScanner) quoted(quote byte) error {
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		s.buf.WriteByte(c)
+		switch {
+		case c == '\\' && quote != '`':
+			c, err = s.r.ReadByte()
+			if err != nil {
+				return err
+			}
+			s.buf.WriteByte(c)
+		case c == quote:
+			if !s.peekIs(string(quote)) {
+				return nil
+			}
+			c, _ = s.r.ReadByte()
+			s.buf.WriteByte(c)
+		}
+	}
+}
+
+func (s *stmtScanner) lineComment() error {
+	line, err := s.r.ReadString('\n')
+	s.buf.WriteString(line)
+	return err
+}
+
+func (s *stmtScanner) blockComment() error {
+	c, _ := s.r.ReadByte() // the opening '*'
+	s.buf.WriteByte(c)
+	prev := byte(0)
+	for {
+		c, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		s.buf.WriteByte(c)
+		if prev == '*' && c == '/' {
+			return nil
+		}
+		prev = c
+	}
+}
+
+// ParseReader feeds the sql read from r to p one statement at a time, so dumps
+// far larger than memory can be parsed
+func ParseReader(p Parser, r io.Reader) error {
+	_, err := parseReader(p, r, false)
+	return err
+}
+
+// parseReader is ParseReader optionally dropping DML, it returns how many
+// statements were dropped
+func parseReader(p Parser, r io.Reader, skipDML bool) (dropped int, err error) {
+	// the whole reader is one sql content for Config.BaselineLock
+	if d, ok := p.(*defaultParser); ok && !d.streaming {
+		d.streaming = true
+		defer func() {
+			d.streaming = false
+			d.lockBaseline()
+		}()
+	}
+	scanner := newStmtScanner(r)
+	for {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			return dropped, nil
+		}
+		if err != nil {
+			return dropped, err
+		}
+		if skipDML && isDML(stmt) {
+			dropped++
+			continue
+		}
+		if err = p.ParseSQL(stmt); err != nil {
+			return dropped, err
+		}
+	}
+}
+
+var dmlKeywords = []string{"INSERT", "REPLACE", "UPDATE", "DELETE"}
+
+// isDML reports whether stmt starts with a data manipulation keyword, looking
+// past leading comments but never into the statement itself
+func isDML(stmt string) bool {
+	rest := skipComments(stmt)
+	for _, kw := range dmlKeywords {
+		if len(rest) > len(kw) && strings.EqualFold(rest[:len(kw)], kw) && !isIdentByte(rest[len(kw)]) {
+			return true
+		}
+	}
+	return false
+}
+
+func skipComments(stmt string) string {
+	for {
+		stmt = strings.TrimLeft(stmt, " \t\r\n")
+		switch {
+		case strings.HasPrefix(stmt, "#"), strings.HasPrefix(stmt, "--") && (len(stmt) == 2 || strings.ContainsRune(" \t\r\n", rune(stmt[2]))):
+			i := strings.IndexByte(stmt, '\n')
+			if i < 0 {
+				return ""
+			}
+			stmt = stmt[i+1:]
+		case strings.HasPrefix(stmt, "/*") && !strings.HasPrefix(stmt, "/*!"):
+			i := strings.Index(stmt[2:], "*/")
+			if i < 0 {
+				return ""
+			}
+			stmt = stmt[i+4:]
+		default:
+			return stmt
+		}
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// filterDML drops the DML statements of sql, which is returned unchanged when
+// there is nothing to drop
+func filterDML(sql string) (string, int, error) {
+	scanner := newStmtScanner(strings.NewReader(sql))
+	kept := make([]string, 0)
+	dropped := 0
+	for {
+		stmt, err := scanner.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		if isDML(stmt) {
+			dropped++
+			continue
+		}
+		kept = append(kept, stmt)
+	}
+	if dropped == 0 {
+		return sql, 0, nil
+	}
+	return strings.Join(kept, ";\n"), dropped, nil
+}
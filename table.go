@@ -1,10 +1,12 @@
 package rawsql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pingcap/tidb/pkg/parser"
@@ -14,131 +16,434 @@ import (
 	"github.com/pingcap/tidb/pkg/parser/types"
 	"gorm.io/gorm"
 	"gorm.io/gorm/migrator"
+	"gorm.io/rawsql/meta"
 )
 
-type Table struct {
-	ColumnTypes []gorm.ColumnType
-	Indexes     []gorm.Index
-	Name        string
-	Comment     string
-}
-
+// Parser turns sql into tables: ParseSQL is called for every sql content in
+// order and GetTables returns the tables built so far, the built-in Parser
+// returns copies the caller may change. Parsers are given in
+// Config.Parser or created by the Backend named in Config.Backend, and may
+// implement ReportingParser to fill the ParseReport.
 type Parser interface {
 	ParseSQL(sql string) error
 	GetTables() map[string]*Table
 }
 
 type defaultParser struct {
-	tables map[string]*Table
+	tables   map[string]*Table
+	config   *Config
+	pool     *sync.Pool
+	strings  map[string]string
+	stats    ParseStats
+	warnings []Warning
+
+	foreignKeyChecksOff bool                         // SET FOREIGN_KEY_CHECKS = 0 is in effect
+	transaction         int                          // number of the open transaction block, 0 outside one
+	transactions        int                          // transaction blocks begun so far
+	temporary           map[string]struct{}          // temporary tables skipped by Config.SkipTemporaryTables
+	national            map[string]bool              // columns of the current statement declared NCHAR or NVARCHAR
+	spatial             map[string]spatialColumn     // columns of the current statement declared with a spatial type
+	vector              map[string]int64             // dimensions of the columns of the current statement declared with a VECTOR type
+	directives          map[string]map[string]string // directives of the columns of the current statement, see stmtDirectives
+	baseline            map[string]struct{}          // tables of the first sql content under Config.BaselineLock, nil until it was applied
+	streaming           bool                         // a reader is fed one statement at a time, the baseline locks at its end rather than after each statement
+	spanContext         context.Context              // of the rawsql.parse span the statements are applied in, nil outside one
+}
+
+func newDefaultParse(config *Config) Parser {
+	if config == nil {
+		config = &Config{}
+	}
+	d := &defaultParser{
+		tables:    make(map[string]*Table),
+		config:    config,
+		pool:      newTiDBPool(config),
+		strings:   make(map[string]string),
+		temporary: make(map[string]struct{}),
+	}
+	d.seed(config.Tables)
+	return d
+}
+
+// seed adds copies of the predefined tables of Config.Tables, which the sql
+// can alter and reference as if it had created them
+func (d *defaultParser) seed(tables []*Table) {
+	for _, table := range tables {
+		c := table.Clone()
+		c.Name, c.Alterer = d.intern(c.Name), ApplyAlter
+		d.tables[c.Name] = c
+	}
 }
 
-func newDefaultParse() Parser {
-	return &defaultParser{tables: make(map[string]*Table)}
+// newTiDBPool pools the tidb parsers configured by config
+func newTiDBPool(config *Config) *sync.Pool {
+	return &sync.Pool{New: func() interface{} {
+		var p *parser.Parser
+		if config.NewTiDBParser != nil {
+			p = config.NewTiDBParser()
+		} else {
+			p = parser.New()
+		}
+		if config.SQLMode != "" {
+			// validated by Initialize
+			mode, _ := mysql.GetSQLMode(config.SQLMode)
+			p.SetSQLMode(mode)
+		}
+		return p
+	}}
+}
+
+// useConfig makes a parser created for another Config, e.g. by ImportFromDB,
+// parse with the options of config
+func (d *defaultParser) useConfig(config *Config) {
+	if d.config != config {
+		d.config, d.pool = config, newTiDBPool(config)
+		d.seed(config.Tables)
+	}
+}
+
+// intern returns a shared copy of s, so the parsed tables never keep the sql
+// the identifiers and literals were sliced from alive
+func (d *defaultParser) intern(s string) string {
+	if s == "" {
+		return ""
+	}
+	if v, ok := d.strings[s]; ok {
+		return v
+	}
+	v := string([]byte(s))
+	d.strings[v] = v
+	return v
 }
 
+// GetTables returns deep copies of the tables, changing them leaves the parser
+// and the tables returned before untouched
 func (d *defaultParser) GetTables() map[string]*Table {
-	return d.tables
+	tables := make(map[string]*Table, len(d.tables))
+	for name, table := range d.tables {
+		tables[name] = table.Clone()
+	}
+	return tables
 }
 
 func (d *defaultParser) ParseSQL(sql string) error {
-	p := parser.New()
-	stmtNodes, _, err := p.Parse(sql, "", "")
+	stmtNodes, err := d.parseStmts(sql)
 	if err != nil {
 		return err
 	}
 
+	return d.applyStmts(stmtNodes)
+}
+
+// parseStmts parses sql with a pooled tidb parser, it is safe for concurrent use
+func (d *defaultParser) parseStmts(sql string) ([]ast.StmtNode, error) {
+	sql = rewriteVector(rewriteSpatial(rewriteInvisible(sql)))
+	p := d.pool.Get().(*parser.Parser)
+	defer d.pool.Put(p)
+	segments := directiveSegments(sql)
+	if segments == nil {
+		return d.parseText(p, sql)
+	}
+	var stmtNodes []ast.StmtNode
+	for _, seg := range segments {
+		if seg.ignored {
+			stmtNodes = append(stmtNodes, ignoredStmts(seg)...)
+			continue
+		}
+		// padded so parse errors tell the lines of sql
+		nodes, err := d.parseText(p, strings.Repeat("\n", seg.line-1)+seg.text)
+		if err != nil {
+			return nil, err
+		}
+		stmtNodes = append(stmtNodes, nodes...)
+	}
+	return stmtNodes, nil
+}
+
+func (d *defaultParser) parseText(p *parser.Parser, sql string) ([]ast.StmtNode, error) {
+	stmtNodes, _, err := p.Parse(sql, d.config.ParseCharset, d.config.ParseCollation)
+	// the parser reuses its result slice on the next Parse call
+	stmtNodes = append([]ast.StmtNode(nil), stmtNodes...)
+	if err != nil && d.config.RecoverSyntaxErrors {
+		stmtNodes, err = d.parseEach(p, sql), nil
+	}
+	return stmtNodes, err
+}
+
+func (d *defaultParser) applyStmts(stmtNodes []ast.StmtNode) (err error) {
+	warnings := len(d.warnings)
+	var span Span = noopSpan{}
+	defer func() { endSpan(span, err) }()
 	for _, node := range stmtNodes {
+		span.End()
+		span = d.startStatementSpan(node)
+		d.stats.Statements++
+		if failed, ok := node.(*failedStmt); ok {
+			d.applyFailed(failed)
+			continue
+		}
+		if _, ok := node.(*ignoredStmt); ok {
+			d.skip(node, "rawsql:ignore directive")
+			continue
+		}
+		if d.config.OnStatement != nil && !d.config.OnStatement(node, stmtText(node)) {
+			d.skip(node, "OnStatement")
+			continue
+		}
 		switch node.(type) {
 		case *ast.CreateTableStmt:
 			create := node.(*ast.CreateTableStmt)
+			d.national = nationalColumns(create.Text())
+			d.spatial = spatialColumns(create.Text())
+			d.vector = vectorColumns(create.Text())
+			directives := d.applyDirectives(node)
 
-			tableName := create.Table.Name.String()
+			tableName := d.intern(create.Table.Name.String())
 
-			if _, has := d.tables[tableName]; has {
-				panic(fmt.Sprintf("duplicated table %s", tableName))
+			if create.TemporaryKeyword != ast.TemporaryNone && d.config.SkipTemporaryTables {
+				d.temporary[tableName] = struct{}{}
+				d.skip(node, "temporary table")
+				continue
 			}
+			if d.isGhostTable(tableName) {
+				d.skip(node, "ghost table")
+				continue
+			}
+			d.checkCompatibility(node)
 
-			d.tables[tableName] = &Table{
-				Name:        tableName,
-				Comment:     getTableComment(create),
-				ColumnTypes: d.getColumnTypes(create),
-				Indexes:     d.getIndexes(create),
+			if existing, has := d.tables[tableName]; has {
+				if _, ok := d.baseline[tableName]; ok {
+					return d.baselineError(node, existing, d.newTable(tableName, create))
+				}
+				panic(fmt.Sprintf("duplicated table %s", tableName))
 			}
+
+			table := d.newTable(tableName, create)
+			table.Directives = directives
+			d.applyTypeMappings(table)
+			d.applyNullable(table)
+			d.applyMetadata(table)
+			d.checkForeignKeys(node, table)
+			d.tables[tableName] = table
+			d.stats.Tables++
+			d.stats.Columns += len(table.ColumnTypes)
+			d.stats.Indexes += len(table.Indexes)
 		case *ast.AlterTableStmt:
 			alter := node.(*ast.AlterTableStmt)
+			d.national = nationalColumns(alter.Text())
+			d.spatial = spatialColumns(alter.Text())
+			d.vector = vectorColumns(alter.Text())
+			directives := d.applyDirectives(node)
 
 			tableName := alter.Table.Name.String()
 
+			// a temporary table shadows the table of the same name
+			if _, ok := d.temporary[tableName]; ok {
+				d.skip(node, "temporary table")
+				continue
+			}
+			if d.isGhostTable(tableName) {
+				d.skip(node, "ghost table")
+				continue
+			}
+			d.checkCompatibility(node)
+
 			table, has := d.tables[tableName]
 			if !has {
 				panic(fmt.Sprintf("table %s not exists", tableName))
 			}
+			table.RawSQL += "\n" + stmtText(node) + ";"
+			for name, value := range directives {
+				if table.Directives == nil {
+					table.Directives = make(map[string]string)
+				}
+				table.Directives[name] = value
+			}
 
+			var hint AlterHint
 			for _, spec := range alter.Specs {
-				if spec.OldColumnName != nil {
-					cols := table.ColumnTypes
-					for i, v := range cols {
-						if v.Name() == spec.OldColumnName.Name.String() {
-							table.ColumnTypes = append(cols[:i], cols[i+1:]...)
-						}
-					}
+				switch spec.Tp {
+				case ast.AlterTableAddColumns, ast.AlterTableModifyColumn, ast.AlterTableChangeColumn:
+				case ast.AlterTableOption:
+					d.alterTableOptions(table, spec)
+					continue
+				case ast.AlterTableAttributes:
+					d.alterAttributes(table, spec.AttributesSpec)
+					continue
+				case ast.AlterTableRenameColumn:
+					d.alterRenameColumn(node, table, spec)
+					continue
+				case ast.AlterTableDropColumn:
+					d.alterDropColumn(node, table, spec)
+					continue
+				case ast.AlterTableRenameTable:
+					d.renameTable(table.Name, d.intern(spec.NewTable.Name.String()))
+					continue
+				case ast.AlterTableAlgorithm:
+					hint.Algorithm = spec.Algorithm.String()
+					continue
+				case ast.AlterTableLock:
+					hint.Lock = spec.LockType.String()
+					continue
+				default:
+					d.warn(node, fmt.Sprintf("unsupported alter table clause %s", alterSpecText(spec)))
+					continue
 				}
 
-				for _, v := range spec.NewColumns {
-					ct := d.getColumnType(v)
+				if spec.Tp == ast.AlterTableAddColumns {
+					d.stats.Columns += len(spec.NewColumns)
+				}
+				d.alterColumns(table, spec)
+			}
+			meta.NumberColumns(table.ColumnTypes)
+			d.applyTypeMappings(table)
+			d.applyNullable(table)
+			d.applyMetadata(table)
+			if hint != (AlterHint{}) {
+				table.AlterHints = append(table.AlterHints, hint)
+			}
+			d.logApplied(node)
+		case *ast.DropTableStmt:
+			d.applyDrop(node.(*ast.DropTableStmt))
+		case *ast.RenameTableStmt:
+			d.applyRenameTable(node.(*ast.RenameTableStmt))
+		case *ast.SetStmt:
+			d.skip(node, "SET statement")
+			d.applySet(node.(*ast.SetStmt))
+		case *ast.BeginStmt, *ast.CommitStmt, *ast.RollbackStmt:
+			d.skip(node, "transaction statement")
+			d.applyTransaction(node)
+		case *ast.CreatePlacementPolicyStmt, *ast.AlterPlacementPolicyStmt, *ast.DropPlacementPolicyStmt:
+			// tables only record the name of their policy
+			d.skip(node, "placement policy")
+		case *ast.CreateResourceGroupStmt, *ast.AlterResourceGroupStmt, *ast.DropResourceGroupStmt:
+			d.skip(node, "resource group")
+		default:
+			d.stats.Skipped++
+			d.warn(node, "unsupported statement")
+		}
+	}
+	span.End()
+	span = noopSpan{}
 
-					for i, existColumn := range table.ColumnTypes {
-						if existColumn.Name() == v.Name.String() {
-							// remove duplicate column
-							table.ColumnTypes = append(
-								table.ColumnTypes[:i],
-								table.ColumnTypes[i+1:]...,
-							)
+	if !d.streaming {
+		d.lockBaseline()
+	}
 
-							break
-						}
-					}
+	if d.config.Strict {
+		return strictError(d.warnings[warnings:])
+	}
+	return nil
+}
 
-					position := -1
-					if spec.Position != nil {
-						switch spec.Position.Tp {
-						case ast.ColumnPositionFirst:
-							position = 0
-						case ast.ColumnPositionAfter:
-							for i, existColumn := range table.ColumnTypes {
-								if existColumn.Name() == spec.Position.RelativeColumn.Name.String() {
-									position = i + 1
-								}
-							}
-						}
-					}
+// newTable builds the table named name of a CREATE TABLE
+func (d *defaultParser) newTable(name string, create *ast.CreateTableStmt) *Table {
+	table := &Table{
+		Name:        name,
+		Comment:     d.intern(getTableComment(create)),
+		ColumnTypes: d.getColumnTypes(create),
+		Indexes:     d.getIndexes(create),
+		ForeignKeys: d.getForeignKeys(create),
+		RawSQL:      stmtText(create) + ";",
+		Alterer:     ApplyAlter,
+	}
+	charset, collation := tableCharset(create.Options)
+	table.Charset, table.Collation = d.intern(charset), d.intern(collation)
+	d.applyTableOptions(table, create.Options)
+	for _, col := range table.ColumnTypes {
+		d.inheritCharset(table, col.(*ColumnType))
+	}
+	return table
+}
 
-					if position > 0 {
-						table.ColumnTypes = append(
-							table.ColumnTypes[:position],
-							append(
-								[]gorm.ColumnType{ct}, table.ColumnTypes[position:]...,
-							)...,
-						)
-					} else {
-						table.ColumnTypes = append(table.ColumnTypes, ct)
-					}
-				}
+// alterColumns applies an ADD, MODIFY or CHANGE COLUMN clause the way MySQL
+// orders columns: added columns go last, modified ones keep their place, and
+// FIRST or AFTER moves them
+func (d *defaultParser) alterColumns(table *Table, spec *ast.AlterTableSpec) {
+	for _, v := range spec.NewColumns {
+		ct := d.getColumnType(v)
+		d.inheritCharset(table, ct.(*ColumnType))
+		table.ForeignKeys = d.columnForeignKeys(table.ForeignKeys, table.Name, v)
+
+		position := len(table.ColumnTypes)
+		old := v.Name.Name.O
+		if spec.OldColumnName != nil {
+			old = spec.OldColumnName.Name.O
+		}
+		if i := columnIndex(table.ColumnTypes, old); i >= 0 {
+			// directives describe the model rather than the column definition
+			if previous, ok := table.ColumnTypes[i].(*ColumnType); ok && ct.(*ColumnType).DirectivesValue == nil && spec.Tp != ast.AlterTableAddColumns {
+				ct.(*ColumnType).DirectivesValue = previous.DirectivesValue
 			}
-		case *ast.DropTableStmt:
-			drop := node.(*ast.DropTableStmt)
+			if spec.Tp == ast.AlterTableChangeColumn {
+				d.renameColumn(table, table.ColumnTypes[i].Name(), ct.Name())
+			}
+			table.ColumnTypes = append(table.ColumnTypes[:i], table.ColumnTypes[i+1:]...)
+			if spec.Tp != ast.AlterTableAddColumns {
+				position = i
+			} else {
+				position = len(table.ColumnTypes)
+			}
+		}
 
-			for _, table := range drop.Tables {
-				if _, has := d.tables[table.Name.String()]; !has && !drop.IfExists {
-					panic(fmt.Sprintf("table %s not exists", table.Name.String()))
+		if spec.Position != nil {
+			switch spec.Position.Tp {
+			case ast.ColumnPositionFirst:
+				position = 0
+			case ast.ColumnPositionAfter:
+				if i := columnIndex(table.ColumnTypes, spec.Position.RelativeColumn.Name.O); i >= 0 {
+					position = i + 1
+				} else {
+					position = len(table.ColumnTypes)
 				}
-
-				delete(d.tables, table.Name.String())
 			}
 		}
+
+		table.ColumnTypes = append(table.ColumnTypes, nil)
+		copy(table.ColumnTypes[position+1:], table.ColumnTypes[position:])
+		table.ColumnTypes[position] = ct
 	}
+}
 
-	return nil
+// alterDropColumn removes the column of ALTER TABLE ... DROP COLUMN, a
+// column the table does not have is reported unless IF EXISTS is given
+func (d *defaultParser) alterDropColumn(node ast.StmtNode, table *Table, spec *ast.AlterTableSpec) {
+	name := spec.OldColumnName.Name.O
+	i := columnIndex(table.ColumnTypes, name)
+	if i < 0 {
+		if !spec.IfExists {
+			d.warn(node, fmt.Sprintf("column %s of table %s not exists", name, table.Name))
+		}
+		return
+	}
+	table.ColumnTypes = append(table.ColumnTypes[:i], table.ColumnTypes[i+1:]...)
+}
+
+// columnIndex returns the position of the column named name, -1 if there is none
+func columnIndex(cols []gorm.ColumnType, name string) int {
+	for i, col := range cols {
+		if strings.EqualFold(col.Name(), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// stmtText copies the original text of node without its delimiter, the text
+// otherwise references the whole sql it was parsed from
+func stmtText(node ast.StmtNode) string {
+	text := strings.TrimRight(strings.TrimSpace(restoreText(node.Text())), "; \t\r\n")
+	return string([]byte(text))
+}
+
+func (d *defaultParser) warn(node ast.StmtNode, message string) {
+	d.warnings = append(d.warnings, Warning{Statement: stmtSummary(node), Message: message, Transaction: d.transaction})
+	d.logWarning(d.warnings[len(d.warnings)-1])
+}
+
+func (d *defaultParser) notice(node ast.StmtNode, message string) {
+	d.warnings = append(d.warnings, Warning{Statement: stmtSummary(node), Message: message, Notice: true, Transaction: d.transaction})
+	d.logWarning(d.warnings[len(d.warnings)-1])
 }
 
 func getTableComment(create *ast.CreateTableStmt) string {
@@ -175,28 +480,30 @@ func (d *defaultParser) getColumnTypes(create *ast.CreateTableStmt) (cols []gorm
 		if primaryConstraint != nil {
 			for _, pk := range primaryConstraint.Keys {
 				if pk.Column.Name.String() == ct.Name() {
-					ct.(*migrator.ColumnType).PrimaryKeyValue = sql.NullBool{
+					ct.(*ColumnType).PrimaryKeyValue = sql.NullBool{
 						Bool:  true,
 						Valid: true,
 					}
+					ct.(*ColumnType).NullableValue.Bool = false
 				}
 			}
 		}
 
 		cols = append(cols, ct)
 	}
+	meta.NumberColumns(cols)
 
 	return cols
 }
 
-func (*defaultParser) getColumnType(col *ast.ColumnDef) gorm.ColumnType {
-	ct := &migrator.ColumnType{
-		NameValue: sql.NullString{Valid: true, String: col.Name.OrigColName()},
+func (d *defaultParser) getColumnType(col *ast.ColumnDef) gorm.ColumnType {
+	ct := meta.NewColumnType(migrator.ColumnType{
+		NameValue: sql.NullString{Valid: true, String: d.intern(col.Name.OrigColName())},
 		DataTypeValue: sql.NullString{
 			Valid:  true,
 			String: strings.ToLower(types.TypeToStr(col.Tp.GetType(), col.Tp.GetCharset())),
 		},
-		ColumnTypeValue: sql.NullString{Valid: true, String: strings.ToLower(col.Tp.String())},
+		ColumnTypeValue: sql.NullString{Valid: true, String: d.intern(columnTypeString(col.Tp))},
 		PrimaryKeyValue: sql.NullBool{
 			Bool:  mysql.HasPriKeyFlag(col.Tp.GetFlag()),
 			Valid: mysql.HasPriKeyFlag(col.Tp.GetFlag()),
@@ -208,37 +515,102 @@ func (*defaultParser) getColumnType(col *ast.ColumnDef) gorm.ColumnType {
 		LengthValue:      sql.NullInt64{Int64: int64(col.Tp.GetFlen()), Valid: col.Tp.IsVarLengthType()},
 		DecimalSizeValue: sql.NullInt64{Int64: int64(col.Tp.GetFlen()), Valid: col.Tp.IsDecimalValid()},
 		ScaleValue:       sql.NullInt64{Int64: int64(col.Tp.GetDecimal()), Valid: col.Tp.IsDecimalValid()},
-		NullableValue:    sql.NullBool{Bool: true, Valid: true},
+		NullableValue:    sql.NullBool{Bool: !mysql.HasNotNullFlag(col.Tp.GetFlag()), Valid: true},
 		SQLColumnType:    &sql.ColumnType{},
 		ScanTypeValue:    getType(col.Tp),
+	})
+	ct.DirectivesValue = d.directives[strings.ToLower(col.Name.Name.O)]
+	if dimension, ok := d.vector[strings.ToLower(col.Name.Name.O)]; ok {
+		ct.DataTypeValue.String, ct.ColumnTypeValue.String = "vector", d.intern(strings.ToLower(vectorType(dimension)))
+		ct.LengthValue = sql.NullInt64{}
+		ct.ScanTypeValue = d.config.vectorScanType()
+	} else if spatial, ok := d.spatial[strings.ToLower(col.Name.Name.O)]; ok {
+		ct.DataTypeValue.String, ct.ColumnTypeValue.String = spatial.dataType, spatial.dataType
+		ct.LengthValue = sql.NullInt64{}
+		ct.SRIDValue = spatial.srid
+	} else if length, ok := charLength(col.Tp); ok {
+		ct.CharLengthValue = sql.NullInt64{Int64: length, Valid: true}
+		if !charTypes[ct.DatabaseTypeName()] {
+			// binary strings count bytes
+			ct.OctetLengthValue = ct.CharLengthValue
+		}
+	}
+	if isNumeric(col.Tp) {
+		ct.UnsignedValue = sql.NullBool{Bool: mysql.HasUnsignedFlag(col.Tp.GetFlag()), Valid: true}
+	}
+	if charTypes[ct.DatabaseTypeName()] {
+		ct.CharsetValue.String = strings.ToLower(col.Tp.GetCharset())
+		ct.CollationValue.String = strings.ToLower(col.Tp.GetCollate())
+		if d.national[strings.ToLower(col.Name.Name.O)] {
+			ct.CharsetValue.String, ct.CollationValue.String = nationalCharset, nationalCollation
+		}
+		if mysql.HasBinaryFlag(col.Tp.GetFlag()) {
+			ct.BinaryValue = true
+		}
 	}
 	for _, opt := range col.Options {
+		// the last of NOT NULL and NULL wins, as in MySQL
 		if opt.Tp == ast.ColumnOptionNotNull {
 			ct.NullableValue.Bool = false
 			continue
 		}
+		if opt.Tp == ast.ColumnOptionNull {
+			ct.NullableValue.Bool = true
+			continue
+		}
 		if opt.Tp == ast.ColumnOptionComment {
+			if isInvisibleMarker(opt) {
+				ct.InvisibleValue = true
+				continue
+			}
 			ct.CommentValue = sql.NullString{
-				String: opt.Expr.(*test_driver.ValueExpr).Datum.GetString(),
+				String: d.intern(exprString(opt.Expr)),
 				Valid:  true,
 			}
 			continue
 		}
+		if opt.Tp == ast.ColumnOptionUniqKey {
+			ct.UniqueValue = sql.NullBool{Bool: true, Valid: true}
+			continue
+		}
+		if opt.Tp == ast.ColumnOptionCollate {
+			ct.CollationValue.String = strings.ToLower(opt.StrValue)
+			continue
+		}
+		if opt.Tp == ast.ColumnOptionColumnFormat {
+			ct.ColumnFormatValue = strings.ToUpper(opt.StrValue)
+			continue
+		}
+		if opt.Tp == ast.ColumnOptionStorage {
+			ct.StorageValue = strings.ToUpper(opt.StrValue)
+			continue
+		}
+		if opt.Tp == ast.ColumnOptionOnUpdate {
+			ct.OnUpdateValue = d.intern(nowString(opt.Expr))
+			continue
+		}
+		if opt.Tp == ast.ColumnOptionGenerated {
+			ct.GenerationExprValue = d.intern(exprString(opt.Expr))
+			ct.GeneratedStoredValue = opt.Stored
+			continue
+		}
 		if opt.Tp == ast.ColumnOptionAutoIncrement {
 			ct.AutoIncrementValue = sql.NullBool{Bool: true, Valid: true}
 			continue
 		}
 		if opt.Tp == ast.ColumnOptionDefaultValue {
-			if v, ok := opt.Expr.(*test_driver.ValueExpr); ok {
-				ct.DefaultValueValue = sql.NullString{
-					Valid: v.Datum.GetValue() != nil, String: fmt.Sprint(v.Datum.GetValue()),
-				}
+			if v, ok := opt.Expr.(ast.ValueExpr); ok && v.GetValue() == nil {
+				ct.DefaultValueValue = sql.NullString{}
 				continue
 			}
-
-			if v2, ok := opt.Expr.(*ast.FuncCallExpr); ok {
-				ct.DefaultValueValue = sql.NullString{Valid: true, String: v2.FnName.String()}
+			if value, kind, ok := constantDefault(opt.Expr, ct); ok {
+				ct.DefaultValueValue = sql.NullString{Valid: true, String: d.intern(value)}
+				ct.DefaultKindValue = kind
+				continue
 			}
+
+			ct.DefaultValueValue = sql.NullString{Valid: true, String: d.intern(defaultExpression(opt.Expr))}
+			ct.DefaultKindValue = meta.DefaultExpression
 		}
 
 		if opt.Tp == ast.ColumnOptionPrimaryKey {
@@ -248,36 +620,117 @@ func (*defaultParser) getColumnType(col *ast.ColumnDef) gorm.ColumnType {
 			}
 		}
 	}
+	// primary key columns are NOT NULL however they are declared
+	if pk, _ := ct.PrimaryKey(); pk {
+		ct.NullableValue.Bool = false
+	}
 
 	return ct
 }
 
+// defaultKind tells how the literal default value v of ct was written
+func defaultKind(v interface{}, ct *ColumnType) meta.DefaultKind {
+	switch v := v.(type) {
+	case int64:
+		if ct.ScanType() == boolT && (v == 0 || v == 1) {
+			return meta.DefaultBool
+		}
+		return meta.DefaultInt
+	case uint64:
+		return meta.DefaultUint
+	case float32, float64, *test_driver.MyDecimal:
+		return meta.DefaultFloat
+	case string:
+		return meta.DefaultString
+	}
+	return ""
+}
+
 func (d *defaultParser) getIndexes(create *ast.CreateTableStmt) []gorm.Index {
 	if create == nil || len(create.Constraints) == 0 {
 		return nil
 	}
 	indexs := make([]gorm.Index, 0, len(create.Constraints))
-	table := create.Table.Name.String()
+	table := d.intern(create.Table.Name.String())
+	symbols := constraintIndexNames(create.Text())
+	spatial := spatialIndexes(create.Text())
 	for _, cons := range create.Constraints {
-		idx := &migrator.Index{
-			TableName: table, NameValue: cons.Name, ColumnList: []string{},
+		idx := meta.NewIndex(migrator.Index{
+			TableName: table, NameValue: d.intern(cons.Name), ColumnList: []string{},
 			PrimaryKeyValue: sql.NullBool{
 				Bool:  ast.ConstraintPrimaryKey == cons.Tp,
 				Valid: ast.ConstraintPrimaryKey == cons.Tp,
 			},
 			UniqueValue: sql.NullBool{Bool: ast.ConstraintUniq == cons.Tp, Valid: ast.ConstraintUniq == cons.Tp},
+		})
+		if cons.Tp == ast.ConstraintFulltext {
+			idx.ClassValue = "FULLTEXT"
+		} else if spatial[strings.ToLower(cons.Name)] {
+			idx.ClassValue = "SPATIAL"
 		}
-		for _, col := range cons.Keys {
-			idx.ColumnList = append(idx.ColumnList, col.Column.Name.String())
+		if name, ok := symbols[strings.ToLower(cons.Name)]; ok && (cons.Tp == ast.ConstraintPrimaryKey || cons.Tp == ast.ConstraintUniq) {
+			idx.ConstraintValue = idx.NameValue
+			if name != "" && cons.Tp == ast.ConstraintUniq {
+				idx.NameValue = d.intern(name)
+			}
+		}
+		if option := cons.Option; option != nil {
+			idx.UsingValue = d.intern(option.Tp.String())
+			idx.CommentValue = d.intern(option.Comment)
+			idx.KeyBlockSizeValue = option.KeyBlockSize
+			idx.ParserValue = d.intern(option.ParserName.O)
+		}
+		for i, col := range cons.Keys {
+			idx.ColumnList = append(idx.ColumnList, d.intern(col.Column.Name.String()))
+			if col.Length > 0 {
+				if idx.PrefixValue == nil {
+					idx.PrefixValue = make([]int, len(cons.Keys))
+				}
+				idx.PrefixValue[i] = col.Length
+			}
 		}
 		indexs = append(indexs, idx)
 	}
 	return indexs
 }
 
+// constraintIndexNames maps the lower cased symbols of the CONSTRAINT clauses
+// of primary keys and unique keys in text to the index name following UNIQUE
+// KEY, empty when there is none. The tidb parser keeps only the symbol, while
+// MySQL names the index after it only when no index name is given.
+func constraintIndexNames(text string) map[string]string {
+	if !containsFold(text, "CONSTRAINT") {
+		return nil
+	}
+	names := make(map[string]string)
+	tokens := sqlTokens(text)
+	for i := 0; i+2 < len(tokens); i++ {
+		if !tokens[i].is("CONSTRAINT") || !tokens[i+1].isName() {
+			continue
+		}
+		symbol, j := strings.ToLower(tokens[i+1].text), i+2
+		switch {
+		case tokens[j].is("PRIMARY"):
+			names[symbol] = ""
+		case tokens[j].is("UNIQUE"):
+			if j++; j < len(tokens) && (tokens[j].is("KEY") || tokens[j].is("INDEX")) {
+				j++
+			}
+			if j < len(tokens) && tokens[j].isName() && !tokens[j].is("USING") {
+				names[symbol] = tokens[j].text
+			} else {
+				names[symbol] = ""
+			}
+		}
+	}
+	return names
+}
+
 var (
 	intT    = reflect.TypeOf(int32(0))
 	longT   = reflect.TypeOf(int64(0))
+	uintT   = reflect.TypeOf(uint32(0))
+	ulongT  = reflect.TypeOf(uint64(0))
 	boolT   = reflect.TypeOf(false)
 	stringT = reflect.TypeOf("")
 	floatT  = reflect.TypeOf(float32(0))
@@ -285,18 +738,39 @@ var (
 	timeT   = reflect.TypeOf(time.Time{})
 )
 
+func isNumeric(tp *types.FieldType) bool {
+	switch tp.EvalType() {
+	case types.ETInt, types.ETReal, types.ETDecimal:
+		return tp.GetType() != mysql.TypeBit && tp.GetType() != mysql.TypeYear
+	}
+	return false
+}
+
 func getType(tp *types.FieldType) reflect.Type {
 	if tp == nil {
 		return nil
 	}
+	unsigned := mysql.HasUnsignedFlag(tp.GetFlag())
 	switch tp.GetType() {
 	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeLong:
+		if tp.GetType() == mysql.TypeTiny && tp.GetFlen() == 1 {
+			// BOOL and BOOLEAN are aliases of TINYINT(1)
+			return boolT
+		}
+		if unsigned {
+			return uintT
+		}
 		return intT
 	case mysql.TypeFloat:
 		return floatT
 	case mysql.TypeDouble:
 		return doubleT
-	case mysql.TypeTimestamp, mysql.TypeLonglong, mysql.TypeInt24:
+	case mysql.TypeLonglong, mysql.TypeInt24:
+		if unsigned {
+			return ulongT
+		}
+		return longT
+	case mysql.TypeTimestamp:
 		return longT
 	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeNewDate:
 		return timeT
@@ -0,0 +1,84 @@
+package rawsql
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// GormTag returns the gorm struct tag of a parsed column, e.g.
+// column:id;type:bigint(20) unsigned;primaryKey;autoIncrement, so that a model
+// generated from it migrates back to the same column
+func GormTag(ct gorm.ColumnType) string {
+	settings := []string{"column:" + tagValue(ct.Name())}
+	if columnType, ok := ct.ColumnType(); ok && columnType != "" {
+		settings = append(settings, "type:"+tagValue(columnType))
+	}
+	primaryKey, _ := ct.PrimaryKey()
+	if primaryKey {
+		settings = append(settings, "primaryKey")
+	}
+	if autoIncrement, _ := ct.AutoIncrement(); autoIncrement {
+		settings = append(settings, "autoIncrement")
+	}
+	if nullable, ok := ct.Nullable(); ok && !nullable && !primaryKey {
+		settings = append(settings, "not null")
+	}
+	if unique, _ := ct.Unique(); unique && !primaryKey {
+		settings = append(settings, "unique")
+	}
+	if value, ok := ct.DefaultValue(); ok {
+		// gorm trims the quotes of string defaults
+		quoted := charTypes[ct.DatabaseTypeName()]
+		if c, ok := ct.(*ColumnType); ok && c.DefaultKind() != "" {
+			quoted = c.DefaultQuoted()
+		}
+		if quoted {
+			value = QuoteString(value)
+		}
+		settings = append(settings, "default:"+tagValue(value))
+	}
+	if comment, ok := ct.Comment(); ok && comment != "" {
+		settings = append(settings, "comment:"+tagValue(comment))
+	}
+	return strings.Join(settings, ";")
+}
+
+// UniqueIndexTags returns the gorm tag settings declaring the unique indexes
+// of table on their columns by column name, e.g.
+// uniqueIndex:uk_tenant_email,priority:2 on the second column of a composite
+// unique key, so a generated model migrates back to the same index rather than
+// to a unique column each
+func UniqueIndexTags(table *Table) map[string][]string {
+	tags := make(map[string][]string)
+	for _, idx := range table.Indexes {
+		if unique, _ := idx.Unique(); !unique || idx.Name() == "" {
+			continue
+		}
+		if pk, _ := idx.PrimaryKey(); pk {
+			continue
+		}
+		var lengths []int
+		if idx, ok := idx.(*Index); ok {
+			lengths = idx.PrefixLengths()
+		}
+		columns := idx.Columns()
+		for i, column := range columns {
+			setting := "uniqueIndex:" + tagValue(idx.Name())
+			if len(columns) > 1 {
+				setting += fmt.Sprintf(",priority:%d", i+1)
+			}
+			if i < len(lengths) && lengths[i] > 0 {
+				setting += fmt.Sprintf(",length:%d", lengths[i])
+			}
+			tags[column] = append(tags[column], setting)
+		}
+	}
+	return tags
+}
+
+// tagValue escapes the separators gorm splits tag settings on
+func tagValue(s string) string {
+	return strings.ReplaceAll(s, ";", `\;`)
+}
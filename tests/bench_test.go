@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/rawsql"
+)
+
+func generateSchema(tables int) string {
+	var b strings.Builder
+	for i := 0; i < tables; i++ {
+		fmt.Fprintf(&b, "CREATE TABLE `table_%d` (\n"+
+			"  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,\n"+
+			"  `name` varchar(255) DEFAULT NULL COMMENT 'name of the row',\n"+
+			"  `created_at` datetime(3) DEFAULT NULL,\n"+
+			"  PRIMARY KEY (`id`),\n"+
+			"  KEY `idx_name` (`name`)\n"+
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n", i)
+	}
+	return b.String()
+}
+
+func heapAlloc() uint64 {
+	var m runtime.MemStats
+	// pooled objects survive the first collection in the victim cache
+	runtime.GC()
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func TestRetainedMemoryIndependentOfDumpSize(t *testing.T) {
+	const padding = 32 << 20
+
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	dump := generateSchema(1) + "/* " + strings.Repeat("x", padding) + " */"
+	if err := os.WriteFile(path, []byte(dump), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dump = ""
+
+	before := heapAlloc()
+	db, err := gorm.Open(rawsql.New(rawsql.Config{FilePath: []string{path}}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	if retained := heapAlloc() - before; retained > padding/4 {
+		t.Fatalf("parsed schema retains %d bytes, the sql it was parsed from is still referenced", retained)
+	}
+	runtime.KeepAlive(db)
+}
+
+func BenchmarkParseSchema(b *testing.B) {
+	schema := generateSchema(200)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(schema)))
+	for i := 0; i < b.N; i++ {
+		if _, err := gorm.Open(rawsql.New(rawsql.Config{SQL: []string{schema}})); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseManyFiles(b *testing.B) {
+	files := make([]string, 200)
+	for i := range files {
+		files[i] = generateSchema(1)
+		files[i] = strings.Replace(files[i], "table_0", fmt.Sprintf("table_%d", i), 1)
+	}
+	for _, parallelism := range []int{1, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				config := rawsql.Config{SQL: append([]string(nil), files...), Parallelism: parallelism}
+				if _, err := gorm.Open(rawsql.New(config)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,1046 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"gorm.io/rawsql"
+	"gorm.io/rawsql/meta"
+)
+
+func TestColumnOrderAfterAlters(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint, `name` varchar(64), `age` int, `email` varchar(128));",
+		"ALTER TABLE `users` MODIFY COLUMN `name` varchar(255) NOT NULL;",
+		"ALTER TABLE `users` ADD COLUMN `created_at` datetime FIRST;",
+		"ALTER TABLE `users` CHANGE COLUMN `age` `years` int AFTER `email`;",
+		"ALTER TABLE `users` ADD COLUMN `nick` varchar(32) AFTER `id`, MODIFY `created_at` datetime AFTER `nick`;",
+		"ALTER TABLE `users` CHANGE `EMAIL` `mail` varchar(128);",
+	)
+
+	columns, err := db.Migrator().ColumnTypes("users")
+	if err != nil {
+		t.Fatalf("column types: %v", err)
+	}
+	var names []string
+	for i, column := range columns {
+		names = append(names, column.Name())
+		if position := column.(*rawsql.ColumnType).OrdinalPosition(); position != i+1 {
+			t.Errorf("column %s has ordinal position %d, want %d", column.Name(), position, i+1)
+		}
+	}
+	want := []string{"id", "nick", "created_at", "name", "mail", "years"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got columns %v, want %v", names, want)
+	}
+	if nullable, _ := columns[3].Nullable(); nullable {
+		t.Fatalf("expected the modified name column to be NOT NULL")
+	}
+}
+
+func TestChainedColumnPositions(t *testing.T) {
+	for _, c := range []struct {
+		alter string
+		want  []string
+	}{
+		{"ADD COLUMN `b` int AFTER `a`, ADD COLUMN `c` int AFTER `b`, ADD COLUMN `d` int AFTER `c`", []string{"a", "b", "c", "d", "z"}},
+		{"ADD COLUMN `c` int AFTER `a`, ADD COLUMN `b` int AFTER `a`", []string{"a", "b", "c", "z"}},
+		{"ADD COLUMN `x` int FIRST, ADD COLUMN `y` int AFTER `x`, MODIFY COLUMN `z` int AFTER `y`", []string{"x", "y", "z", "a"}},
+		{"MODIFY COLUMN `a` int AFTER `z`, ADD COLUMN `b` int AFTER `a`", []string{"z", "a", "b"}},
+		{"CHANGE COLUMN `a` `a2` int AFTER `z`, ADD COLUMN `b` int AFTER `a2`, ADD COLUMN `c` int FIRST", []string{"c", "z", "a2", "b"}},
+		{"MODIFY COLUMN `z` int FIRST, MODIFY COLUMN `a` int AFTER `z`", []string{"z", "a"}},
+	} {
+		table, err := rawsql.ParseTable("CREATE TABLE `t` (`a` int, `z` int);")
+		if err != nil {
+			t.Fatalf("failed to parse table, got error %v", err)
+		}
+		if err = table.ApplyAlter("ALTER TABLE `t` " + c.alter); err != nil {
+			t.Fatalf("failed to apply %q, got error %v", c.alter, err)
+		}
+		var names []string
+		for i, column := range table.ColumnTypes {
+			names = append(names, column.Name())
+			if position := column.(*rawsql.ColumnType).OrdinalPosition(); position != i+1 {
+				t.Errorf("%q: column %s has ordinal position %d, want %d", c.alter, column.Name(), position, i+1)
+			}
+		}
+		if !reflect.DeepEqual(names, c.want) {
+			t.Errorf("%q: got columns %v, want %v", c.alter, names, c.want)
+		}
+	}
+}
+
+func TestColumnCharset(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{SQL: []string{
+		"CREATE TABLE `users` (`id` int, `name` varchar(64), `bio` text CHARACTER SET latin1,\n" +
+			"  `code` varchar(8) COLLATE ascii_bin, `raw` varbinary(16)) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;",
+		"ALTER TABLE `users` ADD COLUMN `email` varchar(128);",
+		"CREATE TABLE `posts` (`title` varchar(64), `body` text) CHARSET latin1;",
+		"ALTER TABLE `posts` CONVERT TO CHARACTER SET utf8mb4 COLLATE utf8mb4_bin;",
+	}}).(*rawsql.Dialector)
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	users := dialector.Parser.GetTables()["users"]
+	if users.Charset != "utf8mb4" || users.Collation != "utf8mb4_unicode_ci" {
+		t.Fatalf("got table charset %s %s", users.Charset, users.Collation)
+	}
+	assertCharsets(t, db, "users", map[string][2]string{
+		"id":    {"", ""},
+		"name":  {"utf8mb4", "utf8mb4_unicode_ci"},
+		"bio":   {"latin1", ""},
+		"code":  {"ascii", "ascii_bin"},
+		"raw":   {"", ""},
+		"email": {"utf8mb4", "utf8mb4_unicode_ci"},
+	})
+	assertCharsets(t, db, "posts", map[string][2]string{
+		"title": {"utf8mb4", "utf8mb4_bin"},
+		"body":  {"utf8mb4", "utf8mb4_bin"},
+	})
+	if posts := dialector.Parser.GetTables()["posts"]; posts.Charset != "utf8mb4" || posts.Collation != "utf8mb4_bin" {
+		t.Fatalf("CONVERT TO did not change the table charset: %s %s", posts.Charset, posts.Collation)
+	}
+	if warnings := dialector.Report().Warnings; len(warnings) != 0 {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+}
+
+func assertCharsets(t *testing.T, db *gorm.DB, table string, want map[string][2]string) {
+	t.Helper()
+	columns, _ := db.Migrator().ColumnTypes(table)
+	for _, column := range columns {
+		ct := column.(*rawsql.ColumnType)
+		charset, _ := ct.Charset()
+		collation, _ := ct.Collation()
+		if got := [2]string{charset, collation}; got != want[ct.Name()] {
+			t.Errorf("%s.%s: got charset %v, want %v", table, ct.Name(), got, want[ct.Name()])
+		}
+	}
+}
+
+func TestTypeAliases(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `events` (`id` SERIAL, `active` BOOLEAN NOT NULL, `flag` BOOL, `count` int UNSIGNED, `delta` int);")
+	columns, _ := db.Migrator().ColumnTypes("events")
+	if len(columns) != 5 {
+		t.Fatalf("expected 5 columns, got %d", len(columns))
+	}
+
+	id := columns[0].(*rawsql.ColumnType)
+	if columnType, _ := id.ColumnType(); columnType != "bigint(20) unsigned" {
+		t.Errorf("SERIAL should expand to bigint unsigned, got %s", columnType)
+	}
+	if unsigned, _ := id.Unsigned(); !unsigned {
+		t.Errorf("SERIAL should be unsigned")
+	}
+	if autoIncrement, _ := id.AutoIncrement(); !autoIncrement {
+		t.Errorf("SERIAL should be auto increment")
+	}
+	if unique, _ := id.Unique(); !unique {
+		t.Errorf("SERIAL should be unique")
+	}
+	if nullable, _ := id.Nullable(); nullable {
+		t.Errorf("SERIAL should be NOT NULL")
+	}
+	if primaryKey, _ := id.PrimaryKey(); primaryKey {
+		t.Errorf("SERIAL does not imply a primary key")
+	}
+	if scanType := id.ScanType(); scanType != reflect.TypeOf(uint64(0)) {
+		t.Errorf("SERIAL should scan into uint64, got %v", scanType)
+	}
+
+	for _, column := range columns[1:3] {
+		if columnType, _ := column.ColumnType(); columnType != "tinyint(1)" {
+			t.Errorf("%s: BOOLEAN should expand to tinyint(1), got %s", column.Name(), columnType)
+		}
+		if scanType := column.ScanType(); scanType != reflect.TypeOf(false) {
+			t.Errorf("%s: BOOLEAN should scan into bool, got %v", column.Name(), scanType)
+		}
+	}
+	if scanType := columns[3].ScanType(); scanType != reflect.TypeOf(uint32(0)) {
+		t.Errorf("int unsigned should scan into uint32, got %v", scanType)
+	}
+	if unsigned, ok := columns[4].(*rawsql.ColumnType).Unsigned(); unsigned || !ok {
+		t.Errorf("int should be signed")
+	}
+}
+
+func TestNullability(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `t` (`a` int NOT NULL NULL, `b` int NULL NOT NULL, `c` int PRIMARY KEY, `d` SERIAL, "+
+			"`e` int SERIAL DEFAULT VALUE, `f` int, `g` int NULL);",
+		"CREATE TABLE `u` (`id` bigint, `tenant` int, PRIMARY KEY (`tenant`, `id`));",
+	)
+	want := map[string]bool{"a": true, "b": false, "c": false, "d": false, "e": false, "f": true, "g": true, "id": false, "tenant": false}
+	for _, table := range []string{"t", "u"} {
+		columns, _ := db.Migrator().ColumnTypes(table)
+		for _, column := range columns {
+			if nullable, ok := column.Nullable(); !ok || nullable != want[column.Name()] {
+				t.Errorf("%s.%s: got nullable %t", table, column.Name(), nullable)
+			}
+		}
+	}
+}
+
+func TestNationalAndBinaryStrings(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `names` (`a` NCHAR(3), `b` NVARCHAR(4), `c` NATIONAL CHARACTER VARYING(5),\n"+
+			"  `d` char(2) BINARY, `e` varchar(8) CHARACTER SET latin1 BINARY, `f` blob, `g` varbinary(3),\n"+
+			"  `h` int(10) unsigned zerofill, `comment` varchar(16) COMMENT 'not NCHAR') CHARSET=utf8mb4;",
+		"ALTER TABLE `names` ADD COLUMN `i` NATIONAL CHAR(1);",
+	)
+	columns, _ := db.Migrator().ColumnTypes("names")
+
+	want := map[string][4]string{
+		"a":       {"char", "char(3)", "utf8mb3", "utf8mb3_general_ci"},
+		"b":       {"varchar", "varchar(4)", "utf8mb3", "utf8mb3_general_ci"},
+		"c":       {"varchar", "varchar(5)", "utf8mb3", "utf8mb3_general_ci"},
+		"d":       {"char", "char(2)", "utf8mb4", "utf8mb4_bin"},
+		"e":       {"varchar", "varchar(8)", "latin1", "latin1_bin"},
+		"f":       {"blob", "blob", "", ""},
+		"g":       {"varbinary", "varbinary(3)", "", ""},
+		"h":       {"int", "int(10) unsigned zerofill", "", ""},
+		"comment": {"varchar", "varchar(16)", "utf8mb4", ""},
+		"i":       {"char", "char(1)", "utf8mb3", "utf8mb3_general_ci"},
+	}
+	if len(columns) != len(want) {
+		t.Fatalf("expected %d columns, got %d", len(want), len(columns))
+	}
+	for _, column := range columns {
+		ct := column.(*rawsql.ColumnType)
+		columnType, _ := ct.ColumnType()
+		charset, _ := ct.Charset()
+		collation, _ := ct.Collation()
+		if got := [4]string{ct.DatabaseTypeName(), columnType, charset, collation}; got != want[ct.Name()] {
+			t.Errorf("%s: got %v, want %v", ct.Name(), got, want[ct.Name()])
+		}
+	}
+
+	// BINARY stays with the column through snapshots and seeded tables, so a
+	// later CONVERT TO keeps it
+	tables := db.Dialector.(*rawsql.Dialector).Parser.GetTables()
+	var snapshot bytes.Buffer
+	if err := rawsql.EncodeBinarySnapshot(&snapshot, tables); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	decoded, err := rawsql.DecodeBinarySnapshot(&snapshot)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !decoded["names"].ColumnTypes[3].(*rawsql.ColumnType).Binary() {
+		t.Errorf("expected the BINARY attribute of d kept by the snapshot")
+	}
+	converted := openSQL(t, rawsql.Config{Tables: []*rawsql.Table{decoded["names"]}}, "ALTER TABLE `names` CONVERT TO CHARACTER SET latin1;")
+	columns, _ = converted.Migrator().ColumnTypes("names")
+	for i, want := range map[int][2]string{3: {"latin1", "latin1_bin"}, 8: {"latin1", ""}} {
+		ct := columns[i].(*rawsql.ColumnType)
+		charset, _ := ct.Charset()
+		collation, _ := ct.Collation()
+		if got := [2]string{charset, collation}; got != want {
+			t.Errorf("%s after CONVERT TO: got %v, want %v", ct.Name(), got, want)
+		}
+	}
+}
+
+func TestInvisibleColumns(t *testing.T) {
+	create := "CREATE TABLE `users` (`id` bigint, `secret` varchar(64) INVISIBLE COMMENT 'hidden', `shown` int VISIBLE,\n" +
+		"  KEY `idx_secret` (`secret`) INVISIBLE)"
+	dialector := rawsql.New(rawsql.Config{SQL: []string{
+		create + ";",
+		"ALTER TABLE `users` ADD COLUMN `token` char(8) /* api */ INVISIBLE AFTER `id`;",
+	}}).(*rawsql.Dialector)
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	columns, _ := db.Migrator().ColumnTypes("users")
+	want := map[string]bool{"id": false, "token": true, "secret": true, "shown": false}
+	if len(columns) != len(want) {
+		t.Fatalf("expected %d columns, got %d", len(want), len(columns))
+	}
+	for _, column := range columns {
+		if invisible := column.(*rawsql.ColumnType).Invisible(); invisible != want[column.Name()] {
+			t.Errorf("%s: got invisible %t", column.Name(), invisible)
+		}
+	}
+	if comment, _ := columns[2].Comment(); comment != "hidden" {
+		t.Errorf("the comment of an invisible column should be kept, got %q", comment)
+	}
+	if raw := dialector.Parser.GetTables()["users"].RawSQL; !strings.HasPrefix(raw, create+";") {
+		t.Errorf("RawSQL should keep the original text, got %q", raw)
+	}
+}
+
+func TestSpatialColumns(t *testing.T) {
+	create := "CREATE TABLE `places` (`id` bigint, `location` POINT NOT NULL SRID 4326, `area` polygon, SPATIAL KEY `idx_location` (`location`))"
+	dialector := rawsql.New(rawsql.Config{SQL: []string{
+		create + ";",
+		"ALTER TABLE `places` ADD COLUMN `route` linestring SRID 0 COMMENT 'path' AFTER `id`;",
+	}}).(*rawsql.Dialector)
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	columns, _ := db.Migrator().ColumnTypes("places")
+	var got []string
+	for _, column := range columns {
+		ct := column.(*rawsql.ColumnType)
+		columnType, _ := ct.ColumnType()
+		desc := column.Name() + " " + columnType
+		if srid, ok := ct.SRID(); ok {
+			desc += fmt.Sprintf(" srid %d", srid)
+		}
+		got = append(got, desc)
+	}
+	want := []string{"id bigint(20)", "route linestring srid 0", "location point srid 4326", "area polygon"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got columns %v, want %v", got, want)
+	}
+	if nullable, _ := columns[2].Nullable(); nullable {
+		t.Errorf("location should be NOT NULL")
+	}
+	if comment, _ := columns[1].Comment(); comment != "path" {
+		t.Errorf("got route comment %q", comment)
+	}
+	if idx := dialector.Parser.GetTables()["places"].Indexes[0].(*rawsql.Index); idx.Class() != "SPATIAL" {
+		t.Errorf("got index class %q", idx.Class())
+	}
+	if raw := dialector.Parser.GetTables()["places"].RawSQL; !strings.HasPrefix(raw, create+";") {
+		t.Errorf("RawSQL should keep the original text, got %q", raw)
+	}
+	exported := rawsql.CreateTableSQL(dialector.Parser.GetTables()["places"])
+	if !strings.Contains(exported, "`location` point SRID 4326 NOT NULL") || !strings.Contains(exported, "SPATIAL KEY `idx_location`") {
+		t.Errorf("unexpected export %s", exported)
+	}
+}
+
+func TestNDBColumnAttributes(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `t` (`a` int COLUMN_FORMAT DYNAMIC STORAGE MEMORY, `b` int storage disk column_format fixed, `c` int) ENGINE=ndbcluster;",
+		"ALTER TABLE `t` MODIFY `c` int STORAGE DISK;",
+	)
+	columns, _ := db.Migrator().ColumnTypes("t")
+	var got []string
+	for _, column := range columns {
+		ct := column.(*rawsql.ColumnType)
+		got = append(got, column.Name()+" "+ct.ColumnFormat()+"/"+ct.Storage())
+	}
+	if want := []string{"a DYNAMIC/MEMORY", "b FIXED/DISK", "c /DISK"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got columns %v, want %v", got, want)
+	}
+	exported := rawsql.CreateTableSQL(db.Dialector.(*rawsql.Dialector).Parser.GetTables()["t"])
+	if !strings.Contains(exported, "`a` int COLUMN_FORMAT DYNAMIC STORAGE MEMORY") {
+		t.Errorf("unexpected export %s", exported)
+	}
+}
+
+func TestColumnCommentForms(t *testing.T) {
+	db := openSQL(t, rawsql.Config{SQLMode: "NO_BACKSLASH_ESCAPES"},
+		"CREATE TABLE `t` (`a` int COMMENT \"double quoted\", `b` int COMMENT '', `c` int COMMENT 'C:\\path', `d` int)")
+	columns, _ := db.Migrator().ColumnTypes("t")
+	want := []struct {
+		comment string
+		ok      bool
+	}{{"double quoted", true}, {"", true}, {`C:\path`, true}, {"", false}}
+	for i, column := range columns {
+		if comment, ok := column.Comment(); comment != want[i].comment || ok != want[i].ok {
+			t.Errorf("%s: got comment %q %t, want %q %t", column.Name(), comment, ok, want[i].comment, want[i].ok)
+		}
+	}
+}
+
+func TestTypedDefaults(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `t` (`i` int DEFAULT 0, `s` varchar(8) DEFAULT '0', `n` int DEFAULT NULL, "+
+			"`u` bigint unsigned DEFAULT 18446744073709551615, `f` decimal(5,2) DEFAULT 1.50, `b` bool DEFAULT TRUE, "+
+			"`c` datetime DEFAULT CURRENT_TIMESTAMP, `x` int);",
+	)
+	want := map[string]interface{}{
+		"i": int64(0), "s": "0", "u": uint64(18446744073709551615), "f": 1.5, "b": true,
+		"c": rawsql.Expression("CURRENT_TIMESTAMP"),
+	}
+	columns, _ := db.Migrator().ColumnTypes("t")
+	for _, column := range columns {
+		value, ok := column.(*rawsql.ColumnType).Default()
+		if want, has := want[column.Name()]; ok != has || value != want {
+			t.Errorf("%s: got default %#v (%t), want %#v", column.Name(), value, ok, want)
+		}
+	}
+	if kind := columns[1].(*rawsql.ColumnType).DefaultKind(); kind != rawsql.DefaultString {
+		t.Errorf("got default kind %q", kind)
+	}
+}
+
+func TestQuotedDefaults(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `t` (`literal` varchar(32) DEFAULT 'CURRENT_TIMESTAMP', `keyword` datetime DEFAULT CURRENT_TIMESTAMP, `zero` varchar(8) DEFAULT 0);",
+	)
+	columns, _ := db.Migrator().ColumnTypes("t")
+	for i, want := range []bool{true, false, false} {
+		if quoted := columns[i].(*rawsql.ColumnType).DefaultQuoted(); quoted != want {
+			t.Errorf("%s: got quoted %t", columns[i].Name(), quoted)
+		}
+	}
+
+	exported := rawsql.CreateTableSQL(db.Dialector.(*rawsql.Dialector).Parser.GetTables()["t"])
+	for _, want := range []string{
+		"`literal` varchar(32) DEFAULT 'CURRENT_TIMESTAMP'",
+		"`keyword` datetime DEFAULT CURRENT_TIMESTAMP",
+		"`zero` varchar(8) DEFAULT 0",
+	} {
+		if !strings.Contains(exported, want) {
+			t.Errorf("missing %q in %s", want, exported)
+		}
+	}
+	if tag := rawsql.GormTag(columns[1]); !strings.Contains(tag, "default:CURRENT_TIMESTAMP") {
+		t.Errorf("unexpected tag %s", tag)
+	}
+	if tag := rawsql.GormTag(columns[0]); !strings.Contains(tag, "default:'CURRENT_TIMESTAMP'") {
+		t.Errorf("unexpected tag %s", tag)
+	}
+}
+
+func TestExpressionDefaultsExport(t *testing.T) {
+	table, err := rawsql.ParseTable("CREATE TABLE `t` (`id` char(36) DEFAULT (uuid()), `tags` json DEFAULT (JSON_ARRAY()), " +
+		"`at` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3));")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	exported := rawsql.CreateTableSQL(table)
+	for _, want := range []string{
+		"`id` char(36) DEFAULT (UUID())",
+		"`tags` json DEFAULT (JSON_ARRAY())",
+		"`at` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3)",
+	} {
+		if !strings.Contains(exported, want) {
+			t.Errorf("missing %q in %s", want, exported)
+		}
+	}
+
+	again, err := rawsql.ParseTable(exported)
+	if err != nil {
+		t.Fatalf("parse the export: %v", err)
+	}
+	if exportedAgain := rawsql.CreateTableSQL(again); exportedAgain != exported {
+		t.Errorf("the export does not round trip:\n%s\n%s", exported, exportedAgain)
+	}
+	for i, want := range []string{"UUID()", "JSON_ARRAY()", "CURRENT_TIMESTAMP(3)"} {
+		if value, _ := again.ColumnTypes[i].DefaultValue(); value != want {
+			t.Errorf("%s: got default %q, want %q", again.ColumnTypes[i].Name(), value, want)
+		}
+	}
+}
+
+func TestSignedAndExpressionDefaults(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `t` (`a` int DEFAULT -1, `b` decimal(5,2) DEFAULT -1.50, `c` int DEFAULT +2, "+
+			"`e` bigint DEFAULT -9223372036854775808, `f` varchar(36) DEFAULT (UUID()));",
+	)
+	want := map[string]interface{}{
+		"a": int64(-1), "b": -1.5, "c": int64(2), "e": int64(-9223372036854775808),
+		"f": rawsql.Expression("UUID()"),
+	}
+	columns, _ := db.Migrator().ColumnTypes("t")
+	for _, column := range columns {
+		if value, ok := column.(*rawsql.ColumnType).Default(); !ok || value != want[column.Name()] {
+			t.Errorf("%s: got default %#v (%t), want %#v", column.Name(), value, ok, want[column.Name()])
+		}
+	}
+	if value, _ := columns[1].DefaultValue(); value != "-1.50" {
+		t.Errorf("the decimal default should keep its scale, got %q", value)
+	}
+}
+
+func TestCommentAndDefaultFidelity(t *testing.T) {
+	dir := t.TempDir()
+	sql := "CREATE TABLE `商品` (\n" +
+		"  `名前` varchar(64) DEFAULT '未設定' COMMENT '商品の名前 🍣',\n" +
+		"  `quote` varchar(64) DEFAULT 'it''s \\\"quoted\\\"' COMMENT 'it\\'s a \"quote\"',\n" +
+		"  `lines` text COMMENT 'line1\\nline2\\ttab\\\\backslash',\n" +
+		"  `pct` varchar(8) DEFAULT '100\\%' COMMENT 'like \\_escapes'\n" +
+		") COMMENT='商品テーブル\\n二行目'"
+	want := map[string][2]string{
+		"名前":    {"未設定", "商品の名前 🍣"},
+		"quote": {`it's "quoted"`, `it's a "quote"`},
+		"lines": {"", "line1\nline2\ttab\\backslash"},
+		"pct":   {`100\%`, `like \_escapes`},
+	}
+
+	for _, cached := range []bool{false, true} {
+		db := openSQL(t, rawsql.Config{CacheDir: dir}, sql)
+		if tableType, _ := db.Migrator().TableType("商品"); tableType == nil {
+			t.Fatalf("table not found")
+		} else if comment, _ := tableType.Comment(); comment != "商品テーブル\n二行目" {
+			t.Errorf("cached=%t: got table comment %q", cached, comment)
+		}
+		columns, _ := db.Migrator().ColumnTypes("商品")
+		for _, column := range columns {
+			def, _ := column.DefaultValue()
+			comment, _ := column.Comment()
+			if got := [2]string{def, comment}; got != want[column.Name()] {
+				t.Errorf("cached=%t %s: got %q, want %q", cached, column.Name(), got, want[column.Name()])
+			}
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("CREATE TABLE `t` (")
+	i := 0
+	for name, values := range want {
+		if i++; i > 1 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "`%s` text DEFAULT %s COMMENT %s", name, rawsql.QuoteString(values[0]), rawsql.QuoteString(values[1]))
+	}
+	b.WriteString(")")
+	columns, _ := openSQL(t, rawsql.Config{}, b.String()).Migrator().ColumnTypes("t")
+	for _, column := range columns {
+		def, _ := column.DefaultValue()
+		comment, _ := column.Comment()
+		if got := [2]string{def, comment}; got != want[column.Name()] {
+			t.Errorf("requoted %s: got %q, want %q", column.Name(), got, want[column.Name()])
+		}
+	}
+}
+
+func TestGormTag(t *testing.T) {
+	columns, _ := openSQL(t, rawsql.Config{}, "CREATE TABLE `users` ("+
+		"`id` bigint unsigned NOT NULL AUTO_INCREMENT PRIMARY KEY, "+
+		"`email` varchar(128) NOT NULL UNIQUE COMMENT 'login; lower case', "+
+		"`status` varchar(16) DEFAULT 'active', "+
+		"`age` int DEFAULT 18, "+
+		"`created_at` datetime(3) DEFAULT CURRENT_TIMESTAMP, "+
+		"`quote` varchar(16) DEFAULT 'it''s', "+
+		"`sep` varchar(16) DEFAULT 'a;b')",
+	).Migrator().ColumnTypes("users")
+	want := []string{
+		"column:id;type:bigint(20) unsigned;primaryKey;autoIncrement",
+		`column:email;type:varchar(128);not null;unique;comment:login\; lower case`,
+		"column:status;type:varchar(16);default:'active'",
+		"column:age;type:int(11);default:18",
+		"column:created_at;type:datetime(3);default:CURRENT_TIMESTAMP",
+		"column:quote;type:varchar(16);default:'it''s'",
+		`column:sep;type:varchar(16);default:'a\;b'`,
+	}
+	for i, column := range columns {
+		if tag := rawsql.GormTag(column); tag != want[i] {
+			t.Errorf("%s: got %q, want %q", column.Name(), tag, want[i])
+		}
+	}
+	if settings := schema.ParseTagSetting(rawsql.GormTag(columns[1]), ";"); settings["COMMENT"] != "login; lower case" {
+		t.Errorf("gorm reads the comment as %q", settings["COMMENT"])
+	}
+	if settings := schema.ParseTagSetting(rawsql.GormTag(columns[6]), ";"); settings["DEFAULT"] != "'a;b'" {
+		t.Errorf("gorm reads the default as %q", settings["DEFAULT"])
+	}
+}
+
+func TestGenFieldTypes(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{SQL: []string{"CREATE TABLE `users` (" +
+		"`id` bigint unsigned NOT NULL, `age` int unsigned, `score` int, `active` tinyint(1), " +
+		"`name` varchar(64), `born_at` datetime, `ratio` double)"}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	table := dialector.Parser.GetTables()["users"]
+	want := map[string]string{
+		"id": "uint64", "age": "uint32", "score": "int32", "active": "bool",
+		"name": "string", "born_at": "time.Time", "ratio": "float64",
+	}
+	if types := rawsql.GenFieldTypes(table); !reflect.DeepEqual(types, want) {
+		t.Fatalf("expected %v, got %v", want, types)
+	}
+	if tags := rawsql.GenFieldTags(table); tags["id"] != "column:id;type:bigint(20) unsigned;not null" {
+		t.Fatalf("unexpected tag %q", tags["id"])
+	}
+}
+
+func TestColumnExtra(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `t` (`id` bigint AUTO_INCREMENT PRIMARY KEY, `a` int, `b` int AS (`a` + 1), `c` int AS (`a` * 2) STORED,\n"+
+			"  `d` datetime(3) DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3), `e` timestamp ON UPDATE NOW(),\n"+
+			"  `f` int INVISIBLE);",
+	)
+	want := map[string]string{
+		"id": "auto_increment",
+		"a":  "",
+		"b":  "VIRTUAL GENERATED",
+		"c":  "STORED GENERATED",
+		"d":  "DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)",
+		"e":  "on update CURRENT_TIMESTAMP",
+		"f":  "INVISIBLE",
+	}
+	columns, _ := db.Migrator().ColumnTypes("t")
+	for _, column := range columns {
+		if got := column.(*rawsql.ColumnType).Extra(); got != want[column.Name()] {
+			t.Errorf("%s: got extra %q, want %q", column.Name(), got, want[column.Name()])
+		}
+	}
+
+	expression, stored, ok := columns[2].(*rawsql.ColumnType).Generated()
+	if !ok || stored || expression != "`a`+1" {
+		t.Errorf("b: got generated %q stored %t", expression, stored)
+	}
+	sql := rawsql.CreateTableSQL(db.Dialector.(*rawsql.Dialector).Parser.GetTables()["t"])
+	for _, def := range []string{"`c` int AS (`a`*2) STORED", "ON UPDATE CURRENT_TIMESTAMP(3)"} {
+		if !strings.Contains(sql, def) {
+			t.Errorf("expected %q in %s", def, sql)
+		}
+	}
+}
+
+func TestStringLengths(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `t` (`a` varchar(100), `b` char CHARACTER SET latin1, `c` text, `d` enum('x','ünï'), `e` set('a','bc'),\n"+
+			"  `f` varbinary(5), `g` blob, `h` int, `i` varchar(10) CHARACTER SET utf8mb3, `j` json) CHARSET=utf8mb4;",
+		"ALTER TABLE `t` ADD COLUMN `k` varchar(20) COLLATE gbk_chinese_ci;",
+	)
+	want := map[string][2]int64{
+		"a": {100, 400}, "b": {1, 1}, "c": {65535, 65535}, "d": {3, 12}, "e": {4, 16},
+		"f": {5, 5}, "g": {65535, 65535}, "i": {10, 30}, "k": {20, 40},
+	}
+	columns, _ := db.Migrator().ColumnTypes("t")
+	for _, column := range columns {
+		ct := column.(*rawsql.ColumnType)
+		chars, ok := ct.CharLength()
+		octets, _ := ct.OctetLength()
+		if w, isString := want[ct.Name()]; ok != isString || chars != w[0] || octets != w[1] {
+			t.Errorf("%s: got %d characters, %d bytes", ct.Name(), chars, octets)
+		}
+	}
+
+	var buf strings.Builder
+	if err := rawsql.EncodeSnapshot(&buf, db.Dialector.(*rawsql.Dialector).Parser.GetTables()); err != nil {
+		t.Fatalf("failed to encode snapshot, got error %v", err)
+	}
+	tables, err := rawsql.DecodeSnapshot(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("failed to decode snapshot, got error %v", err)
+	}
+	if octets, ok := tables["t"].ColumnTypes[0].(*rawsql.ColumnType).OctetLength(); !ok || octets != 400 {
+		t.Errorf("expected the byte length to survive a snapshot, got %d", octets)
+	}
+}
+
+func TestPrimaryKeyColumns(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `memberships` (`user_id` bigint, `tenant_id` bigint, `role` varchar(16), PRIMARY KEY (`tenant_id`, `user_id`));",
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` text);",
+		"CREATE TABLE `logs` (`line` text);",
+		"ALTER TABLE `memberships` RENAME COLUMN `tenant_id` TO `org_id`;",
+	)
+	tables := db.Dialector.(*rawsql.Dialector).Parser.GetTables()
+	for name, want := range map[string][]string{
+		"memberships": {"org_id", "user_id"},
+		"users":       {"id"},
+		"logs":        nil,
+	} {
+		if got := tables[name].PrimaryKeyColumns(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got primary key %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestUniqueIndexTags(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `accounts` (`id` bigint PRIMARY KEY, `email` varchar(64), `tenant_id` bigint, `handle` varchar(255),\n"+
+			"  UNIQUE KEY `uk_tenant_email` (`tenant_id`, `email`), UNIQUE KEY `uk_handle` (`handle`(32)), KEY `idx_email` (`email`));",
+	)
+	table := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["accounts"]
+	want := map[string][]string{
+		"tenant_id": {"uniqueIndex:uk_tenant_email,priority:1"},
+		"email":     {"uniqueIndex:uk_tenant_email,priority:2"},
+		"handle":    {"uniqueIndex:uk_handle,length:32"},
+	}
+	if got := rawsql.UniqueIndexTags(table); !reflect.DeepEqual(got, want) {
+		t.Errorf("got tags %v", got)
+	}
+
+	// a model carrying the tags declares the same composite index, with the
+	// fields in table order
+	tags := rawsql.GenFieldTags(table)
+	fields := make([]reflect.StructField, 0, len(table.ColumnTypes))
+	for _, ct := range table.ColumnTypes {
+		fields = append(fields, reflect.StructField{
+			Name: schema.NamingStrategy{}.SchemaName(ct.Name()),
+			Type: ct.ScanType(),
+			Tag:  reflect.StructTag(`gorm:"` + tags[ct.Name()] + `"`),
+		})
+	}
+	model := reflect.New(reflect.StructOf(fields)).Interface()
+	s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("failed to parse model, got error %v", err)
+	}
+	idx := s.ParseIndexes()["uk_tenant_email"]
+	if idx.Class != "UNIQUE" || len(idx.Fields) != 2 || idx.Fields[0].DBName != "tenant_id" || idx.Fields[1].DBName != "email" {
+		t.Errorf("unexpected index %+v", idx)
+	}
+}
+
+func TestReturnedTablesAreCopies(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(64), UNIQUE KEY `uk_name` (`name`(8))) ENGINE=InnoDB ROW_FORMAT=DYNAMIC;",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+
+	columns, _ := db.Migrator().ColumnTypes("users")
+	columns[0].(*rawsql.ColumnType).NameValue.String = "changed"
+	columns[1] = nil
+	indexes, _ := db.Migrator().GetIndexes("users")
+	indexes[0].(*rawsql.Index).ColumnList[0] = "changed"
+
+	table := dialector.Parser.GetTables()["users"]
+	table.ColumnTypes[1].(*rawsql.ColumnType).CommentValue.String = "changed"
+	table.Options["ROW_FORMAT"] = "changed"
+	table.Indexes[0].(*rawsql.Index).PrefixValue[0] = 1
+	table.Name = "changed"
+
+	ordered, _ := dialector.TablesInDependencyOrder()
+	ordered[0].ColumnTypes = nil
+
+	columns, _ = db.Migrator().ColumnTypes("users")
+	if len(columns) != 2 || columns[0].Name() != "id" || columns[1] == nil {
+		t.Fatalf("expected the parsed columns untouched, got %v", columns)
+	}
+	if comment, _ := columns[1].Comment(); comment != "" {
+		t.Errorf("expected the comment untouched, got %q", comment)
+	}
+	again := dialector.Parser.GetTables()["users"]
+	if again.Name != "users" || again.Options["ROW_FORMAT"] != "DYNAMIC" || again.Indexes[0].Columns()[0] != "name" ||
+		again.Indexes[0].(*rawsql.Index).PrefixLengths()[0] != 8 {
+		t.Errorf("expected the parser tables untouched, got %+v", again)
+	}
+}
+
+func TestVectorColumns(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `docs` (`id` bigint PRIMARY KEY, `embedding` VECTOR(3) NOT NULL COMMENT 'model output', `any` vector);",
+		"ALTER TABLE `docs` ADD COLUMN `summary` VECTOR<FLOAT>(4) AFTER `id`;",
+	)
+	table := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["docs"]
+	want := map[string]int64{"embedding": 3, "summary": 4}
+	for _, ct := range table.ColumnTypes[1:] {
+		c := ct.(*rawsql.ColumnType)
+		if c.DatabaseTypeName() != "vector" || c.ScanType() != reflect.TypeOf([]float32(nil)) {
+			t.Errorf("expected a []float32 vector column, got %s %v", c.DatabaseTypeName(), c.ScanType())
+		}
+		if dimension, ok := c.VectorDimension(); dimension != want[c.Name()] || ok != (want[c.Name()] > 0) {
+			t.Errorf("unexpected dimension %d %t of %s", dimension, ok, c.Name())
+		}
+	}
+	if comment, _ := table.ColumnTypes[2].Comment(); comment != "model output" {
+		t.Errorf("expected the comment after the vector type, got %q", comment)
+	}
+	if !strings.Contains(table.RawSQL, "VECTOR<FLOAT>(4)") {
+		t.Errorf("expected the original sql, got %s", table.RawSQL)
+	}
+
+	exported, err := rawsql.ParseTable(rawsql.CreateTableSQL(table))
+	if err != nil {
+		t.Fatalf("failed to parse the exported table, got error %v", err)
+	}
+	if columnType, _ := exported.ColumnTypes[1].ColumnType(); columnType != "vector(4)" {
+		t.Errorf("expected the vector column exported, got %s", columnType)
+	}
+
+	db = openSQL(t, rawsql.Config{VectorScanType: reflect.TypeOf("")}, "CREATE TABLE `docs` (`embedding` VECTOR(3));")
+	ct := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["docs"].ColumnTypes[0]
+	if ct.ScanType() != reflect.TypeOf("") {
+		t.Errorf("expected the configured scan type, got %v", ct.ScanType())
+	}
+}
+
+func TestNullableScanTypes(t *testing.T) {
+	ddl := []string{
+		"CREATE TABLE `users` (`id` bigint, `age` int NOT NULL, `score` double, `visits` bigint unsigned, `born` datetime, PRIMARY KEY (`id`));",
+		"ALTER TABLE `users` MODIFY `age` int NULL, MODIFY `score` double NOT NULL;",
+	}
+	want := map[rawsql.NullableStyle][]reflect.Type{
+		rawsql.NullableAsValue:   {reflect.TypeOf(int64(0)), reflect.TypeOf(int32(0)), reflect.TypeOf(float64(0)), reflect.TypeOf(uint64(0)), reflect.TypeOf(time.Time{})},
+		rawsql.NullableAsPointer: {reflect.TypeOf(int64(0)), reflect.TypeOf((*int32)(nil)), reflect.TypeOf(float64(0)), reflect.TypeOf((*uint64)(nil)), reflect.TypeOf((*time.Time)(nil))},
+		rawsql.NullableAsSQLNull: {reflect.TypeOf(int64(0)), reflect.TypeOf(sql.NullInt32{}), reflect.TypeOf(float64(0)), reflect.TypeOf((*uint64)(nil)), reflect.TypeOf(sql.NullTime{})},
+	}
+	for style, types := range want {
+		db := openSQL(t, rawsql.Config{NullableScanType: style}, ddl...)
+		table := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["users"]
+		for i, ct := range table.ColumnTypes {
+			if ct.ScanType() != types[i] {
+				t.Errorf("style %d: expected %s scanned into %v, got %v", style, ct.Name(), types[i], ct.ScanType())
+			}
+		}
+		if style != rawsql.NullableAsSQLNull {
+			continue
+		}
+		if typ := rawsql.GenFieldType(table.ColumnTypes[4]); typ != "sql.NullTime" {
+			t.Errorf("expected the gen type of the nullable datetime, got %s", typ)
+		}
+		var buf bytes.Buffer
+		if err := rawsql.EncodeSnapshot(&buf, map[string]*rawsql.Table{"users": table}); err != nil {
+			t.Fatalf("failed to encode the snapshot, got error %v", err)
+		}
+		decoded, err := rawsql.DecodeSnapshot(&buf)
+		if err != nil {
+			t.Fatalf("failed to decode the snapshot, got error %v", err)
+		}
+		for i, ct := range decoded["users"].ColumnTypes {
+			if ct.ScanType() != types[i] {
+				t.Errorf("expected %s restored with scan type %v, got %v", ct.Name(), types[i], ct.ScanType())
+			}
+		}
+	}
+}
+
+func TestGenDirectives(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"-- rawsql:model-name=UserAccount\n"+
+			"CREATE TABLE `users` (\n"+
+			"  `id` bigint PRIMARY KEY,\n"+
+			"  -- rawsql:field-type=decimal.Decimal\n"+
+			"  -- rawsql:field-name=Funds\n"+
+			"  `balance` decimal(10,2),\n"+
+			"  -- rawsql:field-type=Status\n"+
+			"  status varchar(16),\n"+
+			"  -- rawsql:field-type=unused\n"+
+			"  KEY `idx_status` (`status`)\n"+
+			");",
+		"ALTER TABLE `users` MODIFY `balance` decimal(12,2),\n"+
+			"  -- rawsql:field-type=Tags\n"+
+			"  ADD COLUMN `tags` json;",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	table := dialector.Parser.GetTables()["users"]
+
+	if name := rawsql.GenModelName(table); name != "UserAccount" {
+		t.Errorf("expected the model name directive, got %q", name)
+	}
+	if names := rawsql.GenFieldNames(table); !reflect.DeepEqual(names, map[string]string{"balance": "Funds"}) {
+		t.Errorf("unexpected field names %v", names)
+	}
+	want := map[string]string{"id": "int64", "balance": "decimal.Decimal", "status": "Status", "tags": "Tags"}
+	if types := rawsql.GenFieldTypes(table); !reflect.DeepEqual(types, want) {
+		t.Errorf("unexpected field types %v", types)
+	}
+	warnings := dialector.Report().Warnings
+	if len(warnings) != 1 || warnings[0].Message != "rawsql:field-type directive is not followed by a column definition" {
+		t.Errorf("expected the directive before the index reported, got %v", warnings)
+	}
+
+	var snapshot bytes.Buffer
+	rawsql.EncodeBinarySnapshot(&snapshot, dialector.Parser.GetTables())
+	decoded, err := rawsql.DecodeBinarySnapshot(&snapshot)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if types := rawsql.GenFieldTypes(decoded["users"]); !reflect.DeepEqual(types, want) || rawsql.GenModelName(decoded["users"]) != "UserAccount" {
+		t.Errorf("expected the directives kept in snapshots, got %v", types)
+	}
+}
+
+func TestIndexPrefixDefinitions(t *testing.T) {
+	full, err := rawsql.ParseTable("CREATE TABLE `users` (`name` varchar(64), `email` varchar(64), KEY `idx_name` (`name`, `email`));")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	prefixed, err := rawsql.ParseTable("CREATE TABLE `users` (`name` varchar(64), `email` varchar(64), KEY `idx_name` (`name`(32), `email`));")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	if lengths := prefixed.Indexes[0].(*rawsql.Index).PrefixLengths(); !reflect.DeepEqual(lengths, []int{32, 0}) {
+		t.Errorf("expected the prefix length kept, got %v", lengths)
+	}
+	if a, b := rawsql.IndexSQL(full.Indexes[0]), rawsql.IndexSQL(prefixed.Indexes[0]); a == b || b != "KEY `idx_name` (`name`(32), `email`)" {
+		t.Errorf("expected different index definitions, got %s and %s", a, b)
+	}
+
+	again, err := rawsql.ParseTable(rawsql.CreateTableSQL(prefixed))
+	if err != nil {
+		t.Fatalf("failed to parse the exported table, got error %v", err)
+	}
+	if rawsql.IndexSQL(again.Indexes[0]) != rawsql.IndexSQL(prefixed.Indexes[0]) {
+		t.Errorf("expected the prefix exported, got %s", rawsql.CreateTableSQL(prefixed))
+	}
+
+	_, err = gorm.Open(rawsql.New(rawsql.Config{BaselineLock: true, SQL: []string{full.RawSQL, prefixed.RawSQL}}))
+	if err == nil || !strings.Contains(err.Error(), "+   KEY `idx_name` (`name`(32), `email`)") {
+		t.Errorf("expected the baseline diff to show the prefix, got %v", err)
+	}
+}
+
+func TestIndexesFor(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `email` varchar(64) UNIQUE, `tenant_id` bigint, `name` text, `bio` text, KEY `idx_tenant` (`tenant_id`), KEY `idx_tenant_name` (`tenant_id`, `name`(16)));",
+	)
+	table := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["users"]
+	var names []string
+	for _, idx := range table.IndexesFor("TENANT_ID") {
+		names = append(names, idx.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"idx_tenant", "idx_tenant_name"}) {
+		t.Errorf("expected the indexes of tenant_id, got %v", names)
+	}
+	for column, want := range map[string]bool{"id": true, "email": true, "tenant_id": true, "name": true, "bio": false, "missing": false} {
+		if got := table.IsIndexed(column); got != want {
+			t.Errorf("%s: expected indexed %v, got %v", column, want, got)
+		}
+	}
+}
+
+func TestColumnLookup(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint, `UserName` varchar(64), `email` text);",
+		"ALTER TABLE `users` ADD COLUMN `tenant_id` bigint AFTER `id`;",
+	)
+	table := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["users"]
+	if names := table.ColumnNames(); !reflect.DeepEqual(names, []string{"id", "tenant_id", "UserName", "email"}) {
+		t.Errorf("expected the columns in table order, got %v", names)
+	}
+	if ct, ok := table.Column("username"); !ok || ct.Name() != "UserName" {
+		t.Errorf("expected the column found case-insensitively, got %v %v", ct, ok)
+	}
+	if _, ok := table.Column("missing"); ok {
+		t.Errorf("expected no missing column")
+	}
+}
+
+func TestSensitiveColumns(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (\n"+
+			"  `id` bigint PRIMARY KEY,\n"+
+			"  `email` varchar(128) COMMENT 'contact address, PII, mask:email',\n"+
+			"  `card` varchar(19) COMMENT 'PCI PII Mask:Last4',\n"+
+			"  `token` varchar(64) COMMENT 'mask:redact',\n"+
+			"  `bio` text COMMENT 'not sensitive, shown on the profile'\n"+
+			");",
+	)
+	table := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["users"]
+	got := rawsql.SensitiveColumns(table)
+	want := map[string]rawsql.Sensitivity{
+		"email": {Classes: []string{"PII"}, Mask: "email"},
+		"card":  {Classes: []string{"PCI", "PII"}, Mask: "last4"},
+		"token": {Mask: "redact"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected sensitive columns %v, got %v", want, got)
+	}
+	if s := got["card"]; !s.Is("pii") || s.Is("PHI") {
+		t.Errorf("expected the card column classified PII only besides PCI, got %v", s.Classes)
+	}
+	if s := rawsql.ColumnSensitivity(table.ColumnTypes[4]); s.Sensitive() {
+		t.Errorf("expected prose not to mark the bio column, got %+v", s)
+	}
+}
+
+func init() {
+	rawsql.RegisterCommentExtractor("test-tags", rawsql.RegexpExtractor(regexp.MustCompile(`@(?P<key>\w+)=(?P<value>\S+)`), ""))
+	rawsql.RegisterCommentExtractor("test-deprecated", rawsql.RegexpExtractor(regexp.MustCompile(`\bDEPRECATED\b`), "deprecated"))
+}
+
+func TestCommentMetadata(t *testing.T) {
+	db := openSQL(t, rawsql.Config{CommentExtractors: []string{rawsql.SensitivityExtractor, "test-tags", "test-deprecated"}},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `email` varchar(128) COMMENT 'PII mask:email @owner=identity', `fax` varchar(32) COMMENT 'DEPRECATED') COMMENT 'accounts @owner=identity @tier=1';",
+		"ALTER TABLE `users` MODIFY `fax` varchar(32) COMMENT '@mask=none', ADD COLUMN `age` int COMMENT 'PHI', COMMENT 'accounts @owner=billing';",
+	)
+	table := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["users"]
+	if want := map[string]string{"owner": "billing"}; !reflect.DeepEqual(table.Metadata, want) {
+		t.Errorf("expected the table metadata of the altered comment %v, got %v", want, table.Metadata)
+	}
+	want := []map[string]string{
+		nil,
+		{"sensitivity": "PII", "mask": "email", "owner": "identity"},
+		{"mask": "none"},
+		{"sensitivity": "PHI"},
+	}
+	for i, ct := range table.ColumnTypes {
+		if got := ct.(*rawsql.ColumnType).Metadata(); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("expected the metadata of %s %v, got %v", ct.Name(), want[i], got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := rawsql.EncodeBinarySnapshot(&buf, map[string]*rawsql.Table{"users": table}); err != nil {
+		t.Fatalf("failed to encode the snapshot, got error %v", err)
+	}
+	decoded, err := rawsql.DecodeBinarySnapshot(&buf)
+	if err != nil {
+		t.Fatalf("failed to decode the snapshot, got error %v", err)
+	}
+	if got := decoded["users"].ColumnTypes[1].(*rawsql.ColumnType).Metadata(); !reflect.DeepEqual(got, want[1]) || decoded["users"].Metadata["owner"] != "billing" {
+		t.Errorf("expected the metadata kept by snapshots, got %v and %v", got, decoded["users"].Metadata)
+	}
+
+	db = openSQL(t, rawsql.Config{}, "CREATE TABLE `t` (`a` int COMMENT 'PII');")
+	if metadata := db.Dialector.(*rawsql.Dialector).Parser.GetTables()["t"].ColumnTypes[0].(*rawsql.ColumnType).Metadata(); metadata != nil {
+		t.Errorf("expected no metadata without extractors, got %v", metadata)
+	}
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{CommentExtractors: []string{"missing"}})); err == nil || !strings.Contains(err.Error(), `unknown comment extractor "missing"`) {
+		t.Errorf("expected an unknown extractor error, got %v", err)
+	}
+}
+
+// Money stands for the decimal type of an organization
+type Money struct{ Cents int64 }
+
+func init() {
+	meta.RegisterScanType(reflect.TypeOf(Money{}))
+}
+
+func TestTypeMappings(t *testing.T) {
+	money := reflect.TypeOf(Money{})
+	db := openSQL(t, rawsql.Config{
+		TypeMappings: []rawsql.TypeMapping{
+			{Column: "*_amount", DataType: "DECIMAL", ScanType: money},
+			{Column: "*_at", Table: "audit_*", ScanType: reflect.TypeOf(int64(0))},
+		},
+		NullableScanType: rawsql.NullableAsPointer,
+	},
+		"CREATE TABLE `orders` (`id` bigint PRIMARY KEY, `Total_Amount` decimal(12,2) NOT NULL, `tax_amount` decimal(12,2), `item_amount` int NOT NULL, `created_at` datetime NOT NULL);",
+		"CREATE TABLE `audit_log` (`created_at` datetime NOT NULL);",
+		"ALTER TABLE `orders` ADD COLUMN `fee_amount` decimal(8,2) NOT NULL;",
+	)
+	tables := db.Dialector.(*rawsql.Dialector).Parser.GetTables()
+	want := map[string]reflect.Type{
+		"Total_Amount": money,
+		"tax_amount":   reflect.PtrTo(money),
+		"item_amount":  reflect.TypeOf(int32(0)),
+		"created_at":   reflect.TypeOf(time.Time{}),
+		"fee_amount":   money,
+	}
+	for _, ct := range tables["orders"].ColumnTypes[1:] {
+		if ct.ScanType() != want[ct.Name()] {
+			t.Errorf("expected %s scanned into %v, got %v", ct.Name(), want[ct.Name()], ct.ScanType())
+		}
+	}
+	if scanType := tables["audit_log"].ColumnTypes[0].ScanType(); scanType != reflect.TypeOf(int64(0)) {
+		t.Errorf("expected the mapping of the audit tables, got %v", scanType)
+	}
+
+	var buf bytes.Buffer
+	if err := rawsql.EncodeSnapshot(&buf, tables); err != nil {
+		t.Fatalf("failed to encode the snapshot, got error %v", err)
+	}
+	decoded, err := rawsql.DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("failed to decode the snapshot, got error %v", err)
+	}
+	if scanType := decoded["orders"].ColumnTypes[1].ScanType(); scanType != money {
+		t.Errorf("expected the registered scan type restored, got %v", scanType)
+	}
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{TypeMappings: []rawsql.TypeMapping{{Column: "*_amount"}}})); err == nil {
+		t.Errorf("expected a mapping without a scan type to fail")
+	}
+}
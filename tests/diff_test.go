@@ -0,0 +1,323 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
+	"gorm.io/rawsql"
+	"gorm.io/rawsql/meta"
+)
+
+func TestCompareTables(t *testing.T) {
+	a, err := rawsql.ParseTable("CREATE TABLE `users` (`id` bigint AUTO_INCREMENT, `name` varchar(32), `email` varchar(64), `age` int, PRIMARY KEY (`id`), KEY `idx_name` (`name`), KEY `idx_age` (`age`)) ENGINE=InnoDB;")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	b, err := rawsql.ParseTable("CREATE TABLE `users` (`id` bigint AUTO_INCREMENT, `name` varchar(32), `email` varchar(64), `age` int, PRIMARY KEY (`id`), KEY `idx_name` (`name`), KEY `idx_age` (`age`)) ENGINE=InnoDB;")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	if diff := rawsql.CompareTables(a, b); !diff.Empty() {
+		t.Errorf("expected no difference, got %+v", diff)
+	}
+
+	b, err = rawsql.ParseTable("CREATE TABLE `users` (`id` bigint AUTO_INCREMENT, `name` varchar(64) NOT NULL DEFAULT '', `email` varchar(64), `bio` text COMMENT 'about', PRIMARY KEY (`id`), KEY `idx_name` (`name`(16)), UNIQUE KEY `uk_email` (`email`)) ENGINE=InnoDB ROW_FORMAT=DYNAMIC;")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	if err = b.ApplyAlter("ALTER TABLE `users` RENAME COLUMN `email` TO `mail`;"); err != nil {
+		t.Fatalf("failed to rename column, got error %v", err)
+	}
+	diff := rawsql.CompareTables(a, b)
+	if diff.Empty() {
+		t.Fatalf("expected differences")
+	}
+	if want := []rawsql.FieldChange{{Field: "ROW_FORMAT", To: "DYNAMIC"}}; !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("expected the row format change, got %+v", diff.Changes)
+	}
+	if len(diff.AddedColumns) != 1 || diff.AddedColumns[0].Name() != "bio" || len(diff.RemovedColumns) != 1 || diff.RemovedColumns[0].Name() != "age" {
+		t.Errorf("expected bio added and age removed, got %v and %v", diff.AddedColumns, diff.RemovedColumns)
+	}
+	changes := map[string][]rawsql.FieldChange{}
+	for _, column := range diff.ChangedColumns {
+		changes[column.Name] = column.Changes
+	}
+	if want := (map[string][]rawsql.FieldChange{
+		"mail": {{Field: "name", From: "`email`", To: "`mail`"}},
+		"name": {
+			{Field: "type", From: "varchar(32)", To: "varchar(64)"},
+			{Field: "nullable", From: "NULL", To: "NOT NULL"},
+			{Field: "default", To: "''"},
+		},
+	}); !reflect.DeepEqual(changes, want) {
+		t.Errorf("expected the column changes, got %+v", changes)
+	}
+	if len(diff.ChangedIndexes) != 1 || diff.ChangedIndexes[0].Name != "idx_name" || len(diff.AddedIndexes) != 1 || diff.AddedIndexes[0].Name() != "uk_email" {
+		t.Errorf("expected idx_name changed and uk_email added, got %+v and %v", diff.ChangedIndexes, diff.AddedIndexes)
+	}
+	if len(diff.RemovedIndexes) != 1 || diff.RemovedIndexes[0].Name() != "idx_age" {
+		t.Errorf("expected idx_age removed, got %v", diff.RemovedIndexes)
+	}
+}
+
+func TestTableDiffFormats(t *testing.T) {
+	a, err := rawsql.ParseTable("CREATE TABLE `users` (`id` bigint, `name` varchar(32), `age` int, KEY `idx_age` (`age`));")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	b, err := rawsql.ParseTable("CREATE TABLE `users` (`id` bigint, `name` varchar(64) NOT NULL, `bio` text);")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	diff := rawsql.CompareTables(a, b)
+
+	want := "`users`\n" +
+		"  + column `bio` text [safe]\n" +
+		"  - column `age` int [breaking: the column and its data are dropped]\n" +
+		"  ~ column `name`: type varchar(32) -> varchar(64), nullable NULL -> NOT NULL [needs-backfill: existing NULLs must be filled in first]\n" +
+		"  - index KEY `idx_age` (`age`) [safe]\n"
+	if got := diff.String(); got != want {
+		t.Errorf("expected text\n%s\ngot\n%s", want, got)
+	}
+
+	want = "--- a/users.sql\n+++ b/users.sql\n@@ -1,6 +1,5 @@\n" +
+		" CREATE TABLE `users` (\n" +
+		"   `id` bigint,\n" +
+		"-  `name` varchar(32),\n" +
+		"-  `age` int,\n" +
+		"-  KEY `idx_age` (`age`)\n" +
+		"+  `name` varchar(64) NOT NULL,\n" +
+		"+  `bio` text\n" +
+		" );\n"
+	if got := diff.Unified(); got != want {
+		t.Errorf("expected unified diff\n%s\ngot\n%s", want, got)
+	}
+
+	data, err := json.Marshal(diff)
+	if err != nil {
+		t.Fatalf("failed to marshal diff, got error %v", err)
+	}
+	var decoded struct {
+		AddedColumns   []string `json:"added_columns"`
+		ChangedColumns []struct {
+			To      string               `json:"to"`
+			Changes []rawsql.FieldChange `json:"changes"`
+		} `json:"changed_columns"`
+		RemovedIndexes []string `json:"removed_indexes"`
+	}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal diff, got error %v", err)
+	}
+	if !reflect.DeepEqual(decoded.AddedColumns, []string{"`bio` text"}) || len(decoded.ChangedColumns) != 1 ||
+		decoded.ChangedColumns[0].To != "`name` varchar(64) NOT NULL" || len(decoded.ChangedColumns[0].Changes) != 2 ||
+		!reflect.DeepEqual(decoded.RemovedIndexes, []string{"KEY `idx_age` (`age`)"}) {
+		t.Errorf("unexpected json diff %s", data)
+	}
+
+	if diff = rawsql.CompareTables(a, a); diff.String() != "" || diff.Unified() != "" {
+		t.Errorf("expected nothing rendered without changes, got %q and %q", diff.String(), diff.Unified())
+	}
+}
+
+func TestDiffSeverities(t *testing.T) {
+	a, err := rawsql.ParseTable("CREATE TABLE `orders` (`id` bigint AUTO_INCREMENT PRIMARY KEY, `code` varchar(16), `total` decimal(10,2), `qty` int, `state` enum('new','paid'));")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	for _, tt := range []struct {
+		create   string
+		severity rawsql.Severity
+	}{
+		{"`id` bigint AUTO_INCREMENT PRIMARY KEY, `code` varchar(32), `total` decimal(12,2), `qty` bigint, `state` enum('new','paid','sent'), `note` text", rawsql.Safe},
+		{"`id` bigint AUTO_INCREMENT PRIMARY KEY, `code` varchar(16), `total` decimal(10,2), `qty` int, `state` enum('new','paid'), `region` varchar(8) NOT NULL DEFAULT 'eu'", rawsql.NeedsBackfill},
+		{"`id` bigint AUTO_INCREMENT PRIMARY KEY, `code` varchar(16), `total` decimal(10,2), `qty` int, `state` enum('new','paid'), UNIQUE KEY `uk_code` (`code`)", rawsql.NeedsBackfill},
+		{"`id` bigint AUTO_INCREMENT PRIMARY KEY, `code` varchar(16), `total` decimal(10,2), `qty` int, `state` enum('new','paid'), `region` varchar(8) NOT NULL", rawsql.Breaking},
+		{"`id` bigint AUTO_INCREMENT PRIMARY KEY, `code` varchar(8), `total` decimal(10,2), `qty` int, `state` enum('new','paid')", rawsql.Breaking},
+		{"`id` bigint AUTO_INCREMENT PRIMARY KEY, `code` varchar(16), `total` decimal(10,2), `qty` int unsigned, `state` enum('new','paid')", rawsql.Breaking},
+	} {
+		b, err := rawsql.ParseTable("CREATE TABLE `orders` (" + tt.create + ");")
+		if err != nil {
+			t.Fatalf("failed to parse table, got error %v", err)
+		}
+		diff := rawsql.CompareTables(a, b)
+		if got := diff.Severity(); got != tt.severity {
+			t.Errorf("%s: expected %s, got %s\n%s", tt.create, tt.severity, got, diff)
+		}
+	}
+
+	var severity rawsql.Severity
+	if err = json.Unmarshal([]byte(`"needs-backfill"`), &severity); err != nil || severity != rawsql.NeedsBackfill {
+		t.Errorf("expected needs-backfill decoded, got %v %v", severity, err)
+	}
+}
+
+func TestAlterSQL(t *testing.T) {
+	a, err := rawsql.ParseTable("CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(32), `email` varchar(64), `age` int, `team_id` bigint, KEY `idx_name` (`name`), KEY `idx_age` (`age`), CONSTRAINT `fk_team` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`)) ENGINE=InnoDB;")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	b, err := rawsql.ParseTable("CREATE TABLE `users` (`id` bigint PRIMARY KEY, `bio` text, `name` varchar(64) NOT NULL, `email` varchar(64), `team_id` bigint, KEY `idx_name` (`name`(16)), UNIQUE KEY `uk_email` (`email`), CONSTRAINT `fk_team` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE) ENGINE=InnoDB COMMENT='people';")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	if err = b.ApplyAlter("ALTER TABLE `users` RENAME COLUMN `email` TO `mail`;"); err != nil {
+		t.Fatalf("failed to rename column, got error %v", err)
+	}
+	want := []string{
+		"ALTER TABLE `users` DROP FOREIGN KEY `fk_team`;",
+		"ALTER TABLE `users` DROP INDEX `idx_age`, DROP INDEX `idx_name`, DROP COLUMN `age`, MODIFY COLUMN `name` varchar(64) NOT NULL, CHANGE COLUMN `email` `mail` varchar(64), ADD COLUMN `bio` text AFTER `id`, ADD KEY `idx_name` (`name`(16)), ADD UNIQUE KEY `uk_email` (`mail`), COMMENT='people';",
+		"ALTER TABLE `users` ADD CONSTRAINT `fk_team` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE;",
+	}
+	plan := rawsql.CompareTables(a, b).AlterSQL()
+	if !reflect.DeepEqual(plan, want) {
+		t.Fatalf("expected plan\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(plan, "\n"))
+	}
+
+	// primary keys change as a whole, with the PRIMARY index when there is one
+	for _, c := range [][3]string{
+		{"CREATE TABLE `t` (`id` bigint, `x` int NOT NULL, PRIMARY KEY (`id`));",
+			"CREATE TABLE `t` (`id` bigint, `x` int NOT NULL, PRIMARY KEY (`id`, `x`));",
+			"ALTER TABLE `t` DROP PRIMARY KEY, MODIFY COLUMN `x` int NOT NULL, ADD PRIMARY KEY (`id`, `x`);"},
+		{"CREATE TABLE `t` (`id` bigint PRIMARY KEY, `code` varchar(8) NOT NULL);",
+			"CREATE TABLE `t` (`id` bigint NOT NULL, `code` varchar(8) PRIMARY KEY);",
+			"ALTER TABLE `t` DROP PRIMARY KEY, MODIFY COLUMN `id` bigint NOT NULL, MODIFY COLUMN `code` varchar(8) NOT NULL, ADD PRIMARY KEY (`code`);"},
+		{"CREATE TABLE `t` (`id` bigint);",
+			"CREATE TABLE `t` (`id` bigint, `k` int PRIMARY KEY);",
+			"ALTER TABLE `t` ADD COLUMN `k` int NOT NULL, ADD PRIMARY KEY (`k`);"},
+	} {
+		from, err := rawsql.ParseTable(c[0])
+		if err != nil {
+			t.Fatalf("failed to parse table, got error %v", err)
+		}
+		to, err := rawsql.ParseTable(c[1])
+		if err != nil {
+			t.Fatalf("failed to parse table, got error %v", err)
+		}
+		if plan := rawsql.CompareTables(from, to).AlterSQL(); !reflect.DeepEqual(plan, []string{c[2]}) {
+			t.Errorf("expected plan\n%s\ngot\n%s", c[2], strings.Join(plan, "\n"))
+		}
+	}
+}
+
+type recordingPool struct {
+	gorm.ConnPool
+	executed []string
+	fail     string
+}
+
+func (p *recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.executed = append(p.executed, query)
+	if strings.Contains(query, p.fail) {
+		return nil, errors.New("duplicate key name")
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func TestApplyPlan(t *testing.T) {
+	db := openSQL(t, rawsql.Config{})
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	pool := &recordingPool{fail: "uk_email"}
+	db.ConnPool = pool
+	db.Logger = logger.Discard
+	db.Statement.ConnPool = pool
+	plan := []string{
+		"ALTER TABLE `users` ADD COLUMN `bio` text;",
+		"ALTER TABLE `users` ADD UNIQUE KEY `uk_email` (`email`);",
+		"ALTER TABLE `users` DROP COLUMN `age`;",
+	}
+
+	applied, err := rawsql.ApplyPlan(db, plan, rawsql.ApplyOptions{DryRun: true})
+	if err != nil || len(applied) != 3 || !applied[2].Skipped || len(pool.executed) != 0 {
+		t.Fatalf("expected a dry run executing nothing, got %+v %v %v", applied, err, pool.executed)
+	}
+
+	applied, err = rawsql.ApplyPlan(db, plan, rawsql.ApplyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "duplicate key name") || len(applied) != 2 || applied[1].Err == nil {
+		t.Fatalf("expected to stop at the failed statement, got %+v %v", applied, err)
+	}
+	if !reflect.DeepEqual(pool.executed, plan[:2]) {
+		t.Errorf("expected the statements up to the failure executed, got %v", pool.executed)
+	}
+
+	pool.executed = nil
+	applied, err = rawsql.ApplyPlan(db, plan, rawsql.ApplyOptions{ContinueOnError: true})
+	if err == nil || len(applied) != 3 || !reflect.DeepEqual(pool.executed, plan) {
+		t.Errorf("expected every statement executed, got %+v %v %v", applied, err, pool.executed)
+	}
+}
+
+func TestSchemaAssertion(t *testing.T) {
+	schema := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(64) NOT NULL, `age` int(11), `email` varchar(128), KEY `idx_name` (`name`)) ENGINE=InnoDB COMMENT='people';",
+		"CREATE TABLE `orders` (`id` bigint PRIMARY KEY);",
+	).Dialector.(*rawsql.Dialector).Parser.GetTables()
+
+	// the database runs the migrations up to the email column
+	live := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `Users` (`id` bigint PRIMARY KEY, `name` varchar(64), `age` int, `legacy` text);",
+		"CREATE TABLE `audit` (`id` bigint);",
+	)
+	err := live.Use(&rawsql.SchemaAssertion{Tables: schema})
+	mismatch, ok := err.(*rawsql.SchemaMismatchError)
+	if !ok {
+		t.Fatalf("expected a schema mismatch, got %v", err)
+	}
+	if !reflect.DeepEqual(mismatch.Missing, []string{"orders"}) {
+		t.Errorf("expected the orders table missing, got %v", mismatch.Missing)
+	}
+	want := "rawsql: the database does not match the schema, were the migrations run?\n" +
+		"missing tables: `orders`\n" +
+		"`users`\n" +
+		"  + column `email` varchar(128)\n" +
+		"  - column `legacy` text\n" +
+		"  ~ column `name`: nullable NULL -> NOT NULL"
+	if err.Error() != want {
+		t.Errorf("expected the error\n%s\ngot\n%s", want, err)
+	}
+
+	// arrived after the migrations ran
+	live = openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(64) NOT NULL, `age` int, `email` varchar(128));",
+		"CREATE TABLE `orders` (`id` bigint PRIMARY KEY);",
+	)
+	if err := live.Use(&rawsql.SchemaAssertion{Tables: schema}); err != nil {
+		t.Errorf("expected the migrated database to match, got %v", err)
+	}
+
+	live = openSQL(t, rawsql.Config{}, "CREATE TABLE `users` (`id` bigint PRIMARY KEY);")
+	live.Logger = logger.Discard
+	if err := live.Use(&rawsql.SchemaAssertion{Tables: schema, LogOnly: true}); err != nil {
+		t.Errorf("expected the mismatch only logged, got %v", err)
+	}
+}
+
+func TestSchemaAssertionParserFree(t *testing.T) {
+	var _ *meta.SchemaAssertion = &rawsql.SchemaAssertion{}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command")
+	}
+	cmd := exec.Command("go", "list", "-deps", ".")
+	cmd.Dir = "../meta"
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go list -deps: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "gorm.io/gorm/migrator") {
+		t.Fatalf("expected the dependencies of meta, got\n%s", out)
+	}
+	for _, dep := range strings.Fields(string(out)) {
+		if strings.Contains(dep, "pingcap") {
+			t.Errorf("expected the schema assertion of meta not to link the parser, it imports %s", dep)
+		}
+	}
+}
@@ -2,6 +2,10 @@ package tests
 
 import (
 	"fmt"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
 	"testing"
 
 	"gorm.io/gorm"
@@ -39,3 +43,115 @@ func TestSqlGen(t *testing.T) {
 		fmt.Printf("%+v\n\n", v)
 	}
 }
+
+func TestGenEnum(t *testing.T) {
+	table, err := rawsql.ParseTable("CREATE TABLE `orders` (`id` bigint PRIMARY KEY, `status` ENUM('New','in_review','it''s','2fa','', '-') NOT NULL, `flags` SET('a','b'));")
+	if err != nil {
+		t.Fatalf("failed to parse the table, got error %v", err)
+	}
+	status := table.ColumnTypes[1].(*rawsql.ColumnType)
+	if values, ok := status.EnumValues(); !ok || !reflect.DeepEqual(values, []string{"New", "in_review", "it's", "2fa", "", "-"}) {
+		t.Errorf("expected the enum values as declared, got %q %t", values, ok)
+	}
+	if columnType, _ := status.ColumnType(); columnType != "enum('New','in_review','it''s','2fa','','-')" {
+		t.Errorf("expected the enum values to keep their case, got %s", columnType)
+	}
+	if values, ok := table.ColumnTypes[2].(*rawsql.ColumnType).EnumValues(); !ok || len(values) != 2 {
+		t.Errorf("expected the set values, got %q %t", values, ok)
+	}
+	if _, ok := table.ColumnTypes[0].(*rawsql.ColumnType).EnumValues(); ok {
+		t.Errorf("expected no values of a bigint column")
+	}
+
+	name := rawsql.GenEnumName(table, status)
+	if name != "OrdersStatus" {
+		t.Errorf("expected the model and column names, got %s", name)
+	}
+	src, err := rawsql.GenEnum("models", name, status)
+	if err != nil {
+		t.Fatalf("failed to generate the enum, got error %v", err)
+	}
+	for _, want := range []string{
+		"package models\n",
+		"type OrdersStatus string\n",
+		"\tOrdersStatusNew      OrdersStatus = \"New\"\n",
+		"\tOrdersStatusInReview OrdersStatus = \"in_review\"\n",
+		"\tOrdersStatusItS      OrdersStatus = \"it's\"\n",
+		"\tOrdersStatus2fa      OrdersStatus = \"2fa\"\n",
+		"\tOrdersStatusEmpty    OrdersStatus = \"\"\n",
+		"\tOrdersStatusValue6   OrdersStatus = \"-\"\n",
+		"func (v OrdersStatus) Value() (driver.Value, error) {\n",
+		"func (v *OrdersStatus) Scan(src interface{}) error {\n",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("expected the generated source to contain %q, got\n%s", want, src)
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "enum.go", src, 0); err != nil {
+		t.Errorf("expected valid Go source, got error %v", err)
+	}
+	if _, err := rawsql.GenEnum("models", "Flags", table.ColumnTypes[2]); err == nil {
+		t.Errorf("expected an error generating an enum of a SET column")
+	}
+}
+
+func TestGenSet(t *testing.T) {
+	table, err := rawsql.ParseTable("CREATE TABLE `posts` (`id` bigint PRIMARY KEY, `flags` SET('pinned','Locked','all') NOT NULL DEFAULT '', `state` ENUM('a'));")
+	if err != nil {
+		t.Fatalf("failed to parse the table, got error %v", err)
+	}
+	flags := table.ColumnTypes[1]
+	src, err := rawsql.GenSet("models", rawsql.GenEnumName(table, flags), flags)
+	if err != nil {
+		t.Fatalf("failed to generate the set, got error %v", err)
+	}
+	for _, want := range []string{
+		"type PostsFlags uint64\n",
+		"\tPostsFlagsPinned PostsFlags = 1 << iota\n\tPostsFlagsLocked\n\tPostsFlagsAll\n)",
+		"var postsFlagsText = [...]string{\"pinned\", \"Locked\", \"all\"}\n",
+		"func (s PostsFlags) Has(flags PostsFlags) bool {\n",
+		"func (s PostsFlags) Add(flags PostsFlags) PostsFlags {\n",
+		"func (s PostsFlags) Remove(flags PostsFlags) PostsFlags {\n",
+		"\treturn s&^(PostsFlagsPinned|PostsFlagsLocked|PostsFlagsAll) == 0\n",
+		"func ParsePostsFlags(text string) (PostsFlags, error) {\n",
+		"func (s *PostsFlags) Scan(src interface{}) error {\n",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("expected the generated source to contain %q, got\n%s", want, src)
+		}
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "set.go", src, 0); err != nil {
+		t.Errorf("expected valid Go source, got error %v", err)
+	}
+	if _, err := rawsql.GenSet("models", "State", table.ColumnTypes[2]); err == nil {
+		t.Errorf("expected an error generating a set of an ENUM column")
+	}
+}
+
+func TestUUIDColumns(t *testing.T) {
+	schema := "CREATE TABLE `orders` (`id` binary(16) PRIMARY KEY, `Customer_UUID` char(36) NOT NULL, `trace_uuid` varchar(36), `token` char(36), `ref` binary(16));"
+	db := openSQL(t, rawsql.Config{}, schema)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	table := dialector.Parser.GetTables()["orders"]
+	columns := dialector.UUIDColumns(table)
+	if len(columns) != 2 {
+		t.Fatalf("expected the id and customer_uuid columns, got %v", columns)
+	}
+	if id := columns["id"]; !id.Binary || id.GoType != "[16]byte" || !strings.Contains(id.Hint, "BIN_TO_UUID") {
+		t.Errorf("expected a binary uuid column, got %+v", id)
+	}
+	if customer := columns["Customer_UUID"]; customer.Binary || customer.GoType != "uuid.UUID" || customer.Column != "Customer_UUID" {
+		t.Errorf("expected a text uuid column, got %+v", customer)
+	}
+
+	db = openSQL(t, rawsql.Config{UUIDColumnPatterns: []string{"ref", "tok*"}}, schema)
+	dialector = db.Dialector.(*rawsql.Dialector)
+	columns = dialector.UUIDColumns(dialector.Parser.GetTables()["orders"])
+	if _, ok := columns["ref"]; !ok || len(columns) != 2 || columns["token"].GoType != "uuid.UUID" {
+		t.Errorf("expected the columns of the configured patterns, got %v", columns)
+	}
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{SQL: []string{schema}, UUIDColumnPatterns: []string{"["}})); err == nil {
+		t.Errorf("expected a malformed pattern to fail")
+	}
+}
@@ -0,0 +1,217 @@
+package tests
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/logger"
+	"gorm.io/rawsql"
+)
+
+func TestInformationSchema(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	columns := write("columns.csv", "TABLE_SCHEMA,TABLE_NAME,COLUMN_NAME,ORDINAL_POSITION,COLUMN_DEFAULT,IS_NULLABLE,COLUMN_TYPE,CHARACTER_SET_NAME,COLLATION_NAME,COLUMN_KEY,EXTRA,COLUMN_COMMENT\n"+
+		"shop,orders,user_id,2,NULL,YES,bigint unsigned,NULL,NULL,MUL,,\n"+
+		"shop,orders,id,1,NULL,NO,bigint unsigned,NULL,NULL,PRI,auto_increment,\n"+
+		"shop,orders,created_at,3,CURRENT_TIMESTAMP,NO,datetime,NULL,NULL,,DEFAULT_GENERATED,\n"+
+		"shop,users,id,1,NULL,NO,bigint unsigned,NULL,NULL,PRI,auto_increment,\n"+
+		"shop,users,email,2,,NO,varchar(128),utf8mb4,utf8mb4_bin,UNI,,\"login, lower case\"\n"+
+		"other,users,id,1,NULL,NO,int,NULL,NULL,,,\n")
+	statistics := write("statistics.tsv", "TABLE_SCHEMA\tTABLE_NAME\tNON_UNIQUE\tINDEX_NAME\tSEQ_IN_INDEX\tCOLUMN_NAME\tSUB_PART\tINDEX_TYPE\n"+
+		"shop\tusers\t0\tPRIMARY\t1\tid\tNULL\tBTREE\n"+
+		"shop\tusers\t0\tuk_email\t1\temail\t\\N\tBTREE\n"+
+		"shop\torders\t0\tPRIMARY\t1\tid\tNULL\tBTREE\n"+
+		"shop\torders\t1\tidx_user\t1\tuser_id\tNULL\tBTREE\n")
+	keys := write("keys.json", `[
+		{"CONSTRAINT_NAME": "PRIMARY", "TABLE_SCHEMA": "shop", "TABLE_NAME": "orders", "COLUMN_NAME": "id", "ORDINAL_POSITION": 1, "REFERENCED_TABLE_NAME": null},
+		{"CONSTRAINT_NAME": "fk_user", "TABLE_SCHEMA": "shop", "TABLE_NAME": "orders", "COLUMN_NAME": "user_id", "ORDINAL_POSITION": 1, "REFERENCED_TABLE_NAME": "users", "REFERENCED_COLUMN_NAME": "id"}
+	]`)
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{Loaders: []rawsql.Loader{rawsql.InformationSchema{Columns: columns}}})); err == nil {
+		t.Fatalf("expected an error for an export of several schemas")
+	}
+
+	dialector := rawsql.New(rawsql.Config{Strict: true, Loaders: []rawsql.Loader{
+		rawsql.InformationSchema{Columns: columns, Statistics: statistics, KeyColumnUsage: keys, Schema: "shop"},
+	}}).(*rawsql.Dialector)
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"orders", "users"}) {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+
+	users, _ := db.Migrator().ColumnTypes("users")
+	if tag := rawsql.GormTag(users[0]); tag != "column:id;type:bigint(20) unsigned;primaryKey;autoIncrement" {
+		t.Errorf("unexpected id %s", tag)
+	}
+	if tag := rawsql.GormTag(users[1]); tag != "column:email;type:varchar(128);not null;default:'';comment:login, lower case" {
+		t.Errorf("unexpected email %s", tag)
+	}
+	if collation, _ := users[1].(*rawsql.ColumnType).Collation(); collation != "utf8mb4_bin" {
+		t.Errorf("unexpected collation %s", collation)
+	}
+	orders, _ := db.Migrator().ColumnTypes("orders")
+	if names := []string{orders[0].Name(), orders[1].Name(), orders[2].Name()}; !reflect.DeepEqual(names, []string{"id", "user_id", "created_at"}) {
+		t.Errorf("columns not in ordinal order: %v", names)
+	}
+	if def, _ := orders[2].DefaultValue(); def != "CURRENT_TIMESTAMP" {
+		t.Errorf("unexpected default %q", def)
+	}
+
+	table := dialector.Parser.GetTables()["orders"]
+	if len(table.Indexes) < 2 || table.Indexes[1].Name() != "idx_user" {
+		t.Errorf("unexpected indexes %v", table.Indexes)
+	}
+	if len(table.ForeignKeys) != 1 || table.ForeignKeys[0].Name != "fk_user" || table.ForeignKeys[0].ReferencedTable != "users" {
+		t.Errorf("unexpected foreign keys %+v", table.ForeignKeys)
+	}
+}
+
+// shadowDriver is a database/sql driver standing for a scratch MySQL: it
+// records the statements executed and answers the information_schema queries
+// with the rows of the query's table
+type shadowDriver struct {
+	executed []string
+	tables   map[string][]map[string]driver.Value
+}
+
+func (d *shadowDriver) Open(string) (driver.Conn, error) { return &shadowConn{d}, nil }
+
+type shadowConn struct{ d *shadowDriver }
+
+func (c *shadowConn) Prepare(query string) (driver.Stmt, error) { return &shadowStmt{c.d, query}, nil }
+func (c *shadowConn) Close() error                              { return nil }
+func (c *shadowConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type shadowStmt struct {
+	d     *shadowDriver
+	query string
+}
+
+func (s *shadowStmt) Close() error  { return nil }
+func (s *shadowStmt) NumInput() int { return -1 }
+func (s *shadowStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.executed = append(s.d.executed, s.query)
+	return driver.RowsAffected(0), nil
+}
+
+func (s *shadowStmt) Query([]driver.Value) (driver.Rows, error) {
+	for _, table := range []string{"COLUMNS", "STATISTICS", "KEY_COLUMN_USAGE", "TABLES"} {
+		if strings.Contains(s.query, "information_schema."+table+" ") {
+			rows := s.d.tables[table]
+			columns := map[string]bool{}
+			for _, row := range rows {
+				for column := range row {
+					columns[column] = true
+				}
+			}
+			r := &shadowRows{rows: rows}
+			for column := range columns {
+				r.columns = append(r.columns, column)
+			}
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected query %s", s.query)
+}
+
+type shadowRows struct {
+	columns []string
+	rows    []map[string]driver.Value
+}
+
+func (r *shadowRows) Columns() []string { return r.columns }
+func (r *shadowRows) Close() error      { return nil }
+func (r *shadowRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	for i, column := range r.columns {
+		dest[i] = r.rows[0][column]
+	}
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestShadowVerify(t *testing.T) {
+	column := func(table, name string, position int, columnType, nullable, key, extra string) map[string]driver.Value {
+		return map[string]driver.Value{"TABLE_NAME": table, "COLUMN_NAME": name, "ORDINAL_POSITION": fmt.Sprint(position),
+			"COLUMN_TYPE": columnType, "DATA_TYPE": strings.Fields(columnType)[0], "IS_NULLABLE": nullable, "COLUMN_KEY": key, "EXTRA": extra, "COLUMN_COMMENT": ""}
+	}
+	index := func(table, name string, unique bool, position int, column string) map[string]driver.Value {
+		nonUnique := "1"
+		if unique {
+			nonUnique = "0"
+		}
+		return map[string]driver.Value{"TABLE_NAME": table, "INDEX_NAME": name, "NON_UNIQUE": nonUnique, "SEQ_IN_INDEX": fmt.Sprint(position), "COLUMN_NAME": column, "INDEX_TYPE": "BTREE"}
+	}
+	shadow := &shadowDriver{tables: map[string][]map[string]driver.Value{
+		"COLUMNS": {
+			column("teams", "id", 1, "bigint", "NO", "PRI", "auto_increment"),
+			column("users", "id", 1, "bigint", "NO", "PRI", "auto_increment"),
+			column("users", "email", 2, "varchar(64)", "NO", "UNI", ""),
+			// the server widened the column
+			column("users", "team_id", 3, "bigint", "YES", "MUL", ""),
+			column("audit", "id", 1, "bigint", "NO", "", ""),
+		},
+		"STATISTICS": {
+			index("teams", "PRIMARY", true, 1, "id"),
+			index("users", "PRIMARY", true, 1, "id"),
+			index("users", "email", true, 1, "email"),
+			index("users", "fk_team", false, 1, "team_id"),
+		},
+		"KEY_COLUMN_USAGE": {
+			{"TABLE_NAME": "users", "CONSTRAINT_NAME": "fk_team", "COLUMN_NAME": "team_id", "ORDINAL_POSITION": "1",
+				"REFERENCED_TABLE_NAME": "teams", "REFERENCED_COLUMN_NAME": "id", "UPDATE_RULE": "RESTRICT", "DELETE_RULE": "CASCADE"},
+		},
+		"TABLES": {
+			{"TABLE_NAME": "teams", "ENGINE": "InnoDB", "TABLE_COLLATION": "utf8mb4_0900_ai_ci", "TABLE_COMMENT": ""},
+			{"TABLE_NAME": "users", "ENGINE": "InnoDB", "TABLE_COLLATION": "utf8mb4_0900_ai_ci", "TABLE_COMMENT": ""},
+		},
+	}}
+	sql.Register("rawsql-shadow", shadow)
+	pool, err := sql.Open("rawsql-shadow", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := openSQL(t, rawsql.Config{})
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	db.ConnPool, db.Statement.ConnPool, db.Logger = pool, pool, logger.Discard
+
+	ddl := "CREATE TABLE `teams` (`id` bigint AUTO_INCREMENT PRIMARY KEY) ENGINE=InnoDB;\n" +
+		"CREATE TABLE `users` (`id` bigint AUTO_INCREMENT PRIMARY KEY, `email` varchar(64) NOT NULL UNIQUE, `team_id` int, " +
+		"CONSTRAINT `fk_team` FOREIGN KEY (`team_id`) REFERENCES `teams` (`id`) ON DELETE CASCADE) ENGINE=InnoDB;\n" +
+		"CREATE TABLE `sessions` (`id` bigint);"
+	report, err := rawsql.Shadow{DB: db}.Verify(ddl)
+	if err != nil {
+		t.Fatalf("failed to verify, got error %v", err)
+	}
+	if len(shadow.executed) != 3 || !strings.HasPrefix(shadow.executed[2], "CREATE TABLE `sessions`") {
+		t.Errorf("expected every statement executed, got %v", shadow.executed)
+	}
+	if report.OK() || !reflect.DeepEqual(report.Missing, []string{"sessions"}) || !reflect.DeepEqual(report.Extra, []string{"audit"}) {
+		t.Errorf("expected sessions missing and audit extra, got %v and %v", report.Missing, report.Extra)
+	}
+	if len(report.Diffs) != 1 || report.Diffs[0].String() != "`users`\n  ~ column `team_id`: type int(11) -> bigint(20) [safe]\n" {
+		t.Errorf("expected the users team_id difference only, got %v", report.Diffs)
+	}
+}
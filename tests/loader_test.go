@@ -0,0 +1,264 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/rawsql"
+)
+
+func TestLoadFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/01_users.sql":  {Data: []byte("CREATE TABLE `users` (`id` bigint NOT NULL, PRIMARY KEY (`id`));")},
+		"migrations/02_orders.sql": {Data: []byte("CREATE TABLE `orders` (`id` bigint NOT NULL, `user_id` bigint);")},
+		"other/ignored.txt":        {Data: []byte("not sql")},
+	}
+
+	db, err := gorm.Open(rawsql.New(rawsql.Config{
+		FS:         fsys,
+		FSPatterns: []string{"migrations/*.sql"},
+	}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatalf("get tables: %v", err)
+	}
+	sort.Strings(tables)
+	if len(tables) != 2 || tables[0] != "orders" || tables[1] != "users" {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+}
+
+func TestLoadFromReader(t *testing.T) {
+	r := strings.NewReader("CREATE TABLE `users` (`id` bigint NOT NULL, `name` varchar(64));")
+
+	db, err := gorm.Open(rawsql.New(rawsql.Config{Readers: []io.Reader{r}}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	cols, err := db.Migrator().ColumnTypes("users")
+	if err != nil {
+		t.Fatalf("column types: %v", err)
+	}
+	if len(cols) != 2 || cols[1].Name() != "name" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+}
+
+func TestLoadDirNumericOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"2_create.sql":       "CREATE TABLE `users` (`id` bigint NOT NULL);",
+		"10_alter.sql":       "ALTER TABLE `users` ADD COLUMN `name` varchar(64);",
+		"nested/11_more.sql": "ALTER TABLE `users` ADD COLUMN `age` int;",
+		"notes.txt":          "ALTER TABLE `missing` ADD COLUMN `x` int;",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := gorm.Open(rawsql.New(rawsql.Config{
+		DirPath:   []string{dir},
+		FileOrder: rawsql.NumericPrefixOrder,
+	}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	cols, err := db.Migrator().ColumnTypes("users")
+	if err != nil {
+		t.Fatalf("column types: %v", err)
+	}
+	if len(cols) != 3 || cols[1].Name() != "name" || cols[2].Name() != "age" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+}
+
+func TestNumericPrefixOrder(t *testing.T) {
+	files := []string{"10_b.sql", "2_a.sql", "a/1.sql", "1_c.sql", "readme.sql"}
+	sort.SliceStable(files, func(i, j int) bool { return rawsql.NumericPrefixOrder(files[i], files[j]) })
+	want := []string{"1_c.sql", "2_a.sql", "10_b.sql", "a/1.sql", "readme.sql"}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Fatalf("got %v, want %v", files, want)
+		}
+	}
+}
+
+func TestLoadCompressed(t *testing.T) {
+	dir := t.TempDir()
+
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("CREATE TABLE `users` (`id` bigint NOT NULL);"))
+	gw.Close()
+	if err := os.WriteFile(filepath.Join(dir, "01_users.sql.gz"), gz.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var zb bytes.Buffer
+	zw := zip.NewWriter(&zb)
+	for name, content := range map[string]string{
+		"migrations/01_alter.sql":  "ALTER TABLE `users` ADD COLUMN `name` varchar(64);",
+		"migrations/02_orders.sql": "CREATE TABLE `orders` (`id` bigint NOT NULL);",
+		"migrations/readme.md":     "# not sql",
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	if err := os.WriteFile(filepath.Join(dir, "02_migrations.zip"), zb.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := gorm.Open(rawsql.New(rawsql.Config{DirPath: []string{dir}}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	tables, _ := db.Migrator().GetTables()
+	if len(tables) != 2 {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+	cols, _ := db.Migrator().ColumnTypes("users")
+	if len(cols) != 2 || cols[1].Name() != "name" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+}
+
+func TestLoadRemote(t *testing.T) {
+	schema := []byte("CREATE TABLE `users` (`id` bigint NOT NULL);")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(schema)
+	}))
+	defer srv.Close()
+
+	sum := sha256.Sum256(schema)
+	db, err := gorm.Open(rawsql.New(rawsql.Config{
+		Remote: []rawsql.RemoteSource{{URL: srv.URL + "/schema.sql", SHA256: hex.EncodeToString(sum[:])}},
+	}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if tables, _ := db.Migrator().GetTables(); len(tables) != 1 || tables[0] != "users" {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+
+	_, err = gorm.Open(rawsql.New(rawsql.Config{
+		Remote: []rawsql.RemoteSource{{URL: srv.URL + "/schema.sql", SHA256: "00"}},
+	}))
+	if err == nil {
+		t.Fatal("expected checksum mismatch error")
+	}
+}
+
+func TestLoadFromLoader(t *testing.T) {
+	loader := rawsql.LoaderFunc(func(ctx context.Context) ([]rawsql.Source, error) {
+		return []rawsql.Source{
+			{Name: "users.sql", Content: []byte("CREATE TABLE `users` (`id` bigint NOT NULL);")},
+		}, nil
+	})
+
+	db, err := gorm.Open(rawsql.New(rawsql.Config{Loaders: []rawsql.Loader{loader}}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if tables, _ := db.Migrator().GetTables(); len(tables) != 1 || tables[0] != "users" {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+
+	failing := rawsql.LoaderFunc(func(ctx context.Context) ([]rawsql.Source, error) {
+		return nil, errors.New("boom")
+	})
+	if _, err = gorm.Open(rawsql.New(rawsql.Config{Loaders: []rawsql.Loader{failing}})); err == nil {
+		t.Fatal("expected loader error")
+	}
+}
+
+func TestWatchReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "schema.sql")
+	if err := os.WriteFile(path, []byte("CREATE TABLE `users` (`id` bigint NOT NULL);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := make(chan error, 8)
+	dialector := rawsql.New(rawsql.Config{
+		DirPath:  []string{dir},
+		Watch:    true,
+		OnReload: func(tables map[string]*rawsql.Table, err error) { reloaded <- err },
+	})
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer dialector.(*rawsql.Dialector).Close()
+
+	schema := "CREATE TABLE `users` (`id` bigint NOT NULL);\nCREATE TABLE `orders` (`id` bigint NOT NULL);"
+	if err = os.WriteFile(path, []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err = <-reloaded:
+		if err != nil {
+			t.Fatalf("reload: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+	if tables, _ := db.Migrator().GetTables(); len(tables) != 2 {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+}
+
+func TestParallelParse(t *testing.T) {
+	open := func(parallelism int) *gorm.DB {
+		db, err := gorm.Open(rawsql.New(rawsql.Config{FilePath: []string{"./sql"}, Parallelism: parallelism}))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		return db
+	}
+
+	sequential, parallel := open(0), open(4)
+	got, _ := parallel.Migrator().GetTables()
+	want, _ := sequential.Migrator().GetTables()
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got tables %v, want %v", got, want)
+	}
+	for _, table := range want {
+		wantCols, _ := sequential.Migrator().ColumnTypes(table)
+		gotCols, _ := parallel.Migrator().ColumnTypes(table)
+		assertSameColumns(t, wantCols, gotCols)
+	}
+}
@@ -0,0 +1,1627 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/rawsql"
+	"gorm.io/rawsql/backendtest"
+	"gorm.io/rawsql/meta"
+	"gorm.io/rawsql/rawsqlconform"
+)
+
+func openSQL(t *testing.T, config rawsql.Config, sql ...string) *gorm.DB {
+	t.Helper()
+	config.SQL = append(config.SQL, sql...)
+	db, err := gorm.Open(rawsql.New(config))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return db
+}
+
+func sortedTables(db *gorm.DB) []string {
+	tables, _ := db.Migrator().GetTables()
+	sort.Strings(tables)
+	return tables
+}
+
+func TestInjectTiDBParser(t *testing.T) {
+	created := 0
+	db := openSQL(t, rawsql.Config{
+		NewTiDBParser: func() *parser.Parser {
+			created++
+			p := parser.New()
+			p.SetSQLMode(mysql.ModeANSIQuotes)
+			return p
+		},
+	}, `CREATE TABLE "users" ("id" bigint NOT NULL)`, `ALTER TABLE "users" ADD COLUMN "name" varchar(64)`)
+
+	if created == 0 {
+		t.Error("expected the injected parser factory to be used")
+	}
+	cols, _ := db.Migrator().ColumnTypes("users")
+	if len(cols) != 2 || cols[1].Name() != "name" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{
+		FilePath: []string{"./sql"},
+		SQL:      []string{"SET NAMES utf8mb4; INSERT INTO t VALUES (1);"},
+		SkipDML:  true,
+	}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	report := dialector.Report()
+	if report == nil {
+		t.Fatal("expected a report")
+	}
+	want := rawsql.ParseStats{Statements: 11, Skipped: 2, Tables: 5, Columns: 46, Indexes: 8}
+	if report.ParseStats != want {
+		t.Errorf("got stats %+v, want %+v", report.ParseStats, want)
+	}
+	names := make([]string, 0, len(report.Files))
+	for _, f := range report.Files {
+		names = append(names, f.Name)
+	}
+	wantNames := []string{"sql[0]", "sql/01_tables.sql", "sql/02_user.sql", "sql/03_drop.sql"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("got files %v, want %v", names, wantNames)
+	}
+}
+
+func TestSQLMode(t *testing.T) {
+	db := openSQL(t, rawsql.Config{SQLMode: "ANSI_QUOTES,NO_BACKSLASH_ESCAPES"},
+		`CREATE TABLE "users" ("id" bigint NOT NULL, "path" varchar(64) COMMENT 'C:\temp')`)
+
+	cols, _ := db.Migrator().ColumnTypes("users")
+	if len(cols) != 2 {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+	if comment, _ := cols[1].Comment(); comment != `C:\temp` {
+		t.Errorf("got comment %q, want backslash kept", comment)
+	}
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{SQLMode: "NOT_A_MODE"})); err == nil {
+		t.Error("expected invalid sql mode error")
+	}
+}
+
+func TestStrictUnsupported(t *testing.T) {
+	sql := "CREATE TABLE `users` (`id` bigint NOT NULL);\n" +
+		"CREATE VIEW `v` AS SELECT 1;\n" +
+		"ALTER TABLE `users` ADD COLUMN `name` varchar(64), ORDER BY `name`;"
+
+	dialector := rawsql.New(rawsql.Config{SQL: []string{sql}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("permissive open: %v", err)
+	}
+	warnings := dialector.Report().Warnings
+	if len(warnings) != 2 || warnings[0].Source != "sql[0]" ||
+		warnings[0].Message != "unsupported statement" ||
+		warnings[1].Message != "unsupported alter table clause ORDER BY `name`" {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+
+	_, err := gorm.Open(rawsql.New(rawsql.Config{SQL: []string{sql}, Strict: true}))
+	if !errors.Is(err, rawsql.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported in strict mode, got %v", err)
+	}
+}
+
+func TestAlterDropColumn(t *testing.T) {
+	db := openSQL(t, rawsql.Config{Strict: true},
+		"CREATE TABLE `t` (`id` bigint NOT NULL, `a` int, `b` int);",
+		"ALTER TABLE `t` DROP COLUMN `a`, DROP COLUMN IF EXISTS `missing`;",
+	)
+	columns, _ := db.Migrator().ColumnTypes("t")
+	var names []string
+	for _, ct := range columns {
+		names = append(names, ct.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"id", "b"}) {
+		t.Fatalf("expected a dropped, got columns %v", names)
+	}
+	if position := columns[1].(*rawsql.ColumnType).OrdinalPosition(); position != 2 {
+		t.Errorf("expected b renumbered to 2, got %d", position)
+	}
+
+	table, _ := rawsql.ParseTable("CREATE TABLE `t` (`id` bigint, `a` int);")
+	if err := table.ApplyAlter("ALTER TABLE `t` DROP COLUMN `a`"); err != nil || len(table.ColumnTypes) != 1 {
+		t.Errorf("expected ApplyAlter to drop a, got %d columns, error %v", len(table.ColumnTypes), err)
+	}
+
+	_, err := gorm.Open(rawsql.New(rawsql.Config{Strict: true, SQL: []string{
+		"CREATE TABLE `t` (`id` bigint);", "ALTER TABLE `t` DROP COLUMN `missing`;",
+	}}))
+	if !errors.Is(err, rawsql.ErrUnsupported) {
+		t.Errorf("expected dropping a missing column to fail in strict mode, got %v", err)
+	}
+}
+
+func TestForeignKeyChecks(t *testing.T) {
+	sql := "SET NAMES utf8mb4;\n" +
+		"/*!40014 SET FOREIGN_KEY_CHECKS=0 */;\n" +
+		"SET @saved = 1;\n" +
+		"CREATE TABLE `orders` (`id` bigint NOT NULL, `user_id` bigint,\n" +
+		"  CONSTRAINT `fk_orders_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE);\n" +
+		"CREATE TABLE `users` (`id` bigint NOT NULL);\n" +
+		"SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS;\n" +
+		"CREATE TABLE `items` (`order_id` bigint, FOREIGN KEY (`order_id`) REFERENCES `missing` (`id`));"
+
+	dialector := rawsql.New(rawsql.Config{SQL: []string{sql}, HonorForeignKeyChecks: true}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if warnings := dialector.Report().Warnings; len(warnings) != 1 || !strings.Contains(warnings[0].Message, "missing") {
+		t.Fatalf("expected a single warning for the missing table, got %v", warnings)
+	}
+
+	fks := dialector.Parser.GetTables()["orders"].ForeignKeys
+	want := []rawsql.ForeignKey{{
+		Name: "fk_orders_user", Columns: []string{"user_id"},
+		ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: "CASCADE",
+	}}
+	if !reflect.DeepEqual(fks, want) {
+		t.Fatalf("got foreign keys %+v, want %+v", fks, want)
+	}
+
+	dialector = rawsql.New(rawsql.Config{SQL: []string{sql}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if warnings := dialector.Report().Warnings; len(warnings) != 2 {
+		t.Fatalf("expected FOREIGN_KEY_CHECKS to be ignored by default, got %v", warnings)
+	}
+}
+
+func TestSkipTemporaryTables(t *testing.T) {
+	sql := "CREATE TABLE `users` (`id` bigint NOT NULL, `name` varchar(64));\n" +
+		"CREATE TEMPORARY TABLE `tmp_backfill` (`id` bigint);\n" +
+		"ALTER TABLE `tmp_backfill` ADD COLUMN `name` varchar(64);\n" +
+		"CREATE TEMPORARY TABLE `users` (`id` bigint);\n" +
+		"ALTER TABLE `users` ADD COLUMN `tmp` int;\n" +
+		"DROP TEMPORARY TABLE `users`;\n" +
+		"DROP TEMPORARY TABLE IF EXISTS `tmp_backfill`;\n" +
+		"ALTER TABLE `users` ADD COLUMN `email` varchar(128);"
+
+	db := openSQL(t, rawsql.Config{SkipTemporaryTables: true}, sql)
+	if tables, _ := db.Migrator().GetTables(); !reflect.DeepEqual(tables, []string{"users"}) {
+		t.Fatalf("expected only users, got %v", tables)
+	}
+	columns, _ := db.Migrator().ColumnTypes("users")
+	var names []string
+	for _, c := range columns {
+		names = append(names, c.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"id", "name", "email"}) {
+		t.Fatalf("temporary table statements leaked into users: %v", names)
+	}
+
+	db = openSQL(t, rawsql.Config{}, "CREATE TEMPORARY TABLE `tmp` (`id` bigint);")
+	if tables, _ := db.Migrator().GetTables(); !reflect.DeepEqual(tables, []string{"tmp"}) {
+		t.Fatalf("expected temporary tables to be kept by default, got %v", tables)
+	}
+}
+
+func TestSkipGhostTables(t *testing.T) {
+	sql := "CREATE TABLE `users` (`id` bigint NOT NULL);\n" +
+		"CREATE TABLE `_users_gho` (`id` bigint NOT NULL);\n" +
+		"ALTER TABLE `_users_gho` ADD COLUMN `email` varchar(128);\n" +
+		"CREATE TABLE `_users_ghc` (`id` bigint NOT NULL);\n" +
+		"CREATE TABLE `_orders_old` (`id` bigint NOT NULL);\n" +
+		"DROP TABLE `_orders_old`, `_never_created_del`;\n" +
+		"CREATE TABLE `user_old` (`id` bigint NOT NULL);"
+
+	db := openSQL(t, rawsql.Config{SkipGhostTables: true}, sql)
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"user_old", "users"}) {
+		t.Fatalf("expected ghost tables to be skipped, got %v", tables)
+	}
+
+	db = openSQL(t, rawsql.Config{SkipGhostTables: true, GhostTablePatterns: []string{"user_*"}},
+		"CREATE TABLE `users` (`id` bigint NOT NULL);",
+		"CREATE TABLE `_users_gho` (`id` bigint NOT NULL);",
+		"CREATE TABLE `user_old` (`id` bigint NOT NULL);")
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"_users_gho", "users"}) {
+		t.Fatalf("expected custom patterns to replace the defaults, got %v", tables)
+	}
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{SkipGhostTables: true, GhostTablePatterns: []string{"["}})); err == nil {
+		t.Fatalf("expected an error for a malformed pattern")
+	}
+}
+
+func TestTableRawSQL(t *testing.T) {
+	create := "CREATE TABLE `users` (\n  `id` bigint NOT NULL -- the key\n) COMMENT 'ユーザー'"
+	alter := "ALTER TABLE `users` ADD COLUMN `email` varchar(128)"
+	dialector := rawsql.New(rawsql.Config{
+		SQL: []string{create + ";\nCREATE TABLE `orders` (`id` bigint);\n" + alter + ";"},
+	}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	tables := dialector.Parser.GetTables()
+	if got, want := tables["users"].RawSQL, create+";\n"+alter+";"; got != want {
+		t.Fatalf("got raw sql %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := rawsql.EncodeSnapshot(&buf, tables); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	decoded, err := rawsql.DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded["orders"].RawSQL != tables["orders"].RawSQL {
+		t.Fatalf("raw sql lost in snapshot: %q", decoded["orders"].RawSQL)
+	}
+}
+
+func TestColumnReferences(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{SQL: []string{
+		"CREATE TABLE `users` (`id` bigint NOT NULL);\n" +
+			"CREATE TABLE `orders` (`id` bigint NOT NULL, `user_id` bigint REFERENCES `users` (`id`) ON UPDATE RESTRICT,\n" +
+			"  `buyer_id` bigint, FOREIGN KEY (`buyer_id`) REFERENCES `users` (`id`));\n" +
+			"ALTER TABLE `orders` ADD COLUMN `seller_id` bigint REFERENCES `users` (`id`) ON DELETE SET NULL;",
+	}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	want := []rawsql.ForeignKey{
+		{Name: "orders_ibfk_1", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnUpdate: "RESTRICT"},
+		{Name: "orders_ibfk_2", Columns: []string{"buyer_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+		{Name: "orders_ibfk_3", Columns: []string{"seller_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}, OnDelete: "SET NULL"},
+	}
+	if got := dialector.Parser.GetTables()["orders"].ForeignKeys; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got foreign keys %+v, want %+v", got, want)
+	}
+}
+
+func TestTableAutoIncrement(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{SQL: []string{
+		"CREATE TABLE `users` (`id` bigint NOT NULL AUTO_INCREMENT, PRIMARY KEY (`id`)) ENGINE=InnoDB AUTO_INCREMENT=1042;",
+		"CREATE TABLE `tags` (`name` varchar(32));",
+	}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	tables := dialector.Parser.GetTables()
+	if tables["users"].AutoIncrement != 1042 {
+		t.Fatalf("got AUTO_INCREMENT %d", tables["users"].AutoIncrement)
+	}
+	if column, ok := tables["users"].AutoIncrementColumn(); !ok || column.Name() != "id" {
+		t.Fatalf("expected id to be the auto increment column")
+	}
+	if _, ok := tables["tags"].AutoIncrementColumn(); ok || tables["tags"].AutoIncrement != 0 {
+		t.Fatalf("tags has no auto increment column")
+	}
+}
+
+func TestAlterTableOptions(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{SQL: []string{
+		"CREATE TABLE `users` (`id` bigint AUTO_INCREMENT, PRIMARY KEY (`id`)) ENGINE=MyISAM ROW_FORMAT=COMPACT COMMENT='people';",
+		"ALTER TABLE `users` AUTO_INCREMENT = 100;",
+		"ALTER TABLE `users` ENGINE=InnoDB, CHARSET=utf8mb4, ROW_FORMAT=DYNAMIC, KEY_BLOCK_SIZE=8, COMMENT 'all users';",
+	}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if warnings := dialector.Report().Warnings; len(warnings) != 0 {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+
+	users := dialector.Parser.GetTables()["users"]
+	if users.AutoIncrement != 100 || users.Engine != "InnoDB" || users.Charset != "utf8mb4" || users.Comment != "all users" {
+		t.Fatalf("options not applied: %d %s %s %s", users.AutoIncrement, users.Engine, users.Charset, users.Comment)
+	}
+	if want := map[string]string{"ROW_FORMAT": "DYNAMIC", "KEY_BLOCK_SIZE": "8"}; !reflect.DeepEqual(users.Options, want) {
+		t.Fatalf("got options %v, want %v", users.Options, want)
+	}
+}
+
+func TestAlterAlgorithmAndLock(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{Strict: true, SQL: []string{
+		"CREATE TABLE `users` (`id` bigint);",
+		"ALTER TABLE `users` ADD COLUMN `email` varchar(128), ALGORITHM=INPLACE, LOCK=NONE;",
+		"ALTER TABLE `users` ALGORITHM=INSTANT, ADD COLUMN `age` int AFTER `id`;",
+		"ALTER TABLE `users` MODIFY `age` bigint;",
+	}}).(*rawsql.Dialector)
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	columns, _ := db.Migrator().ColumnTypes("users")
+	var names []string
+	for _, column := range columns {
+		names = append(names, column.Name())
+	}
+	if want := []string{"id", "age", "email"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got columns %v, want %v", names, want)
+	}
+	want := []rawsql.AlterHint{{Algorithm: "INPLACE", Lock: "NONE"}, {Algorithm: "INSTANT"}}
+	if hints := dialector.Parser.GetTables()["users"].AlterHints; !reflect.DeepEqual(hints, want) {
+		t.Fatalf("got hints %v, want %v", hints, want)
+	}
+}
+
+func TestDropTable(t *testing.T) {
+	schema := []string{
+		"CREATE TABLE `users` (`id` bigint NOT NULL);",
+		"CREATE TABLE `orders` (`id` bigint, `user_id` bigint, CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`));",
+		"CREATE TABLE `logs` (`id` bigint);",
+	}
+	open := func(config rawsql.Config, drop string) (*rawsql.Dialector, error) {
+		config.SQL = append(append([]string(nil), schema...), drop)
+		dialector := rawsql.New(config).(*rawsql.Dialector)
+		_, err := gorm.Open(dialector)
+		return dialector, err
+	}
+
+	dialector, err := open(rawsql.Config{Strict: true}, "DROP TABLE IF EXISTS `logs`, `missing`;")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, ok := dialector.Parser.GetTables()["logs"]; ok {
+		t.Fatalf("logs should be dropped")
+	}
+	if warnings := dialector.Report().Warnings; len(warnings) != 1 || !warnings[0].Notice {
+		t.Fatalf("expected a notice for the missing table, got %v", warnings)
+	}
+
+	dialector, _ = open(rawsql.Config{}, "DROP TABLE `users`;")
+	if fks := dialector.Parser.GetTables()["orders"].ForeignKeys; len(fks) != 1 {
+		t.Fatalf("foreign keys should be kept by default, got %v", fks)
+	}
+	if warnings := dialector.Report().Warnings; len(warnings) != 1 || warnings[0].Notice || !strings.Contains(warnings[0].Message, "fk_user") {
+		t.Fatalf("expected a warning for the dangling foreign key, got %v", warnings)
+	}
+
+	for _, config := range []rawsql.Config{{DroppedReferences: rawsql.RemoveForeignKeys}, {}} {
+		drop := "DROP TABLE `users`;"
+		if config.DroppedReferences == rawsql.KeepForeignKeys {
+			drop = "DROP TABLE `users` CASCADE;"
+		}
+		dialector, _ = open(config, drop)
+		if fks := dialector.Parser.GetTables()["orders"].ForeignKeys; len(fks) != 0 {
+			t.Fatalf("%s: foreign keys should be removed, got %v", drop, fks)
+		}
+		if warnings := dialector.Report().Warnings; len(warnings) != 1 || !warnings[0].Notice {
+			t.Fatalf("%s: expected a notice for the removed foreign key, got %v", drop, warnings)
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("expected dropping a missing table to fail")
+			}
+		}()
+		dialector, _ = open(rawsql.Config{}, "DROP TABLE `logs`, `missing`;")
+	}()
+	if _, ok := dialector.Parser.GetTables()["logs"]; !ok {
+		t.Fatalf("a failed DROP TABLE should not drop any table")
+	}
+}
+
+func TestTablesInDependencyOrder(t *testing.T) {
+	db := openSQL(t, rawsql.Config{HonorForeignKeyChecks: true},
+		"SET FOREIGN_KEY_CHECKS = 0;",
+		"CREATE TABLE `order_items` (`order_id` bigint REFERENCES `orders` (`id`), `product_id` bigint REFERENCES `products` (`id`));",
+		"CREATE TABLE `orders` (`id` bigint, `user_id` bigint REFERENCES `users` (`id`));",
+		"CREATE TABLE `users` (`id` bigint, `manager_id` bigint REFERENCES `users` (`id`));",
+		"CREATE TABLE `products` (`id` bigint);",
+	)
+	tables, err := db.Dialector.(*rawsql.Dialector).TablesInDependencyOrder()
+	if err != nil {
+		t.Fatalf("order: %v", err)
+	}
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		names = append(names, table.Name)
+	}
+	if expected := []string{"products", "users", "orders", "order_items"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+
+	db = openSQL(t, rawsql.Config{HonorForeignKeyChecks: true},
+		"SET FOREIGN_KEY_CHECKS = 0;",
+		"CREATE TABLE `a` (`b_id` bigint REFERENCES `b` (`id`));",
+		"CREATE TABLE `b` (`id` bigint, `c_id` bigint REFERENCES `c` (`id`));",
+		"CREATE TABLE `c` (`id` bigint, `b_id` bigint REFERENCES `b` (`id`));",
+	)
+	_, err = db.Dialector.(*rawsql.Dialector).TablesInDependencyOrder()
+	var cycle *rawsql.CycleError
+	if !errors.As(err, &cycle) || !reflect.DeepEqual(cycle.Tables, []string{"b", "c"}) {
+		t.Fatalf("expected the cycle b -> c, got %v", err)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint, `manager_id` bigint REFERENCES `users` (`id`));",
+		"CREATE TABLE `orders` (`id` bigint, `user_id` bigint REFERENCES `users` (`id`));",
+		"CREATE TABLE `order_items` (`order_id` bigint REFERENCES `orders` (`id`));",
+		"CREATE TABLE `coupons` (`user_id` bigint REFERENCES `users` (`id`));",
+		"CREATE TABLE `logs` (`id` bigint);",
+	)
+	graph := db.Dialector.(*rawsql.Dialector).Graph()
+	if edges := graph.Edges(); len(edges) != 4 || edges[0].From != "coupons" || edges[0].To != "users" {
+		t.Fatalf("unexpected edges %v", edges)
+	}
+	if referencing := graph.Referencing("users"); !reflect.DeepEqual(referencing, []string{"coupons", "orders"}) {
+		t.Fatalf("unexpected tables referencing users %v", referencing)
+	}
+	if referenced := graph.Referenced("order_items"); !reflect.DeepEqual(referenced, []string{"orders"}) {
+		t.Fatalf("unexpected tables referenced by order_items %v", referenced)
+	}
+	if dependents := graph.Dependents("users"); !reflect.DeepEqual(dependents, []string{"coupons", "order_items", "orders"}) {
+		t.Fatalf("unexpected dependents of users %v", dependents)
+	}
+	if dependencies := graph.Dependencies("order_items"); !reflect.DeepEqual(dependencies, []string{"orders", "users"}) {
+		t.Fatalf("unexpected dependencies of order_items %v", dependencies)
+	}
+	expected := [][]string{{"coupons", "order_items", "orders", "users"}, {"logs"}}
+	if components := graph.Components(); !reflect.DeepEqual(components, expected) {
+		t.Fatalf("expected components %v, got %v", expected, components)
+	}
+}
+
+func TestRenameHistory(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{SQL: []string{
+		"CREATE TABLE `users` (`id` bigint, `mail` varchar(64), UNIQUE KEY `idx_mail` (`mail`));",
+		"CREATE TABLE `orders` (`id` bigint, `user_id` bigint, CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`));",
+		"ALTER TABLE `users` CHANGE `mail` `email` varchar(128);",
+		"ALTER TABLE `users` RENAME COLUMN `email` TO `primary_email`, RENAME COLUMN `id` TO `user_id`;",
+		"RENAME TABLE `users` TO `members`;",
+		"ALTER TABLE `members` RENAME TO `accounts`;",
+	}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	tables := dialector.Parser.GetTables()
+	accounts, ok := tables["accounts"]
+	if !ok || len(tables) != 2 {
+		t.Fatalf("expected users renamed to accounts, got %v", tables)
+	}
+	if !reflect.DeepEqual(accounts.PreviousNames, []string{"users", "members"}) {
+		t.Fatalf("unexpected previous names %v", accounts.PreviousNames)
+	}
+	expected := []rawsql.Rename{{From: "mail", To: "email"}, {From: "email", To: "primary_email"}, {From: "id", To: "user_id"}}
+	if !reflect.DeepEqual(accounts.ColumnRenames, expected) {
+		t.Fatalf("expected column renames %v, got %v", expected, accounts.ColumnRenames)
+	}
+	if name := accounts.CurrentColumnName("mail"); name != "primary_email" {
+		t.Fatalf("expected mail to be primary_email now, got %s", name)
+	}
+	if columns := accounts.Indexes[0].Columns(); !reflect.DeepEqual(columns, []string{"primary_email"}) || accounts.Indexes[0].Table() != "accounts" {
+		t.Fatalf("index not renamed: %s %v", accounts.Indexes[0].Table(), columns)
+	}
+	if fk := tables["orders"].ForeignKeys[0]; fk.ReferencedTable != "accounts" || !reflect.DeepEqual(fk.ReferencedColumns, []string{"user_id"}) {
+		t.Fatalf("foreign key not renamed: %+v", fk)
+	}
+	if !strings.Contains(accounts.RawSQL, "\nRENAME TABLE `users` TO `members`;\nALTER TABLE `members` RENAME TO `accounts`;") {
+		t.Fatalf("unexpected raw sql %s", accounts.RawSQL)
+	}
+}
+
+type fakeParser struct {
+	tables map[string]*rawsql.Table
+	stats  rawsql.ParseStats
+}
+
+func (p *fakeParser) ParseSQL(sql string) error {
+	for _, name := range strings.Fields(sql) {
+		p.tables[name] = &rawsql.Table{Name: name}
+		p.stats.Statements++
+		p.stats.Tables++
+	}
+	return nil
+}
+
+func (p *fakeParser) GetTables() map[string]*rawsql.Table { return p.tables }
+func (p *fakeParser) Stats() rawsql.ParseStats            { return p.stats }
+func (p *fakeParser) Warnings() []rawsql.Warning          { return nil }
+
+func TestParserBackend(t *testing.T) {
+	rawsql.RegisterBackend("fake", func(config *rawsql.Config) rawsql.Parser {
+		return &fakeParser{tables: map[string]*rawsql.Table{}}
+	})
+	if backends := rawsql.Backends(); !reflect.DeepEqual(backends, []string{"fake", rawsql.TiDBBackend}) {
+		t.Fatalf("unexpected backends %v", backends)
+	}
+
+	dialector := rawsql.New(rawsql.Config{Backend: "fake", SQL: []string{"users orders", "items"}}).(*rawsql.Dialector)
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"items", "orders", "users"}) {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+	if report := dialector.Report(); report.Tables != 3 || len(report.Files) != 2 || report.Files[0].Tables != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	if _, err = gorm.Open(rawsql.New(rawsql.Config{Backend: "missing"})); err == nil {
+		t.Fatalf("expected an unknown backend error")
+	}
+}
+
+// nocommentParser is a backend losing the column comments, which the
+// conformance harness has to catch
+type nocommentParser struct {
+	sql []string
+}
+
+func (p *nocommentParser) ParseSQL(sql string) error {
+	p.sql = append(p.sql, sql)
+	return nil
+}
+
+func (p *nocommentParser) GetTables() map[string]*rawsql.Table {
+	dialector := rawsql.New(rawsql.Config{SQL: p.sql}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		return nil
+	}
+	tables := dialector.Parser.GetTables()
+	for _, table := range tables {
+		for _, ct := range table.ColumnTypes {
+			ct.(*rawsql.ColumnType).CommentValue = sql.NullString{}
+		}
+	}
+	return tables
+}
+
+func TestBackendHarness(t *testing.T) {
+	rawsql.RegisterBackend("nocomment", func(config *rawsql.Config) rawsql.Parser {
+		return &nocommentParser{}
+	})
+	diffs, err := backendtest.Compare("nocomment")
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	if _, ok := diffs["columns"]; !ok || len(diffs) != 1 {
+		t.Errorf("expected the lost comment of the columns entry reported, got %v", diffs)
+	}
+	if diffs, err = backendtest.Compare(rawsql.TiDBBackend); err != nil || len(diffs) > 0 {
+		t.Errorf("expected the TiDB backend to conform to itself, got %v, error %v", diffs, err)
+	}
+}
+
+func TestImportFromDB(t *testing.T) {
+	live := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint unsigned NOT NULL AUTO_INCREMENT, `name` varchar(64) COMMENT 'display name', PRIMARY KEY (`id`), KEY `idx_name` (`name`)) COMMENT='people';",
+		"CREATE TABLE `logs` (`id` bigint);",
+	)
+	parser, err := rawsql.ImportFromDB(live, "users")
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	dialector := rawsql.New(rawsql.Config{Parser: parser, SQLMode: "ANSI_QUOTES", SQL: []string{
+		`ALTER TABLE "users" ADD COLUMN "email" varchar(128) AFTER "id", RENAME COLUMN "name" TO "nickname";`,
+	}}).(*rawsql.Dialector)
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"users"}) {
+		t.Fatalf("unexpected tables %v", tables)
+	}
+	table := dialector.Parser.GetTables()["users"]
+	if table.Comment != "people" || len(table.ColumnTypes) != 3 {
+		t.Fatalf("unexpected table %+v", table)
+	}
+	if tag := rawsql.GormTag(table.ColumnTypes[0]); tag != "column:id;type:bigint(20) unsigned;primaryKey;autoIncrement" {
+		t.Errorf("unexpected id %s", tag)
+	}
+	if ct := table.ColumnTypes[2].(*rawsql.ColumnType); ct.Name() != "nickname" || ct.OrdinalPosition() != 3 {
+		t.Errorf("unexpected column %s at %d", ct.Name(), ct.OrdinalPosition())
+	}
+	if columns := table.Indexes[len(table.Indexes)-1].Columns(); !reflect.DeepEqual(columns, []string{"nickname"}) {
+		t.Errorf("index not renamed: %v", columns)
+	}
+}
+
+func TestIndexOptions(t *testing.T) {
+	sql := "CREATE TABLE `posts` (`id` bigint NOT NULL, `user_id` bigint, `body` text, PRIMARY KEY (`id`), " +
+		"KEY `idx_user` (`user_id`) KEY_BLOCK_SIZE=8 COMMENT 'by author', " +
+		"FULLTEXT KEY `ft_body` (`body`) WITH PARSER ngram COMMENT 'search');"
+	dialector := rawsql.New(rawsql.Config{SQL: []string{sql}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	check := func(tables map[string]*rawsql.Table) {
+		t.Helper()
+		indexes := map[string]*rawsql.Index{}
+		for _, idx := range tables["posts"].Indexes {
+			indexes[idx.Name()] = idx.(*rawsql.Index)
+		}
+		if idx := indexes["idx_user"]; idx.Comment() != "by author" || idx.Class() != "" {
+			t.Errorf("unexpected idx_user %+v", idx)
+		} else if size, ok := idx.KeyBlockSize(); !ok || size != 8 {
+			t.Errorf("unexpected idx_user key block size %d", size)
+		}
+		if idx := indexes["ft_body"]; idx.Comment() != "search" || idx.Class() != "FULLTEXT" || idx.Parser() != "ngram" {
+			t.Errorf("unexpected ft_body %+v", idx)
+		} else if _, ok := idx.KeyBlockSize(); ok {
+			t.Errorf("ft_body has no key block size")
+		}
+	}
+	check(dialector.Parser.GetTables())
+
+	var b bytes.Buffer
+	if err := rawsql.EncodeBinarySnapshot(&b, dialector.Parser.GetTables()); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	tables, err := rawsql.DecodeBinarySnapshot(&b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	check(tables)
+
+	exported := rawsql.New(rawsql.Config{SQL: []string{rawsql.CreateTableSQL(tables["posts"])}}).(*rawsql.Dialector)
+	if _, err = gorm.Open(exported); err != nil {
+		t.Fatalf("open exported: %v", err)
+	}
+	check(exported.Parser.GetTables())
+}
+
+func TestConstraintNameAndUsing(t *testing.T) {
+	sql := "CREATE TABLE `t` (`a` int NOT NULL, `b` int, `c` int, CONSTRAINT `pk_t` PRIMARY KEY USING HASH (`a`), " +
+		"CONSTRAINT `uk_sym` UNIQUE KEY `uk_b` (`b`) USING BTREE, CONSTRAINT uk_c UNIQUE (`c`), UNIQUE KEY `uk_bc` (`b`, `c`));"
+	dialector := rawsql.New(rawsql.Config{SQL: []string{sql}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	check := func(tables map[string]*rawsql.Table) {
+		t.Helper()
+		var got []string
+		for _, idx := range tables["t"].Indexes {
+			idx := idx.(*rawsql.Index)
+			got = append(got, idx.Name()+"/"+idx.Constraint()+"/"+idx.Using())
+		}
+		if want := []string{"pk_t/pk_t/HASH", "uk_b/uk_sym/BTREE", "uk_c/uk_c/", "uk_bc//"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got indexes %v, want %v", got, want)
+		}
+	}
+	check(dialector.Parser.GetTables())
+
+	var b bytes.Buffer
+	if err := rawsql.EncodeSnapshot(&b, dialector.Parser.GetTables()); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	tables, err := rawsql.DecodeSnapshot(&b)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	check(tables)
+
+	exported := rawsql.New(rawsql.Config{SQL: []string{rawsql.CreateTableSQL(tables["t"])}}).(*rawsql.Dialector)
+	if _, err = gorm.Open(exported); err != nil {
+		t.Fatalf("open exported: %v", err)
+	}
+	check(exported.Parser.GetTables())
+}
+
+func TestOnStatement(t *testing.T) {
+	var kinds, raws []string
+	config := rawsql.Config{CacheDir: t.TempDir(), OnStatement: func(stmt ast.StmtNode, raw string) bool {
+		switch stmt.(type) {
+		case *ast.CreateTableStmt:
+			kinds = append(kinds, "create")
+		case *ast.AlterTableStmt:
+			kinds = append(kinds, "alter")
+		default:
+			kinds = append(kinds, "other")
+		}
+		raws = append(raws, raw)
+		return true
+	}}
+	sql := []string{
+		"CREATE TABLE `users` (`id` bigint, `secret` varchar(64) INVISIBLE);",
+		"SET FOREIGN_KEY_CHECKS = 0;\nALTER TABLE `users` ADD COLUMN `name` varchar(64);",
+	}
+	for i := 0; i < 2; i++ {
+		kinds, raws = nil, nil
+		db := openSQL(t, config, sql...)
+		if columns, _ := db.Migrator().ColumnTypes("users"); len(columns) != 3 {
+			t.Fatalf("the statements should still be applied")
+		}
+		if want := []string{"create", "other", "alter"}; !reflect.DeepEqual(kinds, want) {
+			t.Fatalf("run %d: got statements %v, want %v", i, kinds, want)
+		}
+		if raws[0] != "CREATE TABLE `users` (`id` bigint, `secret` varchar(64) INVISIBLE)" {
+			t.Errorf("got raw text %q", raws[0])
+		}
+	}
+
+	// returning false skips the statement
+	skipping := rawsql.New(rawsql.Config{SQL: sql, OnStatement: func(stmt ast.StmtNode, raw string) bool {
+		_, alter := stmt.(*ast.AlterTableStmt)
+		return !alter
+	}}).(*rawsql.Dialector)
+	db, err := gorm.Open(skipping)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if columns, _ := db.Migrator().ColumnTypes("users"); len(columns) != 2 {
+		t.Errorf("expected the ALTER TABLE skipped, got %d columns", len(columns))
+	}
+	// the SET statement and the ALTER TABLE
+	if report := skipping.Report(); report.Skipped != 2 || report.Statements != 3 {
+		t.Errorf("expected 2 of 3 statements skipped, got %d of %d", report.Skipped, report.Statements)
+	}
+}
+
+// recordingLogger keeps the messages logged at each level
+type recordingLogger struct {
+	infos, warns []string
+}
+
+func (l *recordingLogger) LogMode(logger.LogLevel) logger.Interface { return l }
+
+func (l *recordingLogger) Info(_ context.Context, msg string, data ...interface{}) {
+	l.infos = append(l.infos, fmt.Sprintf(msg, data...))
+}
+
+func (l *recordingLogger) Warn(_ context.Context, msg string, data ...interface{}) {
+	l.warns = append(l.warns, fmt.Sprintf(msg, data...))
+}
+
+func (l *recordingLogger) Error(_ context.Context, msg string, data ...interface{}) {}
+
+func (l *recordingLogger) Trace(context.Context, time.Time, func() (string, int64), error) {}
+
+func TestLogger(t *testing.T) {
+	log := &recordingLogger{}
+	openSQL(t, rawsql.Config{Logger: log, SkipGhostTables: true},
+		"CREATE TABLE `users` (`id` bigint);",
+		"CREATE TABLE `_users_gho` (`id` bigint);\nALTER TABLE `users` ADD COLUMN `name` varchar(64), ENGINE=InnoDB;",
+		"CREATE VIEW `v` AS SELECT 1;",
+	)
+	infos := strings.Join(log.infos, "\n")
+	for _, want := range []string{
+		"rawsql: skipped ghost table: CREATE TABLE `_users_gho`",
+		"rawsql: applied ALTER TABLE `users` ADD COLUMN `name` varchar(64), ENGINE=InnoDB",
+		"rawsql: parsed sql[1] in ",
+		"rawsql: parsed 1 tables from 4 statements in ",
+	} {
+		if !strings.Contains(infos, want) {
+			t.Errorf("missing %q in\n%s", want, infos)
+		}
+	}
+	if len(log.warns) != 1 || !strings.Contains(log.warns[0], "unsupported statement: CREATE VIEW") {
+		t.Errorf("unexpected warnings %q", log.warns)
+	}
+}
+
+type recordingMetrics struct {
+	parsed, skipped, failed, durations int
+}
+
+func (m *recordingMetrics) StatementsParsed(n int)      { m.parsed += n }
+func (m *recordingMetrics) StatementsSkipped(n int)     { m.skipped += n }
+func (m *recordingMetrics) StatementsFailed(n int)      { m.failed += n }
+func (m *recordingMetrics) ParseDuration(time.Duration) { m.durations++ }
+
+func TestMetrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	openSQL(t, rawsql.Config{Metrics: metrics, SkipDML: true},
+		"CREATE TABLE `users` (`id` bigint);\nINSERT INTO `users` VALUES (1);",
+		"SET NAMES utf8mb4;\nALTER TABLE `users` ADD COLUMN `name` varchar(64);",
+	)
+	if *metrics != (recordingMetrics{parsed: 2, skipped: 2, durations: 1}) {
+		t.Errorf("unexpected metrics %+v", *metrics)
+	}
+
+	_, err := gorm.Open(rawsql.New(rawsql.Config{Metrics: metrics, SQL: []string{"CREATE TABLE `t` (`id` bigint);", "CREATE TABLE `broken` (`id` bigint"}}))
+	if err == nil {
+		t.Fatalf("expected a syntax error")
+	}
+	if *metrics != (recordingMetrics{parsed: 3, skipped: 2, failed: 1, durations: 2}) {
+		t.Errorf("unexpected metrics after a failed load %+v", *metrics)
+	}
+}
+
+type spanKey struct{}
+
+// recordingTracer keeps every span as its path from the root span
+type recordingTracer struct {
+	ended []string
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	path   string
+	attrs  map[string]interface{}
+	err    error
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, rawsql.Span) {
+	if parent, ok := ctx.Value(spanKey{}).(*recordingSpan); ok {
+		name = parent.path + "/" + name
+	}
+	span := &recordingSpan{tracer: r, path: name, attrs: map[string]interface{}{}}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *recordingSpan) RecordError(err error)                      { s.err = err }
+
+func (s *recordingSpan) End() {
+	path := s.path
+	if source, ok := s.attrs["rawsql.source"]; ok {
+		path += fmt.Sprintf("(%v)", source)
+	}
+	if statement, ok := s.attrs["rawsql.statement"]; ok {
+		path += fmt.Sprintf("[%v]", statement)
+	}
+	if s.err != nil {
+		path += " error"
+	}
+	s.tracer.ended = append(s.tracer.ended, path)
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	openSQL(t, rawsql.Config{Tracer: tracer}, "CREATE TABLE `users` (`id` bigint);", "ALTER TABLE `users` ADD COLUMN `name` text;")
+	want := []string{
+		"rawsql.load/rawsql.read",
+		"rawsql.load/rawsql.parse/rawsql.statement[CREATE TABLE `users` (`id` bigint);]",
+		"rawsql.load/rawsql.parse(sql[0])",
+		"rawsql.load/rawsql.parse/rawsql.statement[ALTER TABLE `users` ADD COLUMN `name` text;]",
+		"rawsql.load/rawsql.parse(sql[1])",
+		"rawsql.load/rawsql.build",
+		"rawsql.load",
+	}
+	if !reflect.DeepEqual(tracer.ended, want) {
+		t.Errorf("got spans %v, want %v", tracer.ended, want)
+	}
+
+	tracer.ended = nil
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{Tracer: tracer, SQL: []string{"CREATE TABLE `broken` ("}})); err == nil {
+		t.Fatalf("expected a syntax error")
+	}
+	want = []string{"rawsql.load/rawsql.read", "rawsql.load/rawsql.parse(sql[0]) error", "rawsql.load error"}
+	if !reflect.DeepEqual(tracer.ended, want) {
+		t.Errorf("got spans %v, want %v", tracer.ended, want)
+	}
+
+	tracer.ended = nil
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{Tracer: tracer, BaselineLock: true, SQL: []string{"CREATE TABLE `a` (`id` int);", "CREATE TABLE `a` (`id` bigint);"}})); err == nil {
+		t.Fatalf("expected a baseline error")
+	}
+	if got := tracer.ended[len(tracer.ended)-3]; got != "rawsql.load/rawsql.parse/rawsql.statement[CREATE TABLE `a` (`id` bigint);] error" {
+		t.Errorf("expected the failing statement span to record the error, got spans %v", tracer.ended)
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	table, err := rawsql.ParseTable("CREATE TABLE `orders` (`id` bigint PRIMARY KEY, `user_id` bigint,\n" +
+		"  CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`)) COMMENT 'placed orders';")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	if table.Name != "orders" || table.Comment != "placed orders" || len(table.ColumnTypes) != 2 || len(table.ForeignKeys) != 1 {
+		t.Errorf("unexpected table %+v", table)
+	}
+
+	for _, sql := range []string{
+		"CREATE TABLE `a` (`id` int); CREATE TABLE `b` (`id` int);",
+		"ALTER TABLE `a` ADD COLUMN `name` text;",
+		"",
+	} {
+		if _, err := rawsql.ParseTable(sql); err == nil {
+			t.Errorf("expected an error parsing %q", sql)
+		}
+	}
+	if _, err := rawsql.ParseTable("CREATE TABLE `broken` (`id` bigint"); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
+
+func TestApplyAlter(t *testing.T) {
+	table, err := rawsql.ParseTable("CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(32)) CHARSET=latin1;")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	var buf bytes.Buffer
+	if err = rawsql.EncodeSnapshot(&buf, map[string]*rawsql.Table{"users": table}); err != nil {
+		t.Fatalf("failed to encode snapshot, got error %v", err)
+	}
+	tables, err := rawsql.DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("failed to decode snapshot, got error %v", err)
+	}
+	table = tables["users"]
+
+	if err = table.ApplyAlter("ALTER TABLE `users` ADD COLUMN `email` text FIRST, RENAME COLUMN `name` TO `nick`, RENAME TO `members`;"); err != nil {
+		t.Fatalf("failed to apply alter, got error %v", err)
+	}
+	var names []string
+	for _, column := range table.ColumnTypes {
+		names = append(names, column.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"email", "id", "nick"}) {
+		t.Errorf("got columns %v", names)
+	}
+	if charset, _ := table.ColumnTypes[0].(*rawsql.ColumnType).Charset(); charset != "latin1" {
+		t.Errorf("expected the added column to inherit latin1, got %q", charset)
+	}
+	if table.Name != "members" || !reflect.DeepEqual(table.PreviousNames, []string{"users"}) {
+		t.Errorf("got name %s, previous names %v", table.Name, table.PreviousNames)
+	}
+
+	for _, sql := range []string{
+		"ALTER TABLE `users` ADD COLUMN `age` int;",
+		"CREATE TABLE `members` (`id` int);",
+		"ALTER TABLE `members` DROP COLUMN `nick`; ALTER TABLE `members` ADD COLUMN `age` int;",
+		"ALTER TABLE `members` ADD COLUMN `age`",
+	} {
+		if err := table.ApplyAlter(sql); err == nil {
+			t.Errorf("expected an error applying %q", sql)
+		}
+	}
+
+	// meta has no parser, its tables apply statements with the Alterer given
+	buf.Reset()
+	rawsql.EncodeSnapshot(&buf, map[string]*rawsql.Table{"members": table})
+	decoded, err := meta.DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("failed to decode snapshot, got error %v", err)
+	}
+	table = decoded["members"]
+	if err = table.ApplyAlter("ALTER TABLE `members` DROP COLUMN `nick`;"); err == nil {
+		t.Errorf("expected an error applying without an Alterer")
+	}
+	table.Alterer = rawsql.ApplyAlter
+	if err = table.ApplyAlter("ALTER TABLE `members` DROP COLUMN `nick`;"); err != nil || len(table.ColumnTypes) != 2 {
+		t.Errorf("expected nick dropped, got %d columns, error %v", len(table.ColumnTypes), err)
+	}
+}
+
+func TestValidateDDL(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `email` varchar(64), `name` text, KEY `idx_email` (`email`));",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	issues, err := dialector.ValidateDDL("ALTER TABLE `users` MODIFY COLUMN `missing` int, ADD COLUMN `age` int NOT NULL, ADD COLUMN `score` int NOT NULL DEFAULT 0;\n" +
+		"ALTER TABLE `users` DROP COLUMN `email`, ADD COLUMN `nick` text AFTER `nope`;\n" +
+		"ALTER TABLE `users` CHANGE COLUMN `name` `full_name` text, DROP COLUMN IF EXISTS `name`, ADD COLUMN `name` text;\n" +
+		"ALTER TABLE `orders` ADD COLUMN `total` int;\n" +
+		"CREATE TABLE `users` (`id` int);")
+	if err != nil {
+		t.Fatalf("failed to validate, got error %v", err)
+	}
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	want := []string{
+		"unknown column missing",
+		"NOT NULL column age added without a default, existing rows need a value",
+		"dropped column email is part of index idx_email",
+		"unknown column nope",
+		"table orders not exists",
+		"duplicated table users",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("got issues %q", messages)
+	}
+
+	columns, _ := db.Migrator().ColumnTypes("users")
+	if len(columns) != 3 {
+		t.Errorf("expected the parsed tables untouched, got %d columns", len(columns))
+	}
+	if _, err = dialector.ValidateDDL("ALTER TABLE `users` ADD"); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
+
+func TestCheckQuery(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint AUTO_INCREMENT PRIMARY KEY, `email` varchar(64) NOT NULL, `name` text, `status` enum('Active','Banned') NOT NULL DEFAULT 'Active', `created_at` datetime NOT NULL);",
+		"CREATE TABLE `orders` (`id` bigint AUTO_INCREMENT PRIMARY KEY, `user_id` bigint NOT NULL, `total` decimal(10,2) NOT NULL DEFAULT 0);",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	clean := []string{
+		"SELECT u.id, COUNT(*) AS n FROM users u JOIN orders o ON o.user_id = u.id WHERE u.status = 'Banned' GROUP BY u.id HAVING n > 1 ORDER BY n;",
+		"SELECT email FROM users WHERE id IN (SELECT user_id FROM orders WHERE total > 10 AND orders.user_id = users.id);",
+		"WITH big AS (SELECT user_id, total AS amount FROM orders) SELECT b.amount, x.email FROM big b, (SELECT id, email FROM users) x WHERE x.id = b.user_id;",
+		"SELECT id FROM users UNION SELECT user_id FROM orders ORDER BY id;",
+		"SELECT id FROM users JOIN orders USING (id) WHERE created_at > '2024-01-01 10:00:00';",
+		"SELECT id, email, total FROM users NATURAL JOIN orders;",
+		"SELECT id FROM users NATURAL LEFT JOIN orders WHERE user_id IS NULL;",
+		"INSERT INTO users (email, created_at) VALUES ('a@b.c', NOW()) ON DUPLICATE KEY UPDATE name = VALUES(name);",
+		"INSERT INTO orders VALUES (NULL, 1, 2.5);",
+		"INSERT INTO orders (user_id) SELECT id FROM users;",
+		"UPDATE users u JOIN orders o ON o.user_id = u.id SET u.name = 'x' WHERE o.total > '1.5';",
+		"DELETE FROM orders WHERE user_id = 1 ORDER BY id LIMIT 10;",
+		"UPDATE users SET name = ?, created_at = ? WHERE id = ? AND email <> ?;",
+		"SELECT 1;",
+		"CREATE TABLE `t` (`id` int);",
+	}
+	for _, sql := range clean {
+		if issues, err := dialector.CheckQuery(sql); err != nil || len(issues) > 0 {
+			t.Errorf("%s: expected no issues, got %v, error %v", sql, issues, err)
+		}
+	}
+
+	issues, err := dialector.CheckQuery("SELECT nope FROM users;\n" +
+		"SELECT u.nope, x.id FROM users u;\n" +
+		"SELECT id FROM users, orders;\n" +
+		"SELECT email FROM users WHERE id = 'abc' AND created_at < 'yesterday' AND status = 'deleted' AND email = 1 AND name = NULL;\n" +
+		"SELECT email AS mail FROM users WHERE mail = '';\n" +
+		"INSERT INTO users (name) VALUES ('x');\n" +
+		"INSERT INTO orders VALUES (1, 2);\n" +
+		"INSERT INTO orders (id, user_id) SELECT id, id, id FROM users;\n" +
+		"UPDATE orders SET user_id = NULL, total = 'free', missing = 1;\n" +
+		"DELETE FROM archive WHERE archive.id = 1;")
+	if err != nil {
+		t.Fatalf("failed to check, got error %v", err)
+	}
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	want := []string{
+		"unknown column nope",
+		"unknown column u.nope",
+		"unknown table x of column x.id",
+		"ambiguous column id, of users and orders",
+		"column id is bigint, \"abc\" is no number",
+		"column created_at is datetime, \"yesterday\" is no date",
+		"\"deleted\" is no value of the ENUM column status",
+		"column email is varchar, comparing it with the number 1 converts its values and skips its indexes",
+		"comparison of column name with NULL is never true, use IS NULL",
+		"unknown column mail",
+		"NOT NULL column email without a default is left out",
+		"NOT NULL column created_at without a default is left out",
+		"2 values for 3 columns",
+		"3 selected columns for 2 columns",
+		"NULL for NOT NULL column user_id",
+		"column total is decimal, \"free\" is no number",
+		"unknown column missing",
+		"unknown table archive",
+	}
+	if !reflect.DeepEqual(messages, want) {
+		t.Errorf("got issues %q", messages)
+	}
+	if issues[0].String() != "unknown column nope: SELECT nope FROM users;" {
+		t.Errorf("got issue %s", issues[0])
+	}
+
+	if _, err = dialector.CheckQuery("SELECT FROM"); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+}
+
+func TestDescribeQuery(t *testing.T) {
+	db := openSQL(t, rawsql.Config{NullableScanType: rawsql.NullableAsSQLNull},
+		"CREATE TABLE `users` (`id` bigint AUTO_INCREMENT PRIMARY KEY, `email` varchar(64) NOT NULL, `name` text, `created_at` datetime NOT NULL);",
+		"CREATE TABLE `orders` (`id` bigint AUTO_INCREMENT PRIMARY KEY, `user_id` bigint NOT NULL, `total` decimal(10,2) NOT NULL DEFAULT 0);",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	goTypes := func(values []rawsql.QueryValue) (types []string) {
+		for _, v := range values {
+			types = append(types, v.Name+" "+v.GoType())
+		}
+		return types
+	}
+
+	description, err := dialector.DescribeQuery("SELECT u.id, u.name AS full_name, o.total, COUNT(*) AS n, MAX(o.id) AS last FROM users u LEFT JOIN orders o ON o.user_id = u.id " +
+		"WHERE u.email LIKE ? AND u.name = ? AND u.created_at BETWEEN ? AND ? AND o.id IN (?, 1) GROUP BY u.id LIMIT ?")
+	if err != nil {
+		t.Fatalf("failed to describe, got error %v", err)
+	}
+	if got, want := goTypes(description.Params), []string{" string", "name string", "created_at time.Time", "created_at time.Time", "id int64", " int64"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got params %q", got)
+	}
+	if got, want := goTypes(description.Columns), []string{"id int64", "full_name sql.NullString", "total sql.NullString", "n int64", "last sql.NullInt64"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got columns %q", got)
+	}
+	if c := description.Columns[2]; c.Table != "orders" || c.Column != "total" || !c.Nullable {
+		t.Errorf("expected total of the LEFT JOIN nullable, got %+v", c)
+	}
+	if len(description.Issues) > 0 {
+		t.Errorf("expected no issues, got %v", description.Issues)
+	}
+
+	// as MySQL reports them, fields without alias are named after their text
+	description, err = dialector.DescribeQuery("SELECT COUNT(*), MAX(o.id), LOWER(u.email) FROM users u JOIN orders o ON o.user_id = u.id")
+	if err != nil {
+		t.Fatalf("failed to describe, got error %v", err)
+	}
+	if got, want := goTypes(description.Columns), []string{"COUNT(*) int64", "MAX(o.id) sql.NullInt64", "LOWER(u.email) interface{}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got columns %q", got)
+	}
+
+	// placeholders compared with select fields through their HAVING alias and
+	// with the elements of row constructors
+	description, err = dialector.DescribeQuery("SELECT u.id, COUNT(*) AS n, MAX(o.total) AS top FROM users u JOIN orders o ON o.user_id = u.id GROUP BY u.id HAVING n > ? AND top >= ?")
+	if err != nil {
+		t.Fatalf("failed to describe, got error %v", err)
+	}
+	if got, want := goTypes(description.Params), []string{" int64", "total string"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got params %q", got)
+	}
+	description, err = dialector.DescribeQuery("SELECT id FROM users WHERE (email, created_at) = (?, ?) AND (id, name) IN ((?, ?), (1, 'a'))")
+	if err != nil {
+		t.Fatalf("failed to describe, got error %v", err)
+	}
+	if got, want := goTypes(description.Params), []string{"email string", "created_at time.Time", "id int64", "name string"}; !reflect.DeepEqual(got, want) || len(description.Issues) > 0 {
+		t.Errorf("got params %q, issues %v", got, description.Issues)
+	}
+
+	description, err = dialector.DescribeQuery("INSERT INTO users (email, name, created_at) VALUES (?, ?, NOW()) ON DUPLICATE KEY UPDATE name = ?")
+	if err != nil {
+		t.Fatalf("failed to describe, got error %v", err)
+	}
+	if got, want := goTypes(description.Params), []string{"email string", "name sql.NullString", "name sql.NullString"}; !reflect.DeepEqual(got, want) || description.Columns != nil {
+		t.Errorf("got params %q, columns %v", got, description.Columns)
+	}
+
+	description, err = dialector.DescribeQuery("SELECT * FROM users WHERE missing = ? AND id = ABS(?)")
+	if err != nil {
+		t.Fatalf("failed to describe, got error %v", err)
+	}
+	if got, want := goTypes(description.Params), []string{" interface{}", " interface{}"}; !reflect.DeepEqual(got, want) || len(description.Issues) != 1 {
+		t.Errorf("got params %q, issues %v", got, description.Issues)
+	}
+	if got, want := goTypes(description.Columns), []string{"id int64", "email string", "name sql.NullString", "created_at time.Time"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got columns %q", got)
+	}
+
+	if _, err = dialector.DescribeQuery("SELECT 1; SELECT 2"); err == nil {
+		t.Errorf("expected an error for several statements")
+	}
+}
+
+func TestValidateIdentifiers(t *testing.T) {
+	long := strings.Repeat("x", 65)
+	sql := "CREATE TABLE `rank` (`id` bigint PRIMARY KEY, `select` int, `" + long + "` int, `lead` int, KEY `order` (`lead`));"
+	for _, c := range []struct {
+		version string
+		want    []string
+	}{
+		{"", []string{
+			"rank: table name rank is a reserved word in mysql-8.0",
+			"rank: column name select is a reserved word in mysql-8.0",
+			"rank: column name " + long + " is longer than 64 characters",
+			"rank: column name lead is a reserved word in mysql-8.0",
+			"rank: index name order is a reserved word in mysql-8.0",
+		}},
+		{"mysql-5.7", []string{
+			"rank: column name select is a reserved word in mysql-5.7",
+			"rank: column name " + long + " is longer than 64 characters",
+			"rank: index name order is a reserved word in mysql-5.7",
+		}},
+	} {
+		db := openSQL(t, rawsql.Config{TargetVersion: c.version}, sql)
+		var got []string
+		for _, issue := range db.Dialector.(*rawsql.Dialector).ValidateIdentifiers() {
+			got = append(got, issue.String())
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got issues %q", c.version, got)
+		}
+	}
+
+	table, _ := rawsql.ParseTable("CREATE TABLE `t` (`key` int);")
+	if issues, err := rawsql.ValidateIdentifiers(map[string]*rawsql.Table{"t": table}, "tidb-7.5"); err != nil || len(issues) != 1 {
+		t.Errorf("expected key to be reserved in tidb, got %v, error %v", issues, err)
+	}
+	if _, err := rawsql.ValidateIdentifiers(nil, "oracle-19"); err == nil {
+		t.Errorf("expected an invalid version error")
+	}
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{TargetVersion: "8.x"})); err == nil {
+		t.Errorf("expected Initialize to reject the target version")
+	}
+}
+
+func TestTargetVersion(t *testing.T) {
+	sql := []string{
+		"CREATE TABLE `events` (`id` binary(16) DEFAULT (UUID_TO_BIN(UUID())), `at` datetime DEFAULT CURRENT_TIMESTAMP,\n" +
+			"  `n` int DEFAULT -1 CHECK (`n` > -2), KEY `idx_at` (`at` DESC), CONSTRAINT `positive` CHECK (`n` > 0));",
+		"ALTER TABLE `events` ADD COLUMN `code` char(36) DEFAULT (UUID());",
+	}
+	for _, c := range []struct {
+		version string
+		want    []string
+	}{
+		{"", nil},
+		{"mysql-8.0", nil},
+		{"mysql-5.7", []string{
+			"expression default of column id needs mysql-8.0.13, the target is mysql-5.7",
+			"CHECK column n is not enforced by mysql-5.7",
+			"descending index column at is ignored by mysql-5.7",
+			"CHECK constraint positive is not enforced by mysql-5.7",
+			"expression default of column code needs mysql-8.0.13, the target is mysql-5.7",
+		}},
+		{"8.0.13", []string{
+			"CHECK column n is not enforced by mysql-8.0.13",
+			"CHECK constraint positive is not enforced by mysql-8.0.13",
+		}},
+		{"tidb-7.1", []string{
+			"CHECK column n is not enforced by tidb-7.1",
+			"descending index column at is ignored by tidb-7.1",
+			"CHECK constraint positive is not enforced by tidb-7.1",
+		}},
+		{"tidb-7.5", []string{"descending index column at is ignored by tidb-7.5"}},
+	} {
+		db := openSQL(t, rawsql.Config{TargetVersion: c.version}, sql...)
+		var got []string
+		for _, w := range db.Dialector.(*rawsql.Dialector).Report().Warnings {
+			got = append(got, w.Message)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: got warnings %q", c.version, got)
+		}
+	}
+}
+
+func TestRowSizeLimits(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `wide` (`id` bigint PRIMARY KEY, `a` varchar(10000), `b` varchar(10000), `body` longtext) CHARSET=utf8mb4;",
+		"CREATE TABLE `keys` (`id` bigint, `name` varchar(1000), `slug` varchar(1000), `note` text,\n"+
+			"  PRIMARY KEY (`id`), KEY `idx_name` (`name`), KEY `idx_prefix` (`name`(100), `slug`(100)), KEY `idx_note` (`note`(200)),\n"+
+			"  FULLTEXT KEY `ft_note` (`note`)) CHARSET=utf8mb4;",
+		"CREATE TABLE `compact` (`id` bigint PRIMARY KEY, `name` varchar(255), KEY `idx_name` (`name`)) CHARSET=utf8mb4 ROW_FORMAT=COMPACT;",
+		"CREATE TABLE `fits` (`id` bigint PRIMARY KEY, `a` decimal(20,6), `b` datetime(6), `c` bit(12), `d` varchar(16000) CHARSET latin1) CHARSET=utf8mb4;",
+	)
+	var got []string
+	for _, issue := range db.Dialector.(*rawsql.Dialector).Lint(rawsql.RowSizeLimits) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"compact: key column name of index idx_name takes 1020 bytes, the COMPACT row format allows 767",
+		"keys: estimated key size of index idx_name of 4000 bytes exceeds the 3072 bytes InnoDB allows",
+		"wide: estimated row size of 80025 bytes exceeds the 65535 bytes InnoDB allows",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues %q", got)
+	}
+
+	exported := rawsql.CreateTableSQL(db.Dialector.(*rawsql.Dialector).Parser.GetTables()["keys"])
+	if !strings.Contains(exported, "KEY `idx_prefix` (`name`(100), `slug`(100))") {
+		t.Errorf("expected the prefix lengths exported, got %s", exported)
+	}
+}
+
+func TestNamingConventions(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `Email` varchar(64), `name` varchar(64),\n"+
+			"  UNIQUE KEY `uk_email` (`Email`), UNIQUE KEY `name_unique` (`name`), KEY `idx_name` (`name`), KEY `byName` (`name`));",
+		"CREATE TABLE `Orders` (`id` bigint PRIMARY KEY, `user_id` bigint,\n"+
+			"  CONSTRAINT `fk_orders_users` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`),\n"+
+			"  CONSTRAINT `orders_user` FOREIGN KEY (`id`) REFERENCES `users` (`id`));",
+	)
+	rule, err := rawsql.NamingConventions(rawsql.NamingRules{
+		SnakeCase: true, IndexPrefix: "idx_", UniquePrefix: "uk_", ForeignKeyPattern: "fk_orders_{referenced_table}*",
+	})
+	if err != nil {
+		t.Fatalf("failed to create rule, got error %v", err)
+	}
+	var got []string
+	for _, issue := range db.Dialector.(*rawsql.Dialector).Lint(rule) {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		"Orders: table name Orders is not snake_case",
+		"Orders: foreign key name orders_user does not match fk_orders_users*",
+		"users: column name Email is not snake_case",
+		"users: index name name_unique does not start with uk_",
+		"users: index name byName is not snake_case",
+		"users: index name byName does not start with idx_",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues %q", got)
+	}
+
+	if _, err = rawsql.NamingConventions(rawsql.NamingRules{ForeignKeyPattern: "fk_["}); err == nil {
+		t.Errorf("expected an invalid pattern error")
+	}
+}
+
+func TestBaselineLock(t *testing.T) {
+	baseline := "CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(64));"
+	_, err := gorm.Open(rawsql.New(rawsql.Config{BaselineLock: true, SQL: []string{
+		baseline,
+		"ALTER TABLE `users` ADD COLUMN `email` text;\nCREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(128));",
+	}}))
+	if !errors.Is(err, rawsql.ErrBaselineTable) {
+		t.Fatalf("expected ErrBaselineTable, got %v", err)
+	}
+	for _, line := range []string{"-   `name` varchar(64),", "-   `email` text", "+   `name` varchar(128)"} {
+		if !strings.Contains(err.Error(), line+"\n") {
+			t.Errorf("expected %q in the diff, got %v", line, err)
+		}
+	}
+
+	// tables created after the baseline, and baseline tables dropped first, may be created again
+	openSQL(t, rawsql.Config{BaselineLock: true},
+		baseline,
+		"CREATE TABLE `orders` (`id` bigint);",
+		"DROP TABLE `orders`; CREATE TABLE `orders` (`id` bigint); DROP TABLE `users`;",
+		baseline,
+	)
+
+	// a streamed reader is one sql content, the baseline locks at its end
+	_, err = gorm.Open(rawsql.New(rawsql.Config{BaselineLock: true, StreamReaders: true, Readers: []io.Reader{
+		strings.NewReader(baseline + "\nCREATE TABLE `orders` (`id` bigint);"),
+		strings.NewReader("CREATE TABLE `orders` (`id` bigint, `total` int);"),
+	}}))
+	if !errors.Is(err, rawsql.ErrBaselineTable) || !strings.Contains(err.Error(), "orders") {
+		t.Errorf("expected ErrBaselineTable for orders of the first reader, got %v", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(10), `created_at` datetime(3), `updated_at` datetime(3)) CHARSET=utf8mb4;",
+		"CREATE TABLE `orders` (`user_id` bigint NOT NULL, `total` decimal(10,2) NOT NULL, `created_at` datetime, KEY `idx_user` (`user_id`),\n"+
+			"  CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`));",
+	)
+	tables := db.Dialector.(*rawsql.Dialector).Parser.GetTables()
+	for name, want := range map[string]rawsql.TableStats{
+		"users":  {Columns: 4, Indexes: 0, RowWidth: 8 + 41 + 7 + 7 + 1, PrimaryKey: true, Timestamps: true},
+		"orders": {Columns: 3, Indexes: 2, ForeignKeys: 1, RowWidth: 8 + 5 + 5 + 1},
+	} {
+		if got := rawsql.Stats(tables[name]); got != want {
+			t.Errorf("%s: got stats %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := rawsql.NewRegistry()
+	defer registry.Close()
+
+	if _, err := registry.Register("billing/main", rawsql.Config{SQL: []string{"CREATE TABLE `invoices` (`id` bigint PRIMARY KEY);"}}); err != nil {
+		t.Fatalf("failed to register, got error %v", err)
+	}
+	if _, err := registry.Register("users/main", rawsql.Config{SQL: []string{"CREATE TABLE `users` (`id` bigint PRIMARY KEY);"}}); err != nil {
+		t.Fatalf("failed to register, got error %v", err)
+	}
+	if _, err := registry.Register("users/main", rawsql.Config{}); err == nil {
+		t.Errorf("expected registering a name twice to fail")
+	}
+	if _, err := registry.Register("broken", rawsql.Config{SQL: []string{"CREATE TABLE"}}); err == nil {
+		t.Errorf("expected registering sql that does not parse to fail")
+	}
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"billing/main", "users/main"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	db, ok := registry.Lookup("users/main")
+	if !ok {
+		t.Fatalf("expected users/main to be registered")
+	}
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"users"}) {
+		t.Errorf("expected the tables of the schema only, got %v", tables)
+	}
+
+	if err := registry.Unregister("users/main"); err != nil {
+		t.Errorf("failed to unregister, got error %v", err)
+	}
+	if _, ok := registry.Lookup("users/main"); ok {
+		t.Errorf("expected users/main to be unregistered")
+	}
+	if err := registry.Unregister("users/main"); err == nil {
+		t.Errorf("expected unregistering an unknown name to fail")
+	}
+}
+
+func TestPlacementPolicy(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE PLACEMENT POLICY `eu` PRIMARY_REGION=\"eu-west-1\" REGIONS=\"eu-west-1,eu-central-1\";",
+		"CREATE RESOURCE GROUP `batch` RU_PER_SEC = 500;",
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY) /*T![placement] PLACEMENT POLICY=`eu` */;",
+		"CREATE TABLE `events` (`id` bigint PRIMARY KEY) PLACEMENT POLICY=eu;",
+		"ALTER PLACEMENT POLICY `eu` FOLLOWERS=4;",
+		"ALTER TABLE `events` PLACEMENT POLICY=DEFAULT;",
+		"ALTER RESOURCE GROUP `batch` RU_PER_SEC = 1000;",
+		"DROP RESOURCE GROUP `batch`;",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	if warnings := dialector.Report().Warnings; len(warnings) != 0 {
+		t.Errorf("expected placement and resource group statements to be tolerated, got %v", warnings)
+	}
+	tables := dialector.Parser.GetTables()
+	if policy := tables["users"].PlacementPolicy(); policy != "eu" {
+		t.Errorf("expected the eu placement policy, got %q", policy)
+	}
+	if policy, ok := tables["events"].Options[rawsql.PlacementPolicyOption]; ok {
+		t.Errorf("expected PLACEMENT POLICY=DEFAULT to remove the policy, got %q", policy)
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY);",
+		"CREATE TABLE `events` (`id` bigint PRIMARY KEY);",
+		"ALTER TABLE `users` ATTRIBUTES 'merge_option=allow';",
+		"ALTER TABLE `users` ATTRIBUTES='merge_option=deny, schedule'",
+		"ALTER TABLE `events` ATTRIBUTES 'merge_option=deny';",
+		"ALTER TABLE `events` ATTRIBUTES=DEFAULT;",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	if warnings := dialector.Report().Warnings; len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	tables := dialector.Parser.GetTables()
+	if attrs := tables["users"].Attributes; !reflect.DeepEqual(attrs, map[string]string{"merge_option": "deny", "schedule": ""}) {
+		t.Errorf("expected the attributes of the last ALTER, got %v", attrs)
+	}
+	if attrs := tables["events"].Attributes; attrs != nil {
+		t.Errorf("expected ATTRIBUTES=DEFAULT to remove the attributes, got %v", attrs)
+	}
+}
+
+func TestTransactionStatements(t *testing.T) {
+	db := openSQL(t, rawsql.Config{Strict: true},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY);",
+		"BEGIN;",
+		"ALTER TABLE `users` ADD COLUMN `name` varchar(32);",
+		"COMMIT;",
+		"START TRANSACTION;",
+		"DROP TABLE IF EXISTS `events`;",
+		"ROLLBACK;",
+		"DROP TABLE IF EXISTS `logs`;",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	if columns, _ := db.Migrator().ColumnTypes("users"); len(columns) != 2 {
+		t.Errorf("expected the ALTER in the transaction applied, got %v", columns)
+	}
+	if skipped := dialector.Report().Skipped; skipped != 4 {
+		t.Errorf("expected the transaction statements skipped, got %d", skipped)
+	}
+
+	var transactions []int
+	for _, w := range dialector.Report().Warnings {
+		transactions = append(transactions, w.Transaction)
+	}
+	// the unknown events table, the ROLLBACK and the unknown logs table
+	if !reflect.DeepEqual(transactions, []int{2, 2, 0}) {
+		t.Errorf("unexpected transactions %v of %v", transactions, dialector.Report().Warnings)
+	}
+}
+
+func TestRecoverSyntaxErrors(t *testing.T) {
+	sql := "CREATE TABLE `users` (`id` bigint PRIMARY KEY);\n" +
+		"CREATE TABLE `broken` (`id` bigint PRIMARY KEY,\n  `name` varchar(32) NOT NULL BOGUS);\n" +
+		"-- a comment; with a semicolon\n" +
+		"ALTER TABLE `users` ADD COLUMN `note` varchar(16) DEFAULT 'a;b';;\n" +
+		"CREATE TABLE `events` (`id` bigint PRIMARY KEY)"
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{SQL: []string{sql}})); err == nil {
+		t.Fatalf("expected the sql to be rejected without RecoverSyntaxErrors")
+	}
+
+	db := openSQL(t, rawsql.Config{RecoverSyntaxErrors: true}, sql)
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"events", "users"}) {
+		t.Errorf("expected the statements around the broken one applied, got %v", tables)
+	}
+	if columns, _ := db.Migrator().ColumnTypes("users"); len(columns) != 2 {
+		t.Errorf("expected the ALTER after the broken statement applied, got %v", columns)
+	}
+	warnings := db.Dialector.(*rawsql.Dialector).Report().Warnings
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0].Message, "statement at line 2 does not parse") ||
+		!strings.HasPrefix(warnings[0].Statement, "CREATE TABLE `broken`") {
+		t.Errorf("expected the broken statement reported, got %v", warnings)
+	}
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{SQL: []string{sql}, RecoverSyntaxErrors: true, Strict: true})); !errors.Is(err, rawsql.ErrUnsupported) {
+		t.Errorf("expected Strict to reject the broken statement, got %v", err)
+	}
+}
+
+func TestIgnoreDirective(t *testing.T) {
+	db := openSQL(t, rawsql.Config{Strict: true},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY);\n"+
+			"-- rawsql:ignore\n"+
+			"CREATE FULLTEXT CATALOG ft AS DEFAULT;\n"+
+			"ALTER TABLE `users` ADD COLUMN `name` varchar(32) DEFAULT '\n-- rawsql:ignore\n';\n"+
+			"-- rawsql:ignore-begin\n"+
+			"CREATE TRIGGER t BEFORE INSERT ON users FOR EACH ROW BEGIN SET NEW.name = 'x'; END;\n"+
+			"GO\n"+
+			"-- rawsql:ignore-end\n"+
+			"CREATE TABLE `events` (`id` bigint PRIMARY KEY);",
+	)
+	if tables := sortedTables(db); !reflect.DeepEqual(tables, []string{"events", "users"}) {
+		t.Errorf("expected the statements around the ignored ones applied, got %v", tables)
+	}
+	column, _ := db.Migrator().ColumnTypes("users")
+	if len(column) != 2 {
+		t.Fatalf("expected the directive in a string left alone, got %v", column)
+	}
+	if value, _ := column[1].DefaultValue(); value != "\n-- rawsql:ignore\n" {
+		t.Errorf("unexpected default %q", value)
+	}
+	// the FULLTEXT CATALOG and the three statements the trigger block splits into
+	if skipped := db.Dialector.(*rawsql.Dialector).Report().Skipped; skipped != 4 {
+		t.Errorf("expected the ignored statements skipped, got %d", skipped)
+	}
+
+	_, err := gorm.Open(rawsql.New(rawsql.Config{SQL: []string{"-- rawsql:ignore\nCREATE BOGUS;\nCREATE TABLE `a` (`id` bigint);\nCREATE BOGUS;"}}))
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("expected the syntax error on line 4, got %v", err)
+	}
+}
+
+func TestPredefinedTables(t *testing.T) {
+	accounts, err := rawsql.ParseTable("CREATE TABLE `accounts` (`id` bigint PRIMARY KEY, `email` varchar(64));")
+	if err != nil {
+		t.Fatalf("failed to parse table, got error %v", err)
+	}
+	config := rawsql.Config{Tables: []*rawsql.Table{accounts}}
+	db := openSQL(t, config,
+		"ALTER TABLE `accounts` ADD COLUMN `name` varchar(32);",
+		"CREATE TABLE `orders` (`id` bigint PRIMARY KEY, `account_id` bigint, FOREIGN KEY (`account_id`) REFERENCES `accounts` (`id`));",
+	)
+	dialector := db.Dialector.(*rawsql.Dialector)
+	if warnings := dialector.Report().Warnings; len(warnings) != 0 {
+		t.Errorf("expected the foreign key to resolve, got %v", warnings)
+	}
+	if columns, _ := db.Migrator().ColumnTypes("accounts"); len(columns) != 3 {
+		t.Errorf("expected the ALTER applied to the predefined table, got %v", columns)
+	}
+	if len(accounts.ColumnTypes) != 2 {
+		t.Errorf("expected the given table untouched, got %v", accounts.ColumnTypes)
+	}
+
+	if _, err := gorm.Open(rawsql.New(rawsql.Config{Tables: []*rawsql.Table{accounts, accounts}})); err == nil {
+		t.Errorf("expected a table given twice to be rejected")
+	}
+}
+
+func TestConformanceHarness(t *testing.T) {
+	ddl := "CREATE TABLE `users` (`id` bigint NOT NULL AUTO_INCREMENT, `email` varchar(64) NOT NULL, `bio` text, PRIMARY KEY (`id`), UNIQUE KEY `uk_email` (`email`));"
+	// a rawsql db stands for the live database, executing nothing
+	live := openSQL(t, rawsql.Config{}, ddl)
+	rawsqlconform.Run(t, live, ddl)
+
+	live = openSQL(t, rawsql.Config{}, "CREATE TABLE `users` (`id` bigint NOT NULL AUTO_INCREMENT, `email` varchar(128), PRIMARY KEY (`id`), KEY `uk_email` (`email`));")
+	mismatches, err := rawsqlconform.Compare(live, ddl)
+	if err != nil {
+		t.Fatalf("failed to compare, got error %v", err)
+	}
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.String())
+	}
+	want := []string{
+		"users column email ColumnType: rawsql varchar(64), live varchar(128)",
+		"users column email Length: rawsql 64, live 128",
+		"users column email DecimalSize: rawsql 64,-1, live 128,-1",
+		"users column email Nullable: rawsql false, live true",
+		"users column bio exists: rawsql true, live false",
+		"users index uk_email Unique: rawsql true, live false",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected mismatches\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
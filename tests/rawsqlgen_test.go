@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/rawsql"
+	"gorm.io/rawsql/rawsqlgen"
+)
+
+func TestRawsqlgen(t *testing.T) {
+	db := openSQL(t, rawsql.Config{},
+		"CREATE TABLE `users` (`id` bigint PRIMARY KEY, `name` varchar(64) NOT NULL DEFAULT '' COMMENT 'say \"hi\"\\n', KEY `idx_name` (`name`));",
+		"CREATE TABLE `posts` (`id` bigint PRIMARY KEY, `user_id` bigint, FOREIGN KEY (`user_id`) REFERENCES `users` (`id`));",
+	)
+	tables := db.Dialector.(*rawsql.Dialector).Parser.GetTables()
+	var buf bytes.Buffer
+	if err := rawsqlgen.Generate(&buf, tables, rawsqlgen.Options{Package: "schema", Source: "sql/"}); err != nil {
+		t.Fatalf("failed to generate, got error %v", err)
+	}
+	src := buf.String()
+	for _, want := range []string{
+		"// Code generated by rawsqlgen. DO NOT EDIT.\n// Source: sql/\n\npackage schema\n",
+		"\t\"gorm.io/rawsql/meta\"\n",
+		"func Tables() (map[string]*meta.Table, error) {\n",
+		"func MustTables() map[string]*meta.Table {\n",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected the generated source to contain %q, got\n%s", want, src)
+		}
+	}
+
+	// the snapshot constant decodes to the tables
+	file, err := parser.ParseFile(token.NewFileSet(), "schema_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("expected valid Go source, got error %v", err)
+	}
+	var snapshot strings.Builder
+	ast.Inspect(file, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING && strings.HasPrefix(lit.Value, `"`) {
+			if s, err := strconv.Unquote(lit.Value); err == nil && !strings.HasPrefix(s, "gorm.io/") && s != "strings" {
+				snapshot.WriteString(s)
+			}
+		}
+		return true
+	})
+	decoded, err := rawsql.DecodeSnapshot(strings.NewReader(snapshot.String()))
+	if err != nil {
+		t.Fatalf("failed to decode the embedded snapshot, got error %v", err)
+	}
+	for name, table := range tables {
+		if got, want := rawsql.CreateTableSQL(decoded[name]), rawsql.CreateTableSQL(table); got != want {
+			t.Errorf("expected the embedded table %s\n%s\ngot\n%s", name, want, got)
+		}
+	}
+
+	if err := rawsqlgen.Generate(&buf, tables, rawsqlgen.Options{Package: "my-schema"}); err == nil {
+		t.Errorf("expected an invalid package name to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "schema_gen.go")
+	config := rawsql.Config{FilePath: []string{"./sql"}}
+	if err := rawsqlgen.GenerateFile(path, config, rawsqlgen.Options{Package: "schema", Func: "Schema"}); err != nil {
+		t.Fatalf("failed to generate the file, got error %v", err)
+	}
+	before, _ := os.Stat(path)
+	if err := os.Chtimes(path, before.ModTime().Add(-time.Hour), before.ModTime().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rawsqlgen.GenerateFile(path, config, rawsqlgen.Options{Package: "schema", Func: "Schema"}); err != nil {
+		t.Fatalf("failed to generate the file again, got error %v", err)
+	}
+	after, _ := os.Stat(path)
+	if !after.ModTime().Equal(before.ModTime().Add(-time.Hour)) {
+		t.Errorf("expected an unchanged file left as it is")
+	}
+	if content, _ := os.ReadFile(path); !bytes.Contains(content, []byte("func MustSchema() map[string]*meta.Table {")) {
+		t.Errorf("expected the configured function name, got\n%s", content)
+	}
+}
@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/rawsql"
+)
+
+func columnSummary(ct gorm.ColumnType) []interface{} {
+	columnType, _ := ct.ColumnType()
+	pk, pkOK := ct.PrimaryKey()
+	nullable, _ := ct.Nullable()
+	length, lengthOK := ct.Length()
+	comment, commentOK := ct.Comment()
+	def, defOK := ct.DefaultValue()
+	if !defOK {
+		def = ""
+	}
+	return []interface{}{
+		ct.Name(), ct.DatabaseTypeName(), columnType, pk, pkOK, nullable,
+		length, lengthOK, comment, commentOK, def, defOK, ct.ScanType(),
+	}
+}
+
+func assertSameColumns(t *testing.T, want, got []gorm.ColumnType) {
+	t.Helper()
+	if len(want) != len(got) {
+		t.Fatalf("got %d columns, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if w, g := columnSummary(want[i]), columnSummary(got[i]); !reflect.DeepEqual(w, g) {
+			t.Errorf("column %d: got %v, want %v", i, g, w)
+		}
+	}
+}
+
+func TestParseCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	open := func() *gorm.DB {
+		db, err := gorm.Open(rawsql.New(rawsql.Config{FilePath: []string{"./sql"}, CacheDir: cacheDir}))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		return db
+	}
+
+	parsed, _ := open().Migrator().ColumnTypes("users")
+	entries, _ := os.ReadDir(cacheDir)
+	if len(entries) != 1 || filepath.Ext(entries[0].Name()) != ".json" {
+		t.Fatalf("expected one cache file, got %v", entries)
+	}
+
+	cached, _ := open().Migrator().ColumnTypes("users")
+	assertSameColumns(t, parsed, cached)
+
+	// another charset of the sql text is another cache entry
+	for i, config := range []rawsql.Config{{ParseCharset: "latin1"}, {ParseCharset: "latin1", ParseCollation: "latin1_bin"}} {
+		config.FilePath, config.CacheDir = []string{"./sql"}, cacheDir
+		if _, err := gorm.Open(rawsql.New(config)); err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		if entries, _ := os.ReadDir(cacheDir); len(entries) != i+2 {
+			t.Errorf("expected a cache file per charset and collation, got %v", entries)
+		}
+	}
+}
+
+func TestParseCacheWarnings(t *testing.T) {
+	config := rawsql.Config{CacheDir: t.TempDir(), SQL: []string{"CREATE TABLE `users` (`id` bigint);\nCREATE VIEW `v` AS SELECT 1;"}}
+	for i := 0; i < 2; i++ {
+		dialector := rawsql.New(config).(*rawsql.Dialector)
+		if _, err := gorm.Open(dialector); err != nil {
+			t.Fatalf("open %d: %v", i, err)
+		}
+		if report := dialector.Report(); report.Cached != (i == 1) || len(report.Warnings) != 1 || report.Warnings[0].Message != "unsupported statement" {
+			t.Fatalf("open %d: expected the warning of the view, got %+v", i, report)
+		}
+	}
+
+	config.Strict = true
+	if _, err := gorm.Open(rawsql.New(config)); !errors.Is(err, rawsql.ErrUnsupported) {
+		t.Fatalf("expected the cached warning to fail strict mode, got %v", err)
+	}
+}
+
+// options changing the warnings of the sql are part of the cache key, so a
+// Strict load never replays an entry written without them
+func TestParseCacheOptions(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		sql              string
+		passing, failing rawsql.Config // the Strict load writing the entry and one its warnings must fail
+	}{
+		{"target version", "CREATE TABLE `users` (`age` int, CONSTRAINT `adult` CHECK (`age` >= 18));",
+			rawsql.Config{}, rawsql.Config{TargetVersion: "mysql-5.7"}},
+		{"foreign key checks", "SET FOREIGN_KEY_CHECKS = 0;\nCREATE TABLE `orders` (`user_id` bigint, FOREIGN KEY (`user_id`) REFERENCES `users` (`id`));\nCREATE TABLE `users` (`id` bigint);",
+			rawsql.Config{HonorForeignKeyChecks: true}, rawsql.Config{}},
+	} {
+		cacheDir := t.TempDir()
+		config := c.passing
+		config.CacheDir, config.Strict, config.SQL = cacheDir, true, []string{c.sql}
+		if _, err := gorm.Open(rawsql.New(config)); err != nil {
+			t.Fatalf("%s: open: %v", c.name, err)
+		}
+		config = c.failing
+		config.CacheDir, config.Strict, config.SQL = cacheDir, true, []string{c.sql}
+		for i := 0; i < 2; i++ {
+			if _, err := gorm.Open(rawsql.New(config)); err == nil {
+				t.Errorf("%s: open %d: expected the warnings to fail strict mode", c.name, i)
+			}
+		}
+	}
+}
+
+func TestBinarySnapshot(t *testing.T) {
+	dialector := rawsql.New(rawsql.Config{FilePath: []string{"./sql"}, SQL: []string{
+		"CREATE TABLE `flags` (`id` bigint unsigned PRIMARY KEY, `name` varchar(32) COLLATE utf8mb4_bin, `score` decimal(10,2));",
+		"ALTER TABLE `flags` ADD COLUMN `on` tinyint(1) NOT NULL DEFAULT '0' COMMENT '', RENAME COLUMN `name` TO `label`, RENAME TO `feature_flags`;",
+		"ALTER TABLE `feature_flags` ATTRIBUTES='merge_option=deny';",
+	}}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	tables := dialector.Parser.GetTables()
+
+	var binary, want, got bytes.Buffer
+	if err := rawsql.EncodeBinarySnapshot(&binary, tables); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	decoded, err := rawsql.DecodeBinarySnapshot(&binary)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for name, table := range tables {
+		assertSameColumns(t, table.ColumnTypes, decoded[name].ColumnTypes)
+	}
+	rawsql.EncodeSnapshot(&want, tables)
+	rawsql.EncodeSnapshot(&got, decoded)
+	if want.String() != got.String() {
+		t.Fatalf("binary snapshot lost data:\n%s\n%s", want.String(), got.String())
+	}
+	if binary.Len() != 0 {
+		t.Fatalf("trailing bytes after decoding")
+	}
+}
+
+// meta is released as a module of its own, its build list must not grow the
+// parser or rawsql itself
+func TestMetaModuleParserFree(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command")
+	}
+	cmd := exec.Command("go", "list", "-m", "all")
+	cmd.Dir = "../meta"
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go list -m all: %v\n%s", err, out)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if lines[0] != "gorm.io/rawsql/meta" {
+		t.Fatalf("expected the meta module, got\n%s", out)
+	}
+	for _, line := range lines[1:] {
+		if path := strings.Fields(line)[0]; strings.Contains(path, "pingcap") || strings.HasPrefix(path, "gorm.io/rawsql") {
+			t.Errorf("expected meta to require neither the parser nor rawsql, it requires %s", line)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	sql := []string{
+		"CREATE TABLE `orders` (`id` bigint NOT NULL AUTO_INCREMENT PRIMARY KEY, `user_id` bigint unsigned, " +
+			"`note` varchar(255) CHARACTER SET latin1 DEFAULT 'n/a' COMMENT 'it''s free', " +
+			"CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE" +
+			") ENGINE=InnoDB AUTO_INCREMENT=42 DEFAULT CHARSET=utf8mb4 ROW_FORMAT=DYNAMIC COMMENT='orders';",
+		"CREATE TABLE `users` (`id` bigint unsigned NOT NULL, `name` varchar(64), `created_at` datetime DEFAULT CURRENT_TIMESTAMP, " +
+			"PRIMARY KEY (`id`), UNIQUE KEY `uk_name` (`name`));",
+		"ALTER TABLE `users` ADD COLUMN `email` varchar(128) COLLATE utf8mb4_bin AFTER `id`;",
+	}
+	dialector := rawsql.New(rawsql.Config{SQL: sql, ExportDir: dir}).(*rawsql.Dialector)
+	if _, err := gorm.Open(dialector); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, rawsql.ExportIndexFile))
+	if err != nil || string(index) != "users.sql\norders.sql\n" {
+		t.Fatalf("unexpected index %q: %v", index, err)
+	}
+	var files []string
+	for _, name := range strings.Fields(string(index)) {
+		files = append(files, filepath.Join(dir, name))
+	}
+	exported := rawsql.New(rawsql.Config{FilePath: files, Strict: true}).(*rawsql.Dialector)
+	if _, err = gorm.Open(exported); err != nil {
+		t.Fatalf("open exported: %v", err)
+	}
+
+	normalize := func(tables map[string]*rawsql.Table) string {
+		for _, table := range tables {
+			table.RawSQL, table.AutoIncrement = "", 0
+		}
+		var b bytes.Buffer
+		rawsql.EncodeSnapshot(&b, tables)
+		return b.String()
+	}
+	if want, got := normalize(dialector.Parser.GetTables()), normalize(exported.Parser.GetTables()); want != got {
+		t.Fatalf("exported schema differs\nwant: %s\n got: %s", want, got)
+	}
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/rawsql"
+)
+
+type recordingParser struct {
+	stmts []string
+}
+
+func (p *recordingParser) ParseSQL(sql string) error {
+	p.stmts = append(p.stmts, sql)
+	return nil
+}
+
+func (p *recordingParser) GetTables() map[string]*rawsql.Table {
+	return map[string]*rawsql.Table{}
+}
+
+func TestParseReaderSplitsStatements(t *testing.T) {
+	dump := "-- header; comment\n" +
+		"/*!40101 SET NAMES utf8mb4 */;\n" +
+		"INSERT INTO `t` VALUES ('a;b', \"c\\\";d\", 'it''s;');\n" +
+		"# hash comment;\n" +
+		"CREATE TABLE `t;1` (`id` int /* inline; comment */);\n" +
+		"DELIMITER ;;\n" +
+		"CREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW BEGIN SET @a = 1; END;;\n" +
+		"DELIMITER ;\n" +
+		"SELECT 1--1;\n" +
+		"SELECT 2"
+
+	p := &recordingParser{}
+	if err := rawsql.ParseReader(p, strings.NewReader(dump)); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []string{
+		"-- header; comment\n/*!40101 SET NAMES utf8mb4 */",
+		"INSERT INTO `t` VALUES ('a;b', \"c\\\";d\", 'it''s;')",
+		"# hash comment;\nCREATE TABLE `t;1` (`id` int /* inline; comment */)",
+		"CREATE TRIGGER tr BEFORE INSERT ON t FOR EACH ROW BEGIN SET @a = 1; END",
+		"SELECT 1--1",
+		"SELECT 2",
+	}
+	if !reflect.DeepEqual(p.stmts, want) {
+		t.Fatalf("got %q\nwant %q", p.stmts, want)
+	}
+}
+
+func TestStreamReaders(t *testing.T) {
+	db, err := gorm.Open(rawsql.New(rawsql.Config{
+		SQL:           []string{"CREATE TABLE `users` (`id` bigint NOT NULL);"},
+		Readers:       []io.Reader{strings.NewReader("ALTER TABLE `users` ADD COLUMN `name` varchar(64);\nINSERT INTO `users` VALUES (1, 'a;b');")},
+		StreamReaders: true,
+	}))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	cols, _ := db.Migrator().ColumnTypes("users")
+	if len(cols) != 2 || cols[1].Name() != "name" {
+		t.Fatalf("unexpected columns %v", cols)
+	}
+}
+
+func TestSkipDML(t *testing.T) {
+	dump := "CREATE TABLE `users` (`id` bigint NOT NULL);\n" +
+		"-- data\nINSERT INTO `users` VALUES (1), this is not valid sql;\n" +
+		"/* more */ replace into users values (2);\n" +
+		"ALTER TABLE `users` ADD COLUMN `name` varchar(64);\n" +
+		"UPDATE users SET broken =;"
+
+	for _, stream := range []bool{false, true} {
+		config := rawsql.Config{SkipDML: true}
+		if stream {
+			config.Readers, config.StreamReaders = []io.Reader{strings.NewReader(dump)}, true
+		} else {
+			config.SQL = []string{dump}
+		}
+		db, err := gorm.Open(rawsql.New(config))
+		if err != nil {
+			t.Fatalf("stream=%v open: %v", stream, err)
+		}
+		cols, _ := db.Migrator().ColumnTypes("users")
+		if len(cols) != 2 {
+			t.Fatalf("stream=%v unexpected columns %v", stream, cols)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package rawsql
+
+import "strings"
+
+// sqlToken is a lexical token of sql text, good enough to find keywords and
+// names without parsing
+type sqlToken struct {
+	text       string // unquoted for identifiers, the character for punctuation
+	kind       byte   // 'w' word, 'q' quoted identifier, 's' string literal, 'p' punctuation
+	start, end int    // byte offsets in the sql
+}
+
+func (t sqlToken) is(keyword string) bool {
+	return t.kind == 'w' && strings.EqualFold(t.text, keyword)
+}
+
+func (t sqlToken) isName() bool {
+	return t.kind == 'w' || t.kind == 'q'
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToUpper(s), substr)
+}
+
+// sqlTokens splits sql into tokens, skipping whitespace and comments
+func sqlTokens(sql string) []sqlToken {
+	tokens := make([]sqlToken, 0)
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '#' || c == '-' && strings.HasPrefix(sql[i:], "-- "):
+			if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if j := strings.Index(sql[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(sql)
+			}
+		case c == '`':
+			start := i
+			var b strings.Builder
+			for i++; i < len(sql); i++ {
+				if sql[i] == '`' {
+					if i+1 < len(sql) && sql[i+1] == '`' {
+						i++
+					} else {
+						break
+					}
+				}
+				b.WriteByte(sql[i])
+			}
+			if i++; i > len(sql) {
+				i = len(sql)
+			}
+			tokens = append(tokens, sqlToken{text: b.String(), kind: 'q', start: start, end: i})
+		case c == '\'' || c == '"':
+			start := i
+			for i++; i < len(sql) && sql[i] != c; i++ {
+				if sql[i] == '\\' {
+					i++
+				}
+			}
+			if i++; i > len(sql) {
+				i = len(sql)
+			}
+			tokens = append(tokens, sqlToken{kind: 's', start: start, end: i})
+		case isIdentByte(c):
+			j := i
+			for j < len(sql) && isIdentByte(sql[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: sql[i:j], kind: 'w', start: i, end: j})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{text: sql[i : i+1], kind: 'p', start: i, end: i + 1})
+			i++
+		}
+	}
+	return tokens
+}
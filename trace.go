@@ -0,0 +1,59 @@
+package rawsql
+
+import (
+	"context"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// Tracer starts the spans of a load when given in Config.Tracer: a
+// rawsql.load span around Initialize with rawsql.read, one rawsql.parse per
+// sql content and rawsql.build below it, and a rawsql.parse and rawsql.build
+// per watch reload. The built-in Parser starts a rawsql.statement span per
+// statement below its rawsql.parse, with the statement shortened in the
+// rawsql.statement attribute. It is shaped after the OpenTelemetry Tracer,
+// the gorm.io/rawsql/rawsqlotel module adapts a trace.TracerProvider:
+//
+//	rawsql.Config{Tracer: rawsqlotel.NewTracer(otel.GetTracerProvider())}
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) RecordError(error)                {}
+func (noopSpan) End()                             {}
+
+func (config *Config) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if config.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return config.Tracer.Start(ctx, name)
+}
+
+// startStatementSpan starts the rawsql.statement span of a statement applied
+// in a rawsql.parse span
+func (d *defaultParser) startStatementSpan(node ast.StmtNode) Span {
+	if d.spanContext == nil {
+		return noopSpan{}
+	}
+	_, span := d.config.startSpan(d.spanContext, "rawsql.statement")
+	span.SetAttribute("rawsql.statement", stmtSummary(node))
+	return span
+}
+
+// endSpan records err, when there is one, and ends span
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,68 @@
+package rawsql
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// TypeMapping sets the scan type of the columns it matches, e.g. Money for
+// the DECIMAL columns named *_amount, see Config.TypeMappings
+type TypeMapping struct {
+	Column   string       // path.Match pattern of the lower cased column names, e.g. *_amount
+	Table    string       // path.Match pattern of the table names, any table when empty
+	DataType string       // DatabaseTypeName of the columns, e.g. decimal, any type when empty
+	ScanType reflect.Type // register it, and its pointer under Config.NullableScanType, with meta.RegisterScanType to keep it in snapshots
+}
+
+// matches reports whether the mapping applies to the column of table
+func (m TypeMapping) matches(table, column, dataType string) bool {
+	if m.DataType != "" && !strings.EqualFold(m.DataType, dataType) {
+		return false
+	}
+	if m.Table != "" {
+		if ok, _ := path.Match(m.Table, table); !ok {
+			return false
+		}
+	}
+	ok, _ := path.Match(m.Column, strings.ToLower(column))
+	return ok
+}
+
+// validateTypeMappings reports the first mapping without a scan type or with
+// a malformed pattern
+func (config *Config) validateTypeMappings() error {
+	for _, m := range config.TypeMappings {
+		if m.ScanType == nil {
+			return fmt.Errorf("rawsql: type mapping of %s has no scan type", m.Column)
+		}
+		for _, pattern := range []string{m.Column, m.Table} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyTypeMappings sets the scan types of the columns of table the first
+// matching Config.TypeMappings gives them, Config.NullableScanType still
+// applies to those
+func (d *defaultParser) applyTypeMappings(table *Table) {
+	if len(d.config.TypeMappings) == 0 {
+		return
+	}
+	for _, ct := range table.ColumnTypes {
+		c, ok := ct.(*ColumnType)
+		if !ok {
+			continue
+		}
+		for _, m := range d.config.TypeMappings {
+			if m.matches(table.Name, c.Name(), c.DatabaseTypeName()) {
+				c.ScanTypeValue = m.ScanType
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package rawsql
+
+import (
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DefaultUUIDColumnPatterns match the column names that hold UUIDs by
+// convention, e.g. order_uuid and id
+var DefaultUUIDColumnPatterns = []string{"*_uuid", "uuid", "id"}
+
+// UUIDColumn is a column holding UUIDs, see Dialector.UUIDColumns
+type UUIDColumn struct {
+	Column string
+	Binary bool   // binary(16) holding the 16 bytes, otherwise char(36) holding the text form
+	GoType string // suggested field type, uuid.UUID of github.com/google/uuid or [16]byte
+	Hint   string // how generated code converts the values
+}
+
+const (
+	uuidTextHint   = "uuid.UUID scans the text form and its Value writes it, uuid.Parse and UUID.String convert by hand"
+	uuidBinaryHint = "scan into []byte and copy it, write u[:]; uuid.FromBytes converts to uuid.UUID, whose Value writes the text form; BIN_TO_UUID(col) and UUID_TO_BIN(?) convert in SQL"
+)
+
+func (config *Config) uuidColumnPatterns() []string {
+	if config.UUIDColumnPatterns != nil {
+		return config.UUIDColumnPatterns
+	}
+	return DefaultUUIDColumnPatterns
+}
+
+// validateUUIDColumnPatterns reports the first malformed pattern
+func (config *Config) validateUUIDColumnPatterns() error {
+	for _, pattern := range config.UUIDColumnPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UUIDColumnOf reports whether ct holds UUIDs by convention: a char(36) or
+// binary(16) column whose lower cased name matches Config.UUIDColumnPatterns
+func (dialector Dialector) UUIDColumnOf(ct gorm.ColumnType) (UUIDColumn, bool) {
+	columnType, _ := ct.ColumnType()
+	column := UUIDColumn{Column: ct.Name()}
+	switch columnType {
+	case "char(36)":
+		column.GoType, column.Hint = "uuid.UUID", uuidTextHint
+	case "binary(16)":
+		column.Binary, column.GoType, column.Hint = true, "[16]byte", uuidBinaryHint
+	default:
+		return UUIDColumn{}, false
+	}
+	name := strings.ToLower(ct.Name())
+	for _, pattern := range dialector.uuidColumnPatterns() {
+		if ok, _ := path.Match(pattern, name); ok {
+			return column, true
+		}
+	}
+	return UUIDColumn{}, false
+}
+
+// UUIDColumns returns the UUIDColumn of the columns of table holding UUIDs by
+// column name, their GoType feeds gen.FieldType
+func (dialector Dialector) UUIDColumns(table *Table) map[string]UUIDColumn {
+	columns := make(map[string]UUIDColumn)
+	for _, ct := range table.ColumnTypes {
+		if column, ok := dialector.UUIDColumnOf(ct); ok {
+			columns[ct.Name()] = column
+		}
+	}
+	return columns
+}
@@ -0,0 +1,153 @@
+package rawsql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// Issue is a problem ValidateDDL found in a statement, one MySQL would reject
+// or that is likely to fail or lose data on a populated table, or one of the
+// checks of the parsed tables such as ValidateIdentifiers found
+type Issue struct {
+	Table     string
+	Statement string // shortened statement text, empty for issues of the parsed tables
+	Message   string
+}
+
+func (i Issue) String() string {
+	if i.Statement == "" {
+		return fmt.Sprintf("%s: %s", i.Table, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.Message, i.Statement)
+}
+
+// ValidateDDL applies sql to a copy of the parsed tables and reports unknown
+// tables and columns, dropped columns still part of an index and NOT NULL
+// columns added without a default to existing tables. The tables of the
+// dialector are left untouched, the error is only about sql that does not
+// parse.
+func (dialector Dialector) ValidateDDL(sql string) ([]Issue, error) {
+	tables := make(map[string]*Table)
+	for name, table := range dialector.store.get() {
+		tables[name] = table.Clone()
+	}
+
+	config := *dialector.Config
+	config.Parser, config.OnStatement, config.Logger, config.Strict, config.BaselineLock = nil, nil, nil, false, false
+	d := newDefaultParse(&config).(*defaultParser)
+	d.tables = tables
+	stmtNodes, err := d.parseStmts(sql)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, node := range stmtNodes {
+		issues = append(issues, d.validateStmt(node)...)
+		if r := d.tryApply(node); r != nil {
+			issues = append(issues, Issue{Statement: stmtSummary(node), Message: fmt.Sprint(r)})
+		}
+	}
+	return issues, nil
+}
+
+// tryApply applies node, returning what the parser panicked with
+func (d *defaultParser) tryApply(node ast.StmtNode) (r interface{}) {
+	defer func() { r = recover() }()
+	_ = d.applyStmts([]ast.StmtNode{node})
+	return nil
+}
+
+func (d *defaultParser) validateStmt(node ast.StmtNode) (issues []Issue) {
+	alter, ok := node.(*ast.AlterTableStmt)
+	if !ok {
+		return nil
+	}
+	name := alter.Table.Name.String()
+	table, ok := d.tables[name]
+	if !ok {
+		// reported by tryApply
+		return nil
+	}
+	issue := func(format string, args ...interface{}) {
+		issues = append(issues, Issue{Table: name, Statement: stmtSummary(node), Message: fmt.Sprintf(format, args...)})
+	}
+
+	columns := make([]string, 0, len(table.ColumnTypes))
+	for _, ct := range table.ColumnTypes {
+		columns = append(columns, ct.Name())
+	}
+	for _, spec := range alter.Specs {
+		if spec.Position != nil && spec.Position.Tp == ast.ColumnPositionAfter && nameIndex(columns, spec.Position.RelativeColumn.Name.O) < 0 {
+			issue("unknown column %s", spec.Position.RelativeColumn.Name.O)
+		}
+		switch spec.Tp {
+		case ast.AlterTableAddColumns:
+			for _, col := range spec.NewColumns {
+				switch {
+				case nameIndex(columns, col.Name.Name.O) >= 0:
+					issue("duplicate column %s", col.Name.Name.O)
+				case requiresValue(col):
+					issue("NOT NULL column %s added without a default, existing rows need a value", col.Name.Name.O)
+				}
+				columns = append(columns, col.Name.Name.O)
+			}
+		case ast.AlterTableModifyColumn, ast.AlterTableChangeColumn, ast.AlterTableRenameColumn, ast.AlterTableDropColumn:
+			old := ""
+			if spec.OldColumnName != nil {
+				old = spec.OldColumnName.Name.O
+			} else if len(spec.NewColumns) > 0 {
+				old = spec.NewColumns[0].Name.Name.O
+			}
+			i := nameIndex(columns, old)
+			if i < 0 {
+				if spec.Tp != ast.AlterTableDropColumn || !spec.IfExists {
+					issue("unknown column %s", old)
+				}
+				continue
+			}
+			switch {
+			case spec.Tp == ast.AlterTableDropColumn:
+				for _, idx := range table.Indexes {
+					if nameIndex(idx.Columns(), old) >= 0 {
+						issue("dropped column %s is part of index %s", old, idx.Name())
+					}
+				}
+				columns = append(columns[:i], columns[i+1:]...)
+			case spec.NewColumnName != nil:
+				columns[i] = spec.NewColumnName.Name.O
+			case len(spec.NewColumns) > 0:
+				columns[i] = spec.NewColumns[0].Name.Name.O
+			}
+		}
+	}
+	return issues
+}
+
+// requiresValue reports whether existing rows need a value for col: it is NOT
+// NULL and neither has a default nor is filled in by MySQL
+func requiresValue(col *ast.ColumnDef) bool {
+	notNull := false
+	for _, opt := range col.Options {
+		switch opt.Tp {
+		case ast.ColumnOptionNotNull, ast.ColumnOptionPrimaryKey:
+			notNull = true
+		case ast.ColumnOptionNull:
+			notNull = false
+		case ast.ColumnOptionDefaultValue, ast.ColumnOptionAutoIncrement, ast.ColumnOptionGenerated:
+			return false
+		}
+	}
+	return notNull
+}
+
+func nameIndex(names []string, name string) int {
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,99 @@
+package rawsql
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vectorT is the default scan type of VECTOR columns, see Config.VectorScanType
+var vectorT = reflect.TypeOf([]float32(nil))
+
+var vectorTypeMarker = regexp.MustCompile(`longblob/\*rawsql:((?i:VECTOR)[^*]*)\*/`)
+
+// rewriteVector replaces the TiDB VECTOR, VECTOR(n) and VECTOR<FLOAT>(n)
+// column types of sql with a LONGBLOB so it can be parsed, the type follows
+// in a comment, restoreVector puts it back
+func rewriteVector(sql string) string {
+	if !containsFold(sql, "VECTOR") {
+		return sql
+	}
+	var (
+		b    strings.Builder
+		last int
+		prev sqlToken
+	)
+	tokens := sqlTokens(sql)
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.is("VECTOR") && prev.isName() && !prev.is("AS") {
+			end, _ := vectorTypeEnd(tokens, i)
+			b.WriteString(sql[last:tok.start])
+			b.WriteString("longblob/*rawsql:" + sql[tok.start:tokens[end].end] + "*/")
+			last = tokens[end].end
+			i = end
+		}
+		prev = tokens[i]
+	}
+	if last == 0 {
+		return sql
+	}
+	b.WriteString(sql[last:])
+	return b.String()
+}
+
+// vectorTypeEnd returns the index of the last token of the VECTOR type at
+// tokens[i] and its dimension, 0 when none is given
+func vectorTypeEnd(tokens []sqlToken, i int) (end int, dimension int64) {
+	end = i
+	if end+3 < len(tokens) && tokens[end+1].text == "<" && tokens[end+2].is("FLOAT") && tokens[end+3].text == ">" {
+		end += 3
+	}
+	if end+3 < len(tokens) && tokens[end+1].text == "(" && tokens[end+3].text == ")" {
+		if n, err := strconv.ParseInt(tokens[end+2].text, 10, 64); err == nil {
+			return end + 3, n
+		}
+	}
+	return end, 0
+}
+
+func vectorType(dimension int64) string {
+	if dimension > 0 {
+		return "VECTOR(" + strconv.FormatInt(dimension, 10) + ")"
+	}
+	return "VECTOR"
+}
+
+// restoreVector undoes rewriteVector in statement text
+func restoreVector(text string) string {
+	if !containsFold(text, "RAWSQL:VECTOR") {
+		return text
+	}
+	return vectorTypeMarker.ReplaceAllString(text, "$1")
+}
+
+// vectorColumns returns the columns text declares with a VECTOR type by their
+// lower cased names, with their dimension
+func vectorColumns(text string) map[string]int64 {
+	if !containsFold(text, "RAWSQL:VECTOR") {
+		return nil
+	}
+	columns := make(map[string]int64)
+	var prev sqlToken
+	tokens := sqlTokens(restoreText(text))
+	for i, tok := range tokens {
+		if tok.is("VECTOR") && prev.isName() && !prev.is("AS") {
+			_, columns[strings.ToLower(prev.text)] = vectorTypeEnd(tokens, i)
+		}
+		prev = tok
+	}
+	return columns
+}
+
+func (config *Config) vectorScanType() reflect.Type {
+	if config.VectorScanType != nil {
+		return config.VectorScanType
+	}
+	return vectorT
+}
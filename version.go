@@ -0,0 +1,71 @@
+package rawsql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// serverVersion is the server of Config.TargetVersion
+type serverVersion struct {
+	TiDB                bool
+	Major, Minor, Patch int // Patch is -1 when not given, standing for the latest release of Major.Minor
+}
+
+// defaultServerVersion is validated against when Config.TargetVersion is empty
+var defaultServerVersion = serverVersion{Major: 8, Patch: -1}
+
+func (v serverVersion) String() string {
+	server := "mysql"
+	if v.TiDB {
+		server = "tidb"
+	}
+	if v.Patch < 0 {
+		return fmt.Sprintf("%s-%d.%d", server, v.Major, v.Minor)
+	}
+	return fmt.Sprintf("%s-%d.%d.%d", server, v.Major, v.Minor, v.Patch)
+}
+
+// before reports whether v is older than major.minor.patch
+func (v serverVersion) before(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major < major
+	}
+	if v.Minor != minor {
+		return v.Minor < minor
+	}
+	return v.Patch >= 0 && v.Patch < patch
+}
+
+// parseServerVersion parses a target version such as mysql-5.7, tidb-7.5 or
+// 8.0.32, a bare version is a MySQL one
+func parseServerVersion(s string) (serverVersion, error) {
+	if s == "" {
+		return defaultServerVersion, nil
+	}
+	v := serverVersion{Patch: -1}
+	version := strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(version, "tidb"):
+		v.TiDB, version = true, version[len("tidb"):]
+	case strings.HasPrefix(version, "mysql"):
+		version = version[len("mysql"):]
+	}
+	version = strings.TrimLeft(version, "-_/ v")
+	parts := strings.Split(version, ".")
+	var err error
+	if v.Major, err = strconv.Atoi(parts[0]); err != nil {
+		return v, fmt.Errorf("rawsql: invalid target version %q", s)
+	}
+	if len(parts) > 1 {
+		if v.Minor, err = strconv.Atoi(parts[1]); err != nil {
+			return v, fmt.Errorf("rawsql: invalid target version %q", s)
+		}
+	}
+	if len(parts) > 2 {
+		if v.Patch, err = strconv.Atoi(parts[2]); err != nil {
+			return v, fmt.Errorf("rawsql: invalid target version %q", s)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,192 @@
+package rawsql
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const watchDebounce = 100 * time.Millisecond
+
+type watcher struct {
+	fsw  *fsnotify.Watcher
+	done chan struct{}
+	once sync.Once
+}
+
+func (w *watcher) close() error {
+	var err error
+	w.once.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+// Close stops the watcher started by Config.Watch, it is a no-op otherwise
+func (dialector Dialector) Close() error {
+	if dialector.store == nil {
+		return nil
+	}
+	dialector.store.mu.Lock()
+	w := dialector.store.watcher
+	dialector.store.watcher = nil
+	dialector.store.mu.Unlock()
+	if w == nil {
+		return nil
+	}
+	return w.close()
+}
+
+// watch starts watching the file based sources, userSQL and streamSQL are the
+// sql given directly and read from streams, which are reused as is on reload,
+// streamNames name streamSQL in reports
+func (dialector Dialector) watch(userSQL, streamSQL, streamNames []string) error {
+	if dialector.NewParser == nil && dialector.store.backend == nil {
+		return errors.New("rawsql: Watch with a custom Parser requires NewParser")
+	}
+	userSQL = append([]string(nil), userSQL...)
+	streamSQL = append([]string(nil), streamSQL...)
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dialector.watchDirs() {
+		if err = addRecursive(fsw, dir); err != nil {
+			fsw.Close()
+			return err
+		}
+	}
+
+	w := &watcher{fsw: fsw, done: make(chan struct{})}
+	dialector.store.mu.Lock()
+	dialector.store.watcher = w
+	dialector.store.mu.Unlock()
+
+	go dialector.watchLoop(w, userSQL, streamSQL, streamNames)
+	return nil
+}
+
+func (dialector Dialector) watchDirs() []string {
+	dirs := make([]string, 0)
+	add := func(path string) {
+		if v, err := os.Stat(path); err == nil {
+			if v.IsDir() {
+				dirs = append(dirs, path)
+			} else {
+				dirs = append(dirs, filepath.Dir(path))
+			}
+		}
+	}
+	for _, f := range dialector.FilePath {
+		if f != "" {
+			add(f)
+		}
+	}
+	for _, dir := range dialector.DirPath {
+		if dir != "" {
+			add(dir)
+		}
+	}
+	for _, pattern := range dialector.Glob {
+		add(filepath.Dir(pattern))
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			add(m)
+		}
+	}
+	return dirs
+}
+
+func addRecursive(fsw *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		return fsw.Add(path)
+	})
+}
+
+func (dialector Dialector) watchLoop(w *watcher, userSQL, streamSQL, streamNames []string) {
+	var (
+		timer   *time.Timer
+		trigger <-chan time.Time
+	)
+	for {
+		select {
+		case <-w.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if v, err := os.Stat(event.Name); err == nil && v.IsDir() {
+					_ = addRecursive(w.fsw, event.Name)
+				}
+			}
+			if timer == nil {
+				timer = time.NewTimer(watchDebounce)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+			trigger = timer.C
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			if dialector.OnReload != nil {
+				dialector.OnReload(dialector.store.get(), err)
+			}
+		case <-trigger:
+			trigger = nil
+			tables, report, err := dialector.reload(userSQL, streamSQL, streamNames)
+			if err == nil {
+				dialector.store.set(tables, report)
+			} else {
+				tables = dialector.store.get()
+			}
+			if dialector.OnReload != nil {
+				dialector.OnReload(tables, err)
+			}
+		}
+	}
+}
+
+// reload parses every source again with a fresh parser, leaving the current
+// tables untouched so a broken edit never replaces a working schema
+func (dialector Dialector) reload(userSQL, streamSQL, streamNames []string) (tables map[string]*Table, report *ParseReport, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rawsql: reload: %v", r)
+		}
+	}()
+
+	config := *dialector.Config
+	config.SQL = append([]string(nil), userSQL...)
+	if dialector.NewParser != nil {
+		config.Parser = dialector.NewParser()
+	} else {
+		config.Parser = dialector.store.backend(&config)
+	}
+	fresh := Dialector{Config: &config, store: &tableStore{backend: dialector.store.backend}}
+	if err = fresh.filesTOSQL(); err != nil {
+		return nil, nil, err
+	}
+	for i, sql := range streamSQL {
+		fresh.addSQL(streamNames[i], sql)
+	}
+	if err = fresh.sqlTOTable(fresh.context(), -1); err != nil {
+		return nil, nil, err
+	}
+	return fresh.store.get(), fresh.Report(), nil
+}